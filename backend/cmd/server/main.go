@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"music-app/backend/internal/api"
+	"music-app/backend/internal/jobs"
+	"music-app/backend/internal/notifications"
+	"music-app/backend/internal/suggest"
 	"music-app/backend/internal/utils"
 	"music-app/backend/pkg/config"
 	"music-app/backend/pkg/db"
@@ -10,6 +14,7 @@ import (
 	"music-app/backend/pkg/storage"
 	"net/http"
 	"os"
+	"time"
 
 	_ "music-app/backend/docs" // docs is generated by Swag CLI, you have to import it.
 
@@ -51,7 +56,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	router := api.NewRouter(db, jwtManager, cfg, minioClient)
+	// Build queued data exports, and purge accounts past their cooling-off period and expired
+	// data exports
+	accountJobs := jobs.NewAccountJobs(db, minioClient, cfg)
+	go jobs.Every(context.Background(), time.Hour, "account_purge", accountJobs.PurgeDueAccounts)
+	go jobs.Every(context.Background(), 30*time.Second, "data_export_processing", accountJobs.ProcessDataExports)
+
+	// Keep the cached tracks of smart playlists and the generated covers fresh, and empty the
+	// playlist trash
+	playlistJobs := jobs.NewPlaylistJobs(db, minioClient, cfg)
+	go jobs.Every(context.Background(), 15*time.Minute, "smart_playlist_refresh", playlistJobs.RefreshSmartPlaylists)
+	go jobs.Every(context.Background(), time.Minute, "playlist_cover_generation", playlistJobs.GeneratePlaylistCovers)
+	go jobs.Every(context.Background(), time.Hour, "deleted_playlist_purge", playlistJobs.PurgeDeletedPlaylists)
+
+	// Work through queued imports from other services
+	importJobs := jobs.NewImportJobs(db, minioClient)
+	go jobs.Every(context.Background(), 30*time.Second, "import_processing", importJobs.ProcessImports)
+
+	// Deliver notifications created by any instance to this instance's streams
+	hub := notifications.NewHub(db, cfg.DatabaseURL)
+	go func() {
+		if err := hub.Run(context.Background()); err != nil {
+			slog.Error("Notification hub stopped", "error", err)
+		}
+	}()
+
+	// Rebuild the search-as-you-type index so new releases and listens show up
+	suggestions := suggest.NewIndex(db)
+	go jobs.Every(context.Background(), 10*time.Minute, "search_suggest_refresh", suggestions.Refresh)
+
+	router := api.NewRouter(db, jwtManager, cfg, minioClient, hub, suggestions)
 
 	r := router.NewRouter()
 
@@ -17,9 +17,12 @@ CREATE TABLE "tracks" (
   "duration" INT,
   "cover_image_url" TEXT,
   "genre" VARCHAR(50),
+  "genre_id" INT,
   "lyrics" TEXT,
+  "lyrics_lines" JSONB,
   "quality_bitrate" INT,
   "status" VARCHAR(30) DEFAULT 'published',
+  "search_vector" TSVECTOR,
   "created_at" TIMESTAMP DEFAULT (NOW()),
   "updated_at" TIMESTAMP DEFAULT (NOW())
 );
@@ -30,6 +33,7 @@ CREATE TABLE "albums" (
   "artist_id" INT NOT NULL,
   "cover_url" TEXT,
   "release_date" DATE,
+  "search_vector" TSVECTOR,
   "created_at" TIMESTAMP DEFAULT (NOW())
 );
 
@@ -49,14 +53,25 @@ CREATE TABLE "playlists" (
   "title" VARCHAR(255) NOT NULL,
   "creator_id" INT NOT NULL,
   "cover_url" TEXT,
+  "cover_generated" BOOLEAN NOT NULL DEFAULT false,
+  "cover_version" INT,
+  "cover_sources" TEXT[],
   "privacy" VARCHAR(20) DEFAULT 'public',
+  "version" INT NOT NULL DEFAULT 0,
+  "rules" JSONB,
+  "rules_refreshed_at" TIMESTAMP,
+  "deleted_at" TIMESTAMP,
+  "forked_from_id" INT,
   "created_at" TIMESTAMP DEFAULT (NOW())
 );
 
 CREATE TABLE "playlist_tracks" (
   "id" SERIAL PRIMARY KEY,
   "playlist_id" INT NOT NULL,
-  "track_id" INT NOT NULL
+  "track_id" INT NOT NULL,
+  "position" INT NOT NULL,
+  "added_by" INT,
+  "added_at" TIMESTAMP DEFAULT (NOW())
 );
 
 CREATE TABLE "listens" (
@@ -66,7 +81,9 @@ CREATE TABLE "listens" (
   "device" TEXT,
   "ip" INET,
   "listen_duration" INT,
-  "timestamp" TIMESTAMP DEFAULT (NOW())
+  "timestamp" TIMESTAMP DEFAULT (NOW()),
+  "imported" BOOLEAN NOT NULL DEFAULT FALSE,
+  "import_job_id" INT
 );
 
 CREATE TABLE "likes" (
@@ -80,8 +97,13 @@ CREATE TABLE "comments" (
   "id" SERIAL PRIMARY KEY,
   "track_id" INT NOT NULL,
   "user_id" INT NOT NULL,
+  "parent_id" INT,
   "content" TEXT NOT NULL,
-  "created_at" TIMESTAMP DEFAULT (NOW())
+  "position_ms" INT,
+  "resolved" BOOLEAN NOT NULL DEFAULT false,
+  "resolved_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "updated_at" TIMESTAMP
 );
 
 CREATE TABLE "admin_logs" (
@@ -92,7 +114,7 @@ CREATE TABLE "admin_logs" (
   "timestamp" TIMESTAMP DEFAULT (NOW())
 );
 
-CREATE UNIQUE INDEX ON "playlist_tracks" ("playlist_id", "track_id");
+CREATE INDEX ON "playlist_tracks" ("playlist_id", "position");
 
 CREATE UNIQUE INDEX ON "likes" ("track_id", "user_id");
 
@@ -100,6 +122,10 @@ ALTER TABLE "tracks" ADD FOREIGN KEY ("artist_id") REFERENCES "users" ("id") ON
 
 ALTER TABLE "albums" ADD FOREIGN KEY ("artist_id") REFERENCES "users" ("id") ON DELETE CASCADE;
 
+CREATE UNIQUE INDEX ON "track_tags" ("track_id", "tag_id");
+
+CREATE INDEX ON "track_tags" ("tag_id");
+
 ALTER TABLE "track_tags" ADD FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "track_tags" ADD FOREIGN KEY ("tag_id") REFERENCES "tags" ("id") ON DELETE CASCADE;
@@ -110,6 +136,8 @@ ALTER TABLE "playlist_tracks" ADD FOREIGN KEY ("playlist_id") REFERENCES "playli
 
 ALTER TABLE "playlist_tracks" ADD FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE;
 
+ALTER TABLE "playlist_tracks" ADD FOREIGN KEY ("added_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
 ALTER TABLE "listens" ADD FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "listens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE SET NULL;
@@ -122,13 +150,22 @@ ALTER TABLE "comments" ADD FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") O
 
 ALTER TABLE "comments" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
 
+ALTER TABLE "comments" ADD FOREIGN KEY ("parent_id") REFERENCES "comments" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "comments" ("track_id", "created_at");
+
+CREATE INDEX ON "comments" ("parent_id");
+
+CREATE INDEX ON "comments" ("track_id", "position_ms") WHERE "position_ms" IS NOT NULL;
+
 ALTER TABLE "admin_logs" ADD FOREIGN KEY ("actor_id") REFERENCES "users" ("id") ON DELETE CASCADE;
 
 
 CREATE TABLE "album_tracks" (
   "id" SERIAL PRIMARY KEY,
   "album_id" INT NOT NULL,
-  "track_id" INT NOT NULL
+  "track_id" INT NOT NULL,
+  "position" INT NOT NULL
 );
 
 CREATE UNIQUE INDEX ON "album_tracks" ("album_id", "track_id");
@@ -136,3 +173,445 @@ CREATE UNIQUE INDEX ON "album_tracks" ("album_id", "track_id");
 ALTER TABLE "album_tracks" ADD FOREIGN KEY ("album_id") REFERENCES "albums" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "album_tracks" ADD FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE;
+
+CREATE TABLE "roles" (
+  "id" SERIAL PRIMARY KEY,
+  "name" VARCHAR(50) UNIQUE NOT NULL,
+  "description" TEXT,
+  "created_at" TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE TABLE "permissions" (
+  "id" SERIAL PRIMARY KEY,
+  "name" VARCHAR(100) UNIQUE NOT NULL,
+  "description" TEXT
+);
+
+CREATE TABLE "role_permissions" (
+  "role_id" INT NOT NULL,
+  "permission_id" INT NOT NULL,
+  PRIMARY KEY ("role_id", "permission_id")
+);
+
+CREATE TABLE "user_roles" (
+  "user_id" INT NOT NULL,
+  "role_id" INT NOT NULL,
+  "granted_by" INT,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  PRIMARY KEY ("user_id", "role_id")
+);
+
+ALTER TABLE "role_permissions" ADD FOREIGN KEY ("role_id") REFERENCES "roles" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "role_permissions" ADD FOREIGN KEY ("permission_id") REFERENCES "permissions" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_roles" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_roles" ADD FOREIGN KEY ("role_id") REFERENCES "roles" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_roles" ADD FOREIGN KEY ("granted_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+INSERT INTO "roles" ("name", "description") VALUES
+  ('listener', 'Regular listener'),
+  ('artist', 'Uploads and publishes their own tracks'),
+  ('moderator', 'Moderates community content and takes down tracks'),
+  ('label_manager', 'Manages tracks and albums on behalf of artists'),
+  ('admin', 'Full administrative access');
+
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('tracks.upload', 'Upload new tracks'),
+  ('tracks.publish', 'Publish tracks to the catalog'),
+  ('tracks.manage_all', 'Manage tracks owned by other users'),
+  ('tracks.takedown', 'Take down tracks from the catalog'),
+  ('albums.manage', 'Create and edit albums'),
+  ('tags.manage', 'Create, rename and delete tags'),
+  ('genres.manage', 'Curate the genre taxonomy'),
+  ('comments.moderate', 'Delete comments written by other users'),
+  ('roles.manage', 'Assign roles and permissions'),
+  ('admin.dashboard', 'View the admin dashboard');
+
+INSERT INTO "role_permissions" ("role_id", "permission_id")
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON (
+  (r.name = 'artist' AND p.name IN ('tracks.upload', 'tracks.publish')) OR
+  (r.name = 'moderator' AND p.name IN ('tracks.takedown', 'comments.moderate', 'tags.manage', 'genres.manage', 'admin.dashboard')) OR
+  (r.name = 'label_manager' AND p.name IN ('tracks.upload', 'tracks.publish', 'tracks.manage_all', 'albums.manage')) OR
+  r.name = 'admin'
+);
+
+CREATE TABLE "data_export_jobs" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INT NOT NULL,
+  "status" VARCHAR(20) NOT NULL DEFAULT 'pending',
+  "object_name" TEXT,
+  "error" TEXT,
+  "attempts" INT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "started_at" TIMESTAMP,
+  "completed_at" TIMESTAMP,
+  "expires_at" TIMESTAMP
+);
+
+CREATE TABLE "account_deletions" (
+  "user_id" INT PRIMARY KEY,
+  "requested_at" TIMESTAMP DEFAULT (NOW()),
+  "scheduled_for" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX ON "data_export_jobs" ("user_id");
+
+CREATE UNIQUE INDEX "data_export_jobs_active_idx" ON "data_export_jobs" ("user_id") WHERE "status" IN ('pending', 'processing');
+
+ALTER TABLE "data_export_jobs" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "account_deletions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE TABLE "follows" (
+  "follower_id" INT NOT NULL,
+  "followee_id" INT NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "pinned_at" TIMESTAMP,
+  PRIMARY KEY ("follower_id", "followee_id"),
+  CHECK ("follower_id" <> "followee_id")
+);
+
+CREATE INDEX ON "follows" ("followee_id");
+
+ALTER TABLE "follows" ADD FOREIGN KEY ("follower_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "follows" ADD FOREIGN KEY ("followee_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE TABLE "notifications" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INT NOT NULL,
+  "type" VARCHAR(50) NOT NULL,
+  "actor_id" INT,
+  "entity_type" VARCHAR(30),
+  "entity_id" INT,
+  "message" TEXT NOT NULL,
+  "read_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE INDEX ON "notifications" ("user_id", "id");
+
+CREATE INDEX ON "notifications" ("user_id") WHERE "read_at" IS NULL;
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("actor_id") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "playlist_collaborators" (
+  "playlist_id" INT NOT NULL,
+  "user_id" INT NOT NULL,
+  "role" VARCHAR(20) NOT NULL DEFAULT 'viewer',
+  "invited_by" INT,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "accepted_at" TIMESTAMP,
+  PRIMARY KEY ("playlist_id", "user_id")
+);
+
+CREATE INDEX ON "playlist_collaborators" ("user_id");
+
+ALTER TABLE "playlist_collaborators" ADD FOREIGN KEY ("playlist_id") REFERENCES "playlists" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "playlist_collaborators" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "playlist_collaborators" ADD FOREIGN KEY ("invited_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "playlist_share_links" (
+  "id" SERIAL PRIMARY KEY,
+  "playlist_id" INT NOT NULL,
+  "token" VARCHAR(64) UNIQUE NOT NULL,
+  "created_by" INT,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "revoked_at" TIMESTAMP
+);
+
+CREATE INDEX ON "playlist_share_links" ("playlist_id");
+
+ALTER TABLE "playlist_share_links" ADD FOREIGN KEY ("playlist_id") REFERENCES "playlists" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "playlist_share_links" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "import_jobs" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INT NOT NULL,
+  "source" VARCHAR(20) NOT NULL,
+  "filename" TEXT,
+  "object_name" TEXT,
+  "status" VARCHAR(20) NOT NULL DEFAULT 'pending',
+  "report" JSONB,
+  "error" TEXT,
+  "playlists" JSONB NOT NULL DEFAULT '[]',
+  "attempts" INT NOT NULL DEFAULT 0,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  "started_at" TIMESTAMP,
+  "completed_at" TIMESTAMP
+);
+
+CREATE INDEX ON "import_jobs" ("user_id");
+
+CREATE INDEX "import_jobs_queue_idx" ON "import_jobs" ("status", "created_at") WHERE "status" IN ('pending', 'processing');
+
+ALTER TABLE "import_jobs" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "listens" ADD FOREIGN KEY ("import_job_id") REFERENCES "import_jobs" ("id") ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX "listens_imported_idx" ON "listens" ("user_id", "track_id", "timestamp") WHERE "imported";
+
+CREATE INDEX "playlists_smart_idx" ON "playlists" ("rules_refreshed_at") WHERE "rules" IS NOT NULL;
+
+CREATE TABLE "library_folders" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INT NOT NULL,
+  "parent_id" INT,
+  "name" VARCHAR(255) NOT NULL,
+  "position" INT NOT NULL DEFAULT 0,
+  "pinned_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE INDEX ON "library_folders" ("user_id");
+
+ALTER TABLE "library_folders" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "library_folders" ADD FOREIGN KEY ("parent_id") REFERENCES "library_folders" ("id") ON DELETE CASCADE;
+
+CREATE TABLE "library_playlists" (
+  "user_id" INT NOT NULL,
+  "playlist_id" INT NOT NULL,
+  "folder_id" INT,
+  "position" INT,
+  "pinned_at" TIMESTAMP,
+  PRIMARY KEY ("user_id", "playlist_id")
+);
+
+ALTER TABLE "library_playlists" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "library_playlists" ADD FOREIGN KEY ("playlist_id") REFERENCES "playlists" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "library_playlists" ADD FOREIGN KEY ("folder_id") REFERENCES "library_folders" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "saved_albums" (
+  "user_id" INT NOT NULL,
+  "album_id" INT NOT NULL,
+  "pinned_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  PRIMARY KEY ("user_id", "album_id")
+);
+
+ALTER TABLE "saved_albums" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "saved_albums" ADD FOREIGN KEY ("album_id") REFERENCES "albums" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "playlists_deleted_idx" ON "playlists" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+CREATE TABLE "playlist_revisions" (
+  "id" SERIAL PRIMARY KEY,
+  "playlist_id" INT NOT NULL,
+  "version" INT NOT NULL,
+  "actor_id" INT,
+  "action" VARCHAR(30) NOT NULL,
+  "summary" TEXT NOT NULL,
+  "snapshot" JSONB NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE INDEX ON "playlist_revisions" ("playlist_id", "id");
+
+ALTER TABLE "playlist_revisions" ADD FOREIGN KEY ("playlist_id") REFERENCES "playlists" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "playlist_revisions" ADD FOREIGN KEY ("actor_id") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "playlists" ADD FOREIGN KEY ("forked_from_id") REFERENCES "playlists" ("id") ON DELETE SET NULL;
+
+CREATE TABLE "playlist_follows" (
+  "user_id" INT NOT NULL,
+  "playlist_id" INT NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (NOW()),
+  PRIMARY KEY ("user_id", "playlist_id")
+);
+
+CREATE INDEX ON "playlist_follows" ("playlist_id");
+
+ALTER TABLE "playlist_follows" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "playlist_follows" ADD FOREIGN KEY ("playlist_id") REFERENCES "playlists" ("id") ON DELETE CASCADE;
+
+-- Full-text search. Text is lowercased and stripped of accents before it is indexed or
+-- compared, so "sarki" finds "Şarkı"; pg_trgm tolerates typos in titles and names.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TEXT SEARCH CONFIGURATION search_simple (COPY = simple);
+
+ALTER TEXT SEARCH CONFIGURATION search_simple ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+
+-- unaccent is only STABLE because its dictionary could change; naming the dictionary lets
+-- these functions be IMMUTABLE, so they can be indexed and folded into query plans
+CREATE OR REPLACE FUNCTION search_normalize(input TEXT) RETURNS TEXT
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+  AS $$ SELECT lower(public.unaccent('public.unaccent'::regdictionary, input)) $$;
+
+-- Every word of the input must match, as a prefix so results show up while typing
+CREATE OR REPLACE FUNCTION search_query(input TEXT) RETURNS tsquery
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+  AS $$
+    SELECT to_tsquery('search_simple', COALESCE(string_agg(quote_literal(word) || ':*', ' & '), ''))
+    FROM regexp_split_to_table(search_normalize(input), '[^[:alnum:]]+') AS word
+    WHERE word <> ''
+  $$;
+
+CREATE OR REPLACE FUNCTION track_search_vector(title TEXT, artist TEXT, genre TEXT, lyrics TEXT) RETURNS tsvector
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE
+  AS $$
+    SELECT setweight(to_tsvector('search_simple', COALESCE(title, '')), 'A')
+        || setweight(to_tsvector('search_simple', COALESCE(artist, '')), 'B')
+        || setweight(to_tsvector('search_simple', COALESCE(genre, '')), 'C')
+        || setweight(to_tsvector('search_simple', COALESCE(lyrics, '')), 'D')
+  $$;
+
+CREATE OR REPLACE FUNCTION album_search_vector(title TEXT, artist TEXT) RETURNS tsvector
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE
+  AS $$
+    SELECT setweight(to_tsvector('search_simple', COALESCE(title, '')), 'A')
+        || setweight(to_tsvector('search_simple', COALESCE(artist, '')), 'B')
+  $$;
+
+CREATE OR REPLACE FUNCTION tracks_search_vector_update() RETURNS trigger
+  LANGUAGE plpgsql
+  AS $$
+  BEGIN
+    NEW.search_vector := track_search_vector(NEW.title, (SELECT username FROM users WHERE id = NEW.artist_id), NEW.genre, NEW.lyrics);
+    RETURN NEW;
+  END
+  $$;
+
+CREATE OR REPLACE FUNCTION albums_search_vector_update() RETURNS trigger
+  LANGUAGE plpgsql
+  AS $$
+  BEGIN
+    NEW.search_vector := album_search_vector(NEW.title, (SELECT username FROM users WHERE id = NEW.artist_id));
+    RETURN NEW;
+  END
+  $$;
+
+-- Tracks and albums index their artist's name, so renaming a user reindexes them
+CREATE OR REPLACE FUNCTION users_search_vector_update() RETURNS trigger
+  LANGUAGE plpgsql
+  AS $$
+  BEGIN
+    UPDATE tracks SET search_vector = track_search_vector(title, NEW.username, genre, lyrics) WHERE artist_id = NEW.id;
+    UPDATE albums SET search_vector = album_search_vector(title, NEW.username) WHERE artist_id = NEW.id;
+    RETURN NULL;
+  END
+  $$;
+
+CREATE TRIGGER "tracks_search_vector" BEFORE INSERT OR UPDATE OF "title", "artist_id", "genre", "lyrics" ON "tracks"
+  FOR EACH ROW EXECUTE FUNCTION tracks_search_vector_update();
+
+CREATE TRIGGER "albums_search_vector" BEFORE INSERT OR UPDATE OF "title", "artist_id" ON "albums"
+  FOR EACH ROW EXECUTE FUNCTION albums_search_vector_update();
+
+CREATE TRIGGER "users_search_vector" AFTER UPDATE OF "username" ON "users"
+  FOR EACH ROW WHEN (OLD."username" IS DISTINCT FROM NEW."username") EXECUTE FUNCTION users_search_vector_update();
+
+CREATE INDEX ON "tracks" USING GIN ("search_vector");
+
+CREATE INDEX ON "tracks" USING GIN (search_normalize("title") gin_trgm_ops);
+
+CREATE INDEX ON "tracks" ("artist_id");
+
+CREATE INDEX ON "albums" USING GIN ("search_vector");
+
+CREATE INDEX ON "albums" USING GIN (search_normalize("title") gin_trgm_ops);
+
+CREATE INDEX ON "users" USING GIN (to_tsvector('search_simple', "username"));
+
+CREATE INDEX ON "users" USING GIN (search_normalize("username") gin_trgm_ops);
+
+CREATE INDEX ON "playlists" USING GIN (to_tsvector('search_simple', "title")) WHERE "deleted_at" IS NULL AND "privacy" = 'public';
+
+CREATE INDEX ON "playlists" USING GIN (search_normalize("title") gin_trgm_ops) WHERE "deleted_at" IS NULL AND "privacy" = 'public';
+
+CREATE TABLE "recent_searches" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INT NOT NULL,
+  "query" VARCHAR(200) NOT NULL,
+  "searched_at" TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE UNIQUE INDEX ON "recent_searches" ("user_id", lower("query"));
+
+ALTER TABLE "recent_searches" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+-- genre_key normalizes a genre name or alias for matching: case, accents, punctuation and
+-- spacing are ignored and "&" reads as "and", so "Hip-Hop", "hip hop " and "HIP HOP" match
+CREATE OR REPLACE FUNCTION genre_key(input TEXT) RETURNS TEXT
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+  AS $$ SELECT btrim(regexp_replace(replace(search_normalize(input), '&', ' and '), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+CREATE TABLE "genres" (
+  "id" SERIAL PRIMARY KEY,
+  "name" VARCHAR(50) NOT NULL,
+  "slug" TEXT UNIQUE NOT NULL,
+  "parent_id" INT,
+  "created_at" TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE TABLE "genre_aliases" (
+  "alias" TEXT PRIMARY KEY,
+  "genre_id" INT NOT NULL
+);
+
+CREATE INDEX ON "genres" ("parent_id");
+
+CREATE INDEX ON "genre_aliases" ("genre_id");
+
+CREATE INDEX ON "tracks" ("genre_id");
+
+ALTER TABLE "genres" ADD FOREIGN KEY ("parent_id") REFERENCES "genres" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "genre_aliases" ADD FOREIGN KEY ("genre_id") REFERENCES "genres" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "tracks" ADD FOREIGN KEY ("genre_id") REFERENCES "genres" ("id") ON DELETE SET NULL;
+
+INSERT INTO "genres" ("name", "slug")
+SELECT g.name, replace(genre_key(g.name), ' ', '-')
+FROM (VALUES
+  ('Rock'), ('Pop'), ('Electronic'), ('Hip Hop'), ('R&B'), ('Jazz'),
+  ('Blues'), ('Classical'), ('Country'), ('Folk'), ('Reggae'), ('Latin')
+) AS g(name);
+
+INSERT INTO "genres" ("name", "slug", "parent_id")
+SELECT g.name, replace(genre_key(g.name), ' ', '-'), p.id
+FROM (VALUES
+  ('Alternative Rock', 'Rock'), ('Indie Rock', 'Rock'), ('Punk', 'Rock'),
+  ('Hard Rock', 'Rock'), ('Metal', 'Rock'), ('Synthpop', 'Pop'),
+  ('Indie Pop', 'Pop'), ('K-Pop', 'Pop'), ('House', 'Electronic'),
+  ('Techno', 'Electronic'), ('Trance', 'Electronic'), ('Drum and Bass', 'Electronic'),
+  ('Dubstep', 'Electronic'), ('Ambient', 'Electronic'), ('Trap', 'Hip Hop'),
+  ('Soul', 'R&B'), ('Funk', 'R&B')
+) AS g(name, parent)
+JOIN "genres" p ON p.name = g.parent;
+
+INSERT INTO "genre_aliases" ("alias", "genre_id")
+SELECT genre_key(g.name), g.id FROM "genres" g
+UNION ALL
+SELECT genre_key(a.alias), g.id
+FROM (VALUES
+  ('hiphop', 'Hip Hop'), ('rap', 'Hip Hop'), ('rnb', 'R&B'),
+  ('rhythm and blues', 'R&B'), ('dnb', 'Drum and Bass'), ('drum n bass', 'Drum and Bass'),
+  ('electronica', 'Electronic'), ('edm', 'Electronic'), ('synth pop', 'Synthpop'),
+  ('kpop', 'K-Pop'), ('alt rock', 'Alternative Rock'), ('alternative', 'Alternative Rock'),
+  ('punk rock', 'Punk'), ('heavy metal', 'Metal')
+) AS a(alias, genre)
+JOIN "genres" g ON g.name = a.genre;
+
+CREATE TABLE "schema_migrations" (
+  "name" VARCHAR(100) PRIMARY KEY,
+  "applied_at" TIMESTAMP DEFAULT (NOW())
+);
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// DataExportHandler godoc
+// @Summary Get my data export
+// @Description Returns the status of the current user's latest personal data export. Completed exports include a download URL until they expire.
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DataExportJob "Export is ready, failed or expired"
+// @Success 202 {object} models.DataExportJob "Export is being prepared"
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/export [get]
+func (r *Router) DataExportHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	job, err := repo.GetLatestDataExportJob(userID)
+	if err != nil {
+		slog.Error("Failed to get export job", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get export status", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		utils.JSONError(w, api_errors.ErrNotFound, "no export requested", http.StatusNotFound)
+		return
+	}
+
+	switch job.Status {
+	case models.ExportStatusPending, models.ExportStatusProcessing:
+		utils.JSONSuccess(w, job, http.StatusAccepted)
+		return
+	case models.ExportStatusCompleted:
+		if job.ExpiresAt == nil || job.ExpiresAt.After(time.Now()) {
+			job.DownloadURL = "/api/me/export/download"
+		}
+	}
+	utils.JSONSuccess(w, job, http.StatusOK)
+}
+
+// RequestDataExportHandler godoc
+// @Summary Request a data export
+// @Description Queues a personal data export of the current user. If an export is already queued or being prepared, that one is returned instead. Poll GET /api/me/export for its status; a notification is sent when it is ready.
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 202 {object} models.DataExportJob "Export is being prepared"
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/export [post]
+func (r *Router) RequestDataExportHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	job, created, err := repo.CreateDataExportJob(userID)
+	if err != nil {
+		slog.Error("Failed to create export job", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to start export", http.StatusInternalServerError)
+		return
+	}
+	if created {
+		slog.Info("Data export queued", "job_id", job.ID, "user_id", userID)
+	}
+
+	utils.JSONSuccess(w, job, http.StatusAccepted)
+}
+
+// DownloadDataExportHandler godoc
+// @Summary Download my data export
+// @Description Downloads the ZIP archive of the current user's latest completed data export
+// @Tags Account
+// @Produce application/zip
+// @Security ApiKeyAuth
+// @Success 200 {file} binary "Export archive"
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/export/download [get]
+func (r *Router) DownloadDataExportHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	job, err := repo.GetLatestDataExportJob(userID)
+	if err != nil {
+		slog.Error("Failed to get export job", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get export", http.StatusInternalServerError)
+		return
+	}
+	if job == nil || job.Status != models.ExportStatusCompleted || job.ObjectName == nil ||
+		(job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now())) {
+		utils.JSONError(w, api_errors.ErrNotFound, "no export available", http.StatusNotFound)
+		return
+	}
+
+	objInfo, err := r.Storage.GetObjectInfo(req.Context(), *job.ObjectName)
+	if err != nil {
+		slog.Error("Failed to get export info", "error", err, "object_name", *job.ObjectName)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get export", http.StatusInternalServerError)
+		return
+	}
+
+	obj, err := r.Storage.GetObject(req.Context(), *job.ObjectName, minio.GetObjectOptions{})
+	if err != nil {
+		slog.Error("Failed to get export object", "error", err, "object_name", *job.ObjectName)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get export", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, job.CreatedAt.Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, obj); err != nil {
+		slog.Error("Error streaming export", "error", err, "job_id", job.ID)
+	}
+}
+
+// DeleteAccountHandler godoc
+// @Summary Delete my account
+// @Description Schedules the current user's account for deletion after a cooling-off period. The deletion can be cancelled until then. Requires the current password.
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param body body models.DeleteAccountRequest true "Current password"
+// @Success 202 {object} models.AccountDeletion
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me [delete]
+func (r *Router) DeleteAccountHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var body models.DeleteAccountRequest
+	if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+		return
+	}
+	if body.Password == "" {
+		utils.JSONError(w, api_errors.ErrMissingFields, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	hash, err := repo.GetUserPasswordHash(userID)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrUserNotFound, "user not found", http.StatusNotFound)
+		return
+	}
+	if !utils.CheckPasswordHash(body.Password, hash) {
+		utils.JSONError(w, api_errors.ErrInvalidCredentials, "invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	scheduledFor := time.Now().AddDate(0, 0, r.Config.AccountDeletionGraceDays)
+	deletion, err := repo.ScheduleAccountDeletion(userID, scheduledFor)
+	if err != nil {
+		slog.Error("Failed to schedule account deletion", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to schedule account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Account deletion scheduled", "user_id", userID, "scheduled_for", deletion.ScheduledFor)
+	utils.JSONSuccess(w, deletion, http.StatusAccepted)
+}
+
+// GetAccountDeletionHandler godoc
+// @Summary Get account deletion status
+// @Description Returns the pending deletion of the current user's account, if any
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AccountDeletion
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/deletion [get]
+func (r *Router) GetAccountDeletionHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	deletion, err := repo.GetAccountDeletion(userID)
+	if err != nil {
+		slog.Error("Failed to get account deletion", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get account deletion", http.StatusInternalServerError)
+		return
+	}
+	if deletion == nil {
+		utils.JSONError(w, api_errors.ErrNotFound, "no pending account deletion", http.StatusNotFound)
+		return
+	}
+
+	utils.JSONSuccess(w, deletion, http.StatusOK)
+}
+
+// CancelAccountDeletionHandler godoc
+// @Summary Cancel account deletion
+// @Description Cancels the pending deletion of the current user's account
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/deletion [delete]
+func (r *Router) CancelAccountDeletionHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.CancelAccountDeletion(userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.JSONError(w, api_errors.ErrNotFound, "no pending account deletion", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to cancel account deletion", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to cancel account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "account deletion cancelled"}, http.StatusOK)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"log/slog"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/middleware"
 	"music-app/backend/internal/models"
 	"music-app/backend/internal/repository"
@@ -120,6 +121,14 @@ func (r *Router) CreateAlbumHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	r.notifyFollowers(artist.ID, models.NewNotification{
+		Type:       models.NotificationNewRelease,
+		ActorID:    &artist.ID,
+		EntityType: "album",
+		EntityID:   &album.ID,
+		Message:    fmt.Sprintf("%s released a new album: %s", artist.Username, album.Title),
+	})
+
 	utils.JSONSuccess(w, album, http.StatusCreated)
 }
 
@@ -172,15 +181,29 @@ func (r *Router) GetAlbumHandler(w http.ResponseWriter, req *http.Request) {
 
 // GetAlbumsHandler godoc
 // @Summary Get all albums
-// @Description Retrieves all albums
+// @Description Retrieves all albums, newest first unless sorted otherwise. Genre, duration, popularity and likes are those of the album's tracks, and year is the release year.
 // @Tags Albums
 // @Produce json
+// @Param genre query []string false "Only albums with tracks of these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum total duration in seconds"
+// @Param max_duration query int false "Maximum total duration in seconds"
+// @Param year query int false "Only albums released in this year"
+// @Param artist_id query int false "Only albums by this artist"
+// @Param sort query string false "popularity, title, duration, newest or most_liked"
+// @Param order query string false "asc or desc (default depends on sort)"
 // @Success 200 {array} models.AlbumWithTracks
+// @Failure 400 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/albums [get]
 func (r *Router) GetAlbumsHandler(w http.ResponseWriter, req *http.Request) {
+	opts, err := listing.Albums.Parse(req.URL.Query())
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	repo := repository.NewRepository(r.Db)
-	albums, err := repo.GetAllAlbums()
+	albums, err := repo.GetAllAlbums(opts)
 	if err != nil {
 		slog.Error("Failed to get albums", "error", err)
 		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get albums", http.StatusInternalServerError)
@@ -267,7 +290,7 @@ func (r *Router) RemoveTrackFromAlbumHandler(w http.ResponseWriter, req *http.Re
 
 // SearchAlbumsHandler godoc
 // @Summary Search albums
-// @Description Search for albums by title or artist
+// @Description Search for albums by title or artist, best matches first, ignoring case and accents and tolerating small typos. Matched fields are returned in highlights with matches wrapped in <mark>.
 // @Tags Albums
 // @Produce json
 // @Param q query string true "Search query"
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"music-app/backend/internal/api/auth"
 	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/notifications"
+	"music-app/backend/internal/suggest"
 	"music-app/backend/pkg/config"
 	"music-app/backend/pkg/storage"
 	"net/http"
@@ -18,21 +21,27 @@ var (
 	ValidAudioTypes = []string{"audio/mpeg", "audio/mp3", "audio/wav", "audio/flac"}
 	// MaxUploadSize defines the maximum file size for uploads (10MB)
 	MaxUploadSize int64 = 10 << 20
+	// MaxDataImportSize is the maximum size of an uploaded data export from another service (100MB)
+	MaxDataImportSize int64 = 100 << 20
 )
 
 type Router struct {
-	Db         *sql.DB
-	JWTManager *utils.JWTManager
-	Config     *config.Config
-	Storage    *storage.MinioClient
+	Db            *sql.DB
+	JWTManager    *utils.JWTManager
+	Config        *config.Config
+	Storage       *storage.MinioClient
+	Notifications *notifications.Hub
+	Suggestions   *suggest.Index
 }
 
-func NewRouter(db *sql.DB, jwtManager *utils.JWTManager, cfg *config.Config, storage *storage.MinioClient) *Router {
+func NewRouter(db *sql.DB, jwtManager *utils.JWTManager, cfg *config.Config, storage *storage.MinioClient, hub *notifications.Hub, suggestions *suggest.Index) *Router {
 	return &Router{
-		Db:         db,
-		JWTManager: jwtManager,
-		Config:     cfg,
-		Storage:    storage,
+		Db:            db,
+		JWTManager:    jwtManager,
+		Config:        cfg,
+		Storage:       storage,
+		Notifications: hub,
+		Suggestions:   suggestions,
 	}
 }
 
@@ -53,25 +62,76 @@ func (r *Router) NewRouter() *mux.Router {
 	router.HandleFunc("/api/login", h.LoginHandler).Methods(http.MethodPost, http.MethodOptions)
 	router.HandleFunc("/api/refresh", h.RefreshHandler).Methods(http.MethodPost, http.MethodOptions)
 	router.HandleFunc("/api/logout", h.LogoutHandler).Methods(http.MethodPost, http.MethodOptions)
-	router.HandleFunc("/api/tracks", r.GetTracksHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/search", r.SearchHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/search/albums", r.SearchAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/search/users", r.SearchUsersHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/albums", r.GetAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/albums/{id}", r.GetAlbumHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/tracks/{id}/stream", r.StreamTrackHandler).Methods(http.MethodGet, http.MethodOptions)
+
+	// Public catalog routes (personalized when a valid bearer token is sent)
+	catalog := router.PathPrefix("/api").Subrouter()
+	catalog.HandleFunc("/tracks", r.GetTracksHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tracks/{id}/stream", r.StreamTrackHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search", r.SearchHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search/all", r.SearchAllHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search/suggest", r.SearchSuggestHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search/albums", r.SearchAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search/users", r.SearchUsersHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/search/artists", r.SearchArtistsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/albums", r.GetAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/albums/{id}", r.GetAlbumHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/playlists/{id}", r.GetPlaylistHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/playlists/{id}/export", r.ExportPlaylistHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/shared/playlists/{token}", r.GetSharedPlaylistHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tracks/{id}/comments", r.GetTrackCommentsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tracks/{id}/comments/timeline", r.GetTrackCommentTimelineHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tracks/{id}/lyrics", r.GetTrackLyricsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tags", r.GetTagsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tags/popular", r.GetPopularTagsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/tags/{name}/tracks", r.GetTagTracksHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/genres", r.GetGenresHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/genres/{slug}", r.GetGenreHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/genres/{slug}/tracks", r.GetGenreTracksHandler).Methods(http.MethodGet, http.MethodOptions)
 
 	// Artist routes (public)
-	router.HandleFunc("/api/artists/{id}", r.GetArtistHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/artists/{id}/top-tracks", r.GetArtistTopTracksHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/artists/{id}/albums", r.GetArtistAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/artists/{id}/tracks", r.GetArtistTracksHandler).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc("/api/search/artists", r.SearchArtistsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists", r.GetArtistsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}", r.GetArtistHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}/top-tracks", r.GetArtistTopTracksHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}/albums", r.GetArtistAlbumsHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}/tracks", r.GetArtistTracksHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}/followers", r.GetFollowersHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/artists/{id}/following", r.GetFollowingHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/users/{id}/followers", r.GetFollowersHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.HandleFunc("/users/{id}/following", r.GetFollowingHandler).Methods(http.MethodGet, http.MethodOptions)
+	catalog.Use(authMiddleware.OptionalAuthenticated)
+
+	// Notification stream (EventSource cannot send headers, so the token may be passed as a query parameter)
+	router.Handle("/api/notifications/stream", authMiddleware.AuthenticatedStream(http.HandlerFunc(r.NotificationStreamHandler))).Methods(http.MethodGet, http.MethodOptions)
 
 	// Protected routes (authenticated users)
 	protected := router.PathPrefix("/api").Subrouter()
 	protected.HandleFunc("/me", r.MeHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me", r.DeleteAccountHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/me/export", r.DataExportHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/export", r.RequestDataExportHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/me/export/download", r.DownloadDataExportHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/imports", r.StartImportHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/me/imports", r.GetImportJobsHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/imports/{id}", r.GetImportJobHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/deletion", r.GetAccountDeletionHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/deletion", r.CancelAccountDeletionHandler).Methods(http.MethodDelete, http.MethodOptions)
 	protected.HandleFunc("/users", r.GetUsersHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/users/{id}/follow", r.FollowUserHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/users/{id}/follow", r.UnfollowUserHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/artists/{id}/follow", r.FollowArtistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/artists/{id}/follow", r.UnfollowArtistHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/feed/releases", r.GetReleaseFeedHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/albums/{id}/save", r.SaveAlbumHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/albums/{id}/save", r.UnsaveAlbumHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/library", r.GetLibraryHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/library/folders", r.CreateLibraryFolderHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/library/folders/{id}", r.UpdateLibraryFolderHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/library/folders/{id}", r.DeleteLibraryFolderHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/library/move", r.MoveLibraryItemHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/library/pin", r.PinLibraryItemHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/notifications", r.GetNotificationsHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/notifications/read-all", r.MarkAllNotificationsReadHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/notifications/{id}/read", r.MarkNotificationReadHandler).Methods(http.MethodPost, http.MethodOptions)
 	protected.HandleFunc("/profile", h.GetProfileHandler).Methods(http.MethodGet, http.MethodOptions)
 	protected.HandleFunc("/profile", h.UpdateProfileHandler).Methods(http.MethodPut, http.MethodOptions)
 	protected.HandleFunc("/profile/avatar", r.UploadAvatarHandler).Methods(http.MethodPost, http.MethodOptions)
@@ -82,31 +142,90 @@ func (r *Router) NewRouter() *mux.Router {
 	protected.HandleFunc("/favorites", r.GetFavoritesHandler).Methods(http.MethodGet, http.MethodOptions)
 	protected.HandleFunc("/tracks/{id}/like", r.LikeTrackHandler).Methods(http.MethodPost, http.MethodOptions)
 	protected.HandleFunc("/tracks/{id}/unlike", r.UnlikeTrackHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/tracks/{id}/comments", r.CreateTrackCommentHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/tracks/{id}/lyrics", r.SetTrackLyricsHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/tracks/{id}/lyrics", r.DeleteTrackLyricsHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/tracks/{id}/tags", r.SetTrackTagsHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/tracks/{id}/tags/{name}", r.RemoveTrackTagHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/comments/{id}", r.UpdateCommentHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/comments/{id}", r.DeleteCommentHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/comments/{id}/resolve", r.ResolveCommentHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/comments/{id}/unresolve", r.UnresolveCommentHandler).Methods(http.MethodPost, http.MethodOptions)
 	protected.HandleFunc("/my-tracks", r.GetMyTracksHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/my-tracks/comments", r.GetMyTracksFeedbackHandler).Methods(http.MethodGet, http.MethodOptions)
 	protected.HandleFunc("/my-tracks/{id}", r.UpdateTrackHandler).Methods(http.MethodPut, http.MethodOptions)
 	protected.HandleFunc("/my-tracks/{id}", r.DeleteTrackHandler).Methods(http.MethodDelete, http.MethodOptions)
 	// Playlist routes - GENERIC ROUTES FIRST (without {id})
 	protected.HandleFunc("/playlists", r.CreatePlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
 	protected.HandleFunc("/playlists", r.GetUserPlaylistsHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/playlists/import", r.ImportPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
 	// SPECIFIC ROUTES AFTER GENERIC ONES
-	protected.HandleFunc("/playlists/{id}", r.GetPlaylistHandler).Methods(http.MethodGet, http.MethodOptions)
 	protected.HandleFunc("/playlists/{id}", r.UpdatePlaylistHandler).Methods(http.MethodPut, http.MethodOptions)
 	protected.HandleFunc("/playlists/{id}", r.DeletePlaylistHandler).Methods(http.MethodDelete, http.MethodOptions)
 	protected.HandleFunc("/playlists/{id}/cover", r.UploadPlaylistCoverHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/cover", r.RemovePlaylistCoverHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/rules", r.SetPlaylistRulesHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/rules", r.ClearPlaylistRulesHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/refresh", r.RefreshSmartPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/history", r.GetPlaylistHistoryHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/history/{revisionId}", r.GetPlaylistRevisionHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/history/{revisionId}/restore", r.RestorePlaylistRevisionHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/recover", r.RecoverPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/fork", r.ForkPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/follow", r.FollowPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/follow", r.UnfollowPlaylistHandler).Methods(http.MethodDelete, http.MethodOptions)
 	protected.HandleFunc("/playlists/{id}/tracks", r.AddTrackToPlaylistHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/tracks", r.ReorderPlaylistTracksHandler).Methods(http.MethodPatch, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/tracks/bulk", r.BulkAddTracksHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/tracks/bulk", r.BulkRemoveTracksHandler).Methods(http.MethodDelete, http.MethodOptions)
 	protected.HandleFunc("/playlists/{id}/tracks/{trackId}", r.RemoveTrackFromPlaylistHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/entries/{entryId}", r.RemovePlaylistEntryHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/collaborators", r.GetPlaylistCollaboratorsHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/collaborators", r.InvitePlaylistCollaboratorHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/collaborators/accept", r.AcceptPlaylistInviteHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/collaborators/{userId}", r.UpdatePlaylistCollaboratorHandler).Methods(http.MethodPut, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/collaborators/{userId}", r.RemovePlaylistCollaboratorHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/share-links", r.GetPlaylistShareLinksHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/share-links", r.CreatePlaylistShareLinkHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/playlists/{id}/share-links/{linkId}", r.RevokePlaylistShareLinkHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/me/playlist-invites", r.GetPlaylistInvitesHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/deleted-playlists", r.GetDeletedPlaylistsHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/recent-searches", r.GetRecentSearchesHandler).Methods(http.MethodGet, http.MethodOptions)
+	protected.HandleFunc("/me/recent-searches", r.AddRecentSearchHandler).Methods(http.MethodPost, http.MethodOptions)
+	protected.HandleFunc("/me/recent-searches", r.ClearRecentSearchesHandler).Methods(http.MethodDelete, http.MethodOptions)
+	protected.HandleFunc("/me/recent-searches/{id}", r.DeleteRecentSearchHandler).Methods(http.MethodDelete, http.MethodOptions)
 	protected.Use(authMiddleware.Authenticated)
 
-	// Admin routes (requires admin role - verified via database query)
+	// Permission-gated routes (verified via role_permissions in the database)
 	admin := router.PathPrefix("/api").Subrouter()
-	admin.HandleFunc("/admin/dashboard", r.GetAdminDashboardHandler).Methods(http.MethodGet, http.MethodOptions)
-	admin.HandleFunc("/tracks/upload", r.CreateTrackHandler).Methods(http.MethodPost, http.MethodOptions)
-	admin.HandleFunc("/albums", r.CreateAlbumHandler).Methods(http.MethodPost, http.MethodOptions)
-	admin.HandleFunc("/albums/{id}", r.DeleteAlbumHandler).Methods(http.MethodDelete, http.MethodOptions)
-	admin.HandleFunc("/albums/{id}/tracks", r.AddTrackToAlbumHandler).Methods(http.MethodPost, http.MethodOptions)
-	admin.HandleFunc("/albums/{id}/tracks/{trackId}", r.RemoveTrackFromAlbumHandler).Methods(http.MethodDelete, http.MethodOptions)
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return authMiddleware.RequirePermission(permission)(handler)
+	}
+	admin.Handle("/admin/dashboard", requirePermission(models.PermAdminDashboard, r.GetAdminDashboardHandler)).Methods(http.MethodGet, http.MethodOptions)
+	admin.Handle("/tracks/upload", requirePermission(models.PermTracksUpload, r.CreateTrackHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/tracks/{id}/publish", requirePermission(models.PermTracksPublish, r.PublishTrackHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/albums", requirePermission(models.PermAlbumsManage, r.CreateAlbumHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/albums/{id}", requirePermission(models.PermAlbumsManage, r.DeleteAlbumHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/albums/{id}/tracks", requirePermission(models.PermAlbumsManage, r.AddTrackToAlbumHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/albums/{id}/tracks/{trackId}", requirePermission(models.PermAlbumsManage, r.RemoveTrackFromAlbumHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/admin/tags", requirePermission(models.PermTagsManage, r.CreateTagHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/tags/{id}", requirePermission(models.PermTagsManage, r.UpdateTagHandler)).Methods(http.MethodPut, http.MethodOptions)
+	admin.Handle("/admin/tags/{id}", requirePermission(models.PermTagsManage, r.DeleteTagHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/admin/genres", requirePermission(models.PermGenresManage, r.CreateGenreHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/genres/{id}", requirePermission(models.PermGenresManage, r.UpdateGenreHandler)).Methods(http.MethodPut, http.MethodOptions)
+	admin.Handle("/admin/genres/{id}/merge/{target}", requirePermission(models.PermGenresManage, r.MergeGenreHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/genres/{id}/aliases", requirePermission(models.PermGenresManage, r.AddGenreAliasHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/genres/{id}/aliases/{alias}", requirePermission(models.PermGenresManage, r.DeleteGenreAliasHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/admin/roles", requirePermission(models.PermRolesManage, r.GetRolesHandler)).Methods(http.MethodGet, http.MethodOptions)
+	admin.Handle("/admin/roles/{role}/permissions", requirePermission(models.PermRolesManage, r.GrantRolePermissionHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/roles/{role}/permissions/{permission}", requirePermission(models.PermRolesManage, r.RevokeRolePermissionHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/admin/permissions", requirePermission(models.PermRolesManage, r.GetPermissionsHandler)).Methods(http.MethodGet, http.MethodOptions)
+	admin.Handle("/admin/users/{id}/roles", requirePermission(models.PermRolesManage, r.GetUserRolesHandler)).Methods(http.MethodGet, http.MethodOptions)
+	admin.Handle("/admin/users/{id}/roles", requirePermission(models.PermRolesManage, r.AssignUserRoleHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/admin/users/{id}/roles/{role}", requirePermission(models.PermRolesManage, r.RevokeUserRoleHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	admin.Handle("/moderation/tracks/{id}/takedown", requirePermission(models.PermTracksTakedown, r.TakedownTrackHandler)).Methods(http.MethodPost, http.MethodOptions)
+	admin.Handle("/moderation/tracks/{id}/restore", requirePermission(models.PermTracksTakedown, r.RestoreTrackHandler)).Methods(http.MethodPost, http.MethodOptions)
 	admin.Use(authMiddleware.Authenticated)
-	admin.Use(authMiddleware.RequireAdmin)
 
 	return router
 }
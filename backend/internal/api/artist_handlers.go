@@ -3,6 +3,8 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"log/slog"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/middleware"
 	"music-app/backend/internal/repository"
 	"music-app/backend/pkg/api_errors"
@@ -14,7 +16,7 @@ import (
 
 // GetArtistHandler godoc
 // @Summary Get artist details
-// @Description Retrieves detailed information about a specific artist including statistics
+// @Description Retrieves detailed information about a specific artist including statistics and follower counts
 // @Tags artists
 // @Accept json
 // @Produce json
@@ -45,6 +47,13 @@ func (r *Router) GetArtistHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if userID, ok := middleware.GetUserID(req.Context()); ok && userID != artistID {
+		artist.IsFollowing, err = repo.IsFollowing(userID, artistID)
+		if err != nil {
+			slog.Error("Failed to check follow status", "error", err, "artist_id", artistID)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(artist)
 }
@@ -99,11 +108,17 @@ func (r *Router) GetArtistTopTracksHandler(w http.ResponseWriter, req *http.Requ
 
 // GetArtistAlbumsHandler godoc
 // @Summary Get artist's albums
-// @Description Retrieves all albums for a specific artist
+// @Description Retrieves all albums for a specific artist, latest release first unless sorted otherwise. Takes the filters and sorts of GET /api/albums.
 // @Tags artists
 // @Accept json
 // @Produce json
 // @Param id path int true "Artist ID"
+// @Param genre query []string false "Only albums with tracks of these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum total duration in seconds"
+// @Param max_duration query int false "Maximum total duration in seconds"
+// @Param year query int false "Only albums released in this year"
+// @Param sort query string false "popularity, title, duration, newest or most_liked"
+// @Param order query string false "asc or desc (default depends on sort)"
 // @Success 200 {array} models.Album
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -119,8 +134,14 @@ func (r *Router) GetArtistAlbumsHandler(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	opts, err := listing.Albums.Parse(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	repo := repository.NewRepository(r.Db)
-	albums, err := repo.GetArtistAlbums(artistID)
+	albums, err := repo.GetArtistAlbums(artistID, opts)
 	if err != nil {
 		http.Error(w, api_errors.InternalServerError, http.StatusInternalServerError)
 		return
@@ -132,11 +153,22 @@ func (r *Router) GetArtistAlbumsHandler(w http.ResponseWriter, req *http.Request
 
 // GetArtistTracksHandler godoc
 // @Summary Get artist's all tracks
-// @Description Retrieves all tracks for a specific artist
+// @Description Retrieves all tracks for a specific artist, newest first unless sorted otherwise. Takes the filters and sorts of GET /api/tracks.
 // @Tags artists
 // @Accept json
 // @Produce json
 // @Param id path int true "Artist ID"
+// @Param genre query []string false "Only these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param genre_id query int false "Only tracks in this genre or its sub-genres"
+// @Param tag query []string false "Only tracks with one of these tags, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum duration in seconds"
+// @Param max_duration query int false "Maximum duration in seconds"
+// @Param year query int false "Only tracks released in this year"
+// @Param has_lyrics query bool false "Only tracks with (true) or without (false) lyrics"
+// @Param min_bitrate query int false "Minimum bitrate in kbps"
+// @Param max_bitrate query int false "Maximum bitrate in kbps"
+// @Param sort query string false "popularity, title, duration, newest or most_liked"
+// @Param order query string false "asc or desc (default depends on sort)"
 // @Success 200 {array} models.TrackWithArtist
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -158,8 +190,14 @@ func (r *Router) GetArtistTracksHandler(w http.ResponseWriter, req *http.Request
 		userID = &id
 	}
 
+	opts, err := listing.Tracks.Parse(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	repo := repository.NewRepository(r.Db)
-	tracks, err := repo.GetArtistTracks(artistID, userID)
+	tracks, err := repo.GetArtistTracks(artistID, userID, opts)
 	if err != nil {
 		http.Error(w, api_errors.InternalServerError, http.StatusInternalServerError)
 		return
@@ -169,9 +207,57 @@ func (r *Router) GetArtistTracksHandler(w http.ResponseWriter, req *http.Request
 	json.NewEncoder(w).Encode(tracks)
 }
 
+// GetArtistsHandler godoc
+// @Summary Get artists
+// @Description Retrieves artists (users with published tracks or albums) with their stats, most listened first unless sorted otherwise. An artist matches a genre when they released a track in it and a year when they released a track or album in it; popularity and likes are those of their tracks, title sorts by name and newest by latest track.
+// @Tags artists
+// @Accept json
+// @Produce json
+// @Param genre query []string false "Only artists with tracks of these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param year query int false "Only artists who released something in this year"
+// @Param sort query string false "popularity, title, newest or most_liked"
+// @Param order query string false "asc or desc (default depends on sort)"
+// @Param limit query int false "Number of artists to return (default 50)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} models.ArtistWithStats
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/artists [get]
+func (r *Router) GetArtistsHandler(w http.ResponseWriter, req *http.Request) {
+	opts, err := listing.Artists.Parse(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := req.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	artists, err := repo.ListArtists(opts, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list artists", "error", err)
+		http.Error(w, api_errors.InternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(artists)
+}
+
 // SearchArtistsHandler godoc
 // @Summary Search for artists
-// @Description Search for artists by username
+// @Description Search for artists by username, best matches first, ignoring case and accents and tolerating small typos. The matched username is returned in highlights with matches wrapped in <mark>.
 // @Tags artists
 // @Accept json
 // @Produce json
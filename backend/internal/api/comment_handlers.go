@@ -0,0 +1,617 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxCommentLength is the maximum number of characters in a comment
+const MaxCommentLength = 2000
+
+// GetTrackCommentsHandler godoc
+// @Summary Get track comments
+// @Description Retrieves a page of top-level comments on a track, newest first, with their replies
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Track ID"
+// @Param limit query int false "Number of comments to return (default 20, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.CommentsResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/comments [get]
+func (r *Router) GetTrackCommentsHandler(w http.ResponseWriter, req *http.Request) {
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	offset := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := req.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if r.getVisibleTrack(w, req, repo, trackID) == nil {
+		return
+	}
+
+	comments, total, err := repo.GetTrackComments(trackID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get comments", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get comments", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.CommentsResponse{
+		Comments: comments,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}, http.StatusOK)
+}
+
+// CreateTrackCommentHandler godoc
+// @Summary Comment on a track
+// @Description Adds a comment to a track. Set parent_id to reply to another comment, or position_ms to pin a top-level comment to a moment in the track.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Param body body models.CreateCommentRequest true "Comment"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/comments [post]
+func (r *Router) CreateTrackCommentHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	var body models.CreateCommentRequest
+	if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+		return
+	}
+	content, ok := validateCommentContent(w, body.Content)
+	if !ok {
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	track := r.getVisibleTrack(w, req, repo, trackID)
+	if track == nil {
+		return
+	}
+
+	if body.PositionMs != nil {
+		if body.ParentID != nil {
+			utils.JSONError(w, api_errors.ErrValidationError, "replies cannot have a position", http.StatusBadRequest)
+			return
+		}
+		if track.Duration <= 0 {
+			utils.JSONError(w, api_errors.ErrValidationError, "track duration is unknown", http.StatusBadRequest)
+			return
+		}
+		if *body.PositionMs < 0 || *body.PositionMs > track.Duration*1000 {
+			utils.JSONError(w, api_errors.ErrValidationError, fmt.Sprintf("position_ms must be between 0 and %d", track.Duration*1000), http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment, err := repo.CreateComment(trackID, userID, body.ParentID, body.PositionMs, content)
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			utils.JSONError(w, api_errors.ErrCommentNotFound, "parent comment not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to create comment", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to create comment", http.StatusInternalServerError)
+		return
+	}
+
+	r.notifyComment(repo, track, comment)
+
+	utils.JSONSuccess(w, comment, http.StatusCreated)
+}
+
+// notifyComment tells the track owner about a new comment and, for replies, the author
+// of the thread. Nobody is notified about their own comment or notified twice.
+func (r *Router) notifyComment(repo *repository.Repository, track *models.Track, comment *models.Comment) {
+	notified := map[int]bool{comment.UserID: true}
+
+	if comment.ParentID != nil {
+		parent, err := repo.GetCommentByID(*comment.ParentID)
+		if err == nil && !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			r.notify(models.NewNotification{
+				UserID:     parent.UserID,
+				Type:       models.NotificationCommentReply,
+				ActorID:    &comment.UserID,
+				EntityType: "comment",
+				EntityID:   &comment.ID,
+				Message:    fmt.Sprintf("%s replied to your comment on %s", comment.Username, track.Title),
+			})
+		}
+	}
+
+	if !notified[track.ArtistID] {
+		r.notify(models.NewNotification{
+			UserID:     track.ArtistID,
+			Type:       models.NotificationComment,
+			ActorID:    &comment.UserID,
+			EntityType: "comment",
+			EntityID:   &comment.ID,
+			Message:    fmt.Sprintf("%s commented on %s", comment.Username, track.Title),
+		})
+	}
+}
+
+// UpdateCommentHandler godoc
+// @Summary Edit a comment
+// @Description Edits the content of a comment. Only the author can edit a comment.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Param body body models.UpdateCommentRequest true "New content"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/comments/{id} [put]
+func (r *Router) UpdateCommentHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var body models.UpdateCommentRequest
+	if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+		return
+	}
+	content, ok := validateCommentContent(w, body.Content)
+	if !ok {
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	existing, err := repo.GetCommentByID(commentID)
+	if err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+	if existing.UserID != userID {
+		utils.JSONError(w, api_errors.ErrForbidden, "you can only edit your own comments", http.StatusForbidden)
+		return
+	}
+
+	comment, err := repo.UpdateComment(commentID, content)
+	if err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+
+	utils.JSONSuccess(w, comment, http.StatusOK)
+}
+
+// DeleteCommentHandler godoc
+// @Summary Delete a comment
+// @Description Deletes a comment and its replies. Authors can delete their own comments; users with the comments.moderate permission can delete any comment.
+// @Tags Comments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/comments/{id} [delete]
+func (r *Router) DeleteCommentHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	comment, err := repo.GetCommentByID(commentID)
+	if err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+
+	moderated := false
+	if comment.UserID != userID {
+		allowed, err := repo.UserHasPermission(userID, models.PermCommentsModerate)
+		if err != nil {
+			slog.Error("Failed to check permission", "error", err, "user_id", userID)
+			utils.JSONError(w, api_errors.ErrInternalServer, "failed to check permissions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			utils.JSONError(w, api_errors.ErrForbidden, "you can only delete your own comments", http.StatusForbidden)
+			return
+		}
+		moderated = true
+	}
+
+	if err := repo.DeleteComment(commentID); err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+
+	if moderated {
+		if err := repo.LogAdminAction(fmt.Sprintf("comment.delete:track=%d", comment.TrackID), userID, &commentID); err != nil {
+			slog.Warn("Failed to log admin action", "error", err)
+		}
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "comment deleted"}, http.StatusOK)
+}
+
+// GetTrackCommentTimelineHandler godoc
+// @Summary Get timestamped comments along the track timeline
+// @Description Returns the top-level comments pinned to a position in the track, grouped into equally sized buckets for rendering over the waveform. Only non-empty buckets are returned.
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Track ID"
+// @Param buckets query int false "Number of buckets to divide the track into (default 100, max 1000)"
+// @Param per_bucket query int false "Maximum number of comments returned per bucket (default 5, max 50)"
+// @Success 200 {object} models.CommentTimelineResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/comments/timeline [get]
+func (r *Router) GetTrackCommentTimelineHandler(w http.ResponseWriter, req *http.Request) {
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	bucketCount := 100
+	if b := req.URL.Query().Get("buckets"); b != "" {
+		if parsed, err := strconv.Atoi(b); err == nil && parsed > 0 {
+			bucketCount = parsed
+			if bucketCount > 1000 {
+				bucketCount = 1000
+			}
+		}
+	}
+	perBucket := 5
+	if p := req.URL.Query().Get("per_bucket"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed >= 0 {
+			perBucket = parsed
+			if perBucket > 50 {
+				perBucket = 50
+			}
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	track := r.getVisibleTrack(w, req, repo, trackID)
+	if track == nil {
+		return
+	}
+
+	comments, err := repo.GetTimedComments(trackID)
+	if err != nil {
+		slog.Error("Failed to get timed comments", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get comments", http.StatusInternalServerError)
+		return
+	}
+
+	// Fall back to the last comment if the duration was never recorded
+	durationMs := track.Duration * 1000
+	if len(comments) > 0 && *comments[len(comments)-1].PositionMs > durationMs {
+		durationMs = *comments[len(comments)-1].PositionMs
+	}
+	bucketSize := (durationMs + bucketCount - 1) / bucketCount
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+
+	buckets := []models.CommentTimelineBucket{}
+	for _, c := range comments {
+		// A comment at the very end belongs to the last bucket
+		index := *c.PositionMs / bucketSize
+		if index >= bucketCount {
+			index = bucketCount - 1
+		}
+		start := index * bucketSize
+		if len(buckets) == 0 || buckets[len(buckets)-1].StartMs != start {
+			buckets = append(buckets, models.CommentTimelineBucket{
+				StartMs:  start,
+				EndMs:    start + bucketSize,
+				Comments: []models.Comment{},
+			})
+		}
+		bucket := &buckets[len(buckets)-1]
+		bucket.Count++
+		if len(bucket.Comments) < perBucket {
+			bucket.Comments = append(bucket.Comments, c)
+		}
+	}
+
+	utils.JSONSuccess(w, models.CommentTimelineResponse{
+		TrackID:      trackID,
+		DurationMs:   durationMs,
+		BucketSizeMs: bucketSize,
+		Buckets:      buckets,
+	}, http.StatusOK)
+}
+
+// ResolveCommentHandler godoc
+// @Summary Resolve a comment
+// @Description Marks feedback on a track as resolved. Only the track's artist can resolve comments.
+// @Tags Comments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/comments/{id}/resolve [post]
+func (r *Router) ResolveCommentHandler(w http.ResponseWriter, req *http.Request) {
+	r.setCommentResolved(w, req, true)
+}
+
+// UnresolveCommentHandler godoc
+// @Summary Unresolve a comment
+// @Description Marks resolved feedback on a track as unresolved again. Only the track's artist can unresolve comments.
+// @Tags Comments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Comment ID"
+// @Success 200 {object} models.Comment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/comments/{id}/unresolve [post]
+func (r *Router) UnresolveCommentHandler(w http.ResponseWriter, req *http.Request) {
+	r.setCommentResolved(w, req, false)
+}
+
+func (r *Router) setCommentResolved(w http.ResponseWriter, req *http.Request, resolved bool) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	comment, err := repo.GetCommentByID(commentID)
+	if err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+	if comment.ParentID != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, "only top-level comments can be resolved", http.StatusBadRequest)
+		return
+	}
+
+	ownerID, err := repo.GetTrackOwner(comment.TrackID)
+	if err != nil {
+		slog.Error("Failed to get track owner", "error", err, "track_id", comment.TrackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get track", http.StatusInternalServerError)
+		return
+	}
+	if ownerID != userID {
+		utils.JSONError(w, api_errors.ErrForbidden, "only the track's artist can resolve comments", http.StatusForbidden)
+		return
+	}
+
+	updated, err := repo.SetCommentResolved(commentID, resolved)
+	if err != nil {
+		writeCommentError(w, err, commentID)
+		return
+	}
+
+	utils.JSONSuccess(w, updated, http.StatusOK)
+}
+
+// GetMyTracksFeedbackHandler godoc
+// @Summary Get feedback on my tracks
+// @Description Retrieves top-level comments left by other users on the current user's tracks, newest first
+// @Tags Comments
+// @Produce json
+// @Security ApiKeyAuth
+// @Param track_id query int false "Only include comments on this track"
+// @Param resolved query bool false "Filter by resolved status (e.g. false for unresolved feedback)"
+// @Param timed query bool false "Only include comments pinned to a position in the track"
+// @Param limit query int false "Number of comments to return (default 20, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.TrackFeedbackResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/my-tracks/comments [get]
+func (r *Router) GetMyTracksFeedbackHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := req.URL.Query()
+
+	var trackID *int
+	if t := query.Get("track_id"); t != "" {
+		parsed, err := strconv.Atoi(t)
+		if err != nil {
+			utils.JSONError(w, api_errors.ErrBadRequest, "invalid track_id", http.StatusBadRequest)
+			return
+		}
+		trackID = &parsed
+	}
+
+	var resolved *bool
+	if v := query.Get("resolved"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.JSONError(w, api_errors.ErrBadRequest, "invalid resolved value", http.StatusBadRequest)
+			return
+		}
+		resolved = &parsed
+	}
+
+	timedOnly := false
+	if v := query.Get("timed"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.JSONError(w, api_errors.ErrBadRequest, "invalid timed value", http.StatusBadRequest)
+			return
+		}
+		timedOnly = parsed
+	}
+
+	limit := 20
+	offset := 0
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	feedback, total, err := repo.GetArtistFeedback(userID, trackID, resolved, timedOnly, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get track feedback", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get feedback", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.TrackFeedbackResponse{
+		Comments: feedback,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}, http.StatusOK)
+}
+
+// getVisibleTrack returns the track if it exists and is published, or the requester
+// is its artist or may manage all tracks. Otherwise it writes an error response and
+// returns nil.
+func (r *Router) getVisibleTrack(w http.ResponseWriter, req *http.Request, repo *repository.Repository, trackID int) *models.Track {
+	track, err := repo.GetTrackByID(trackID)
+	if err != nil {
+		slog.Error("Failed to get track", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get track", http.StatusInternalServerError)
+		return nil
+	}
+	if track != nil && track.Status != "published" {
+		userID, ok := middleware.GetUserID(req.Context())
+		if !ok {
+			track = nil
+		} else if userID != track.ArtistID {
+			canManage, err := repo.UserHasPermission(userID, models.PermTracksManageAll)
+			if err != nil {
+				slog.Error("Failed to check permission", "error", err, "user_id", userID)
+				utils.JSONError(w, api_errors.ErrInternalServer, "failed to check permissions", http.StatusInternalServerError)
+				return nil
+			}
+			if !canManage {
+				track = nil
+			}
+		}
+	}
+	if track == nil {
+		utils.JSONError(w, api_errors.ErrTrackNotFound, "track not found", http.StatusNotFound)
+	}
+	return track
+}
+
+func validateCommentContent(w http.ResponseWriter, content string) (string, bool) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		utils.JSONError(w, api_errors.ErrMissingFields, "content is required", http.StatusBadRequest)
+		return "", false
+	}
+	if utf8.RuneCountInString(content) > MaxCommentLength {
+		utils.JSONError(w, api_errors.ErrValidationError, fmt.Sprintf("content must be at most %d characters", MaxCommentLength), http.StatusBadRequest)
+		return "", false
+	}
+	return content, true
+}
+
+func writeCommentError(w http.ResponseWriter, err error, commentID int) {
+	if errors.Is(err, repository.ErrCommentNotFound) {
+		utils.JSONError(w, api_errors.ErrCommentNotFound, "comment not found", http.StatusNotFound)
+		return
+	}
+	slog.Error("Comment operation failed", "error", err, "comment_id", commentID)
+	utils.JSONError(w, api_errors.ErrInternalServer, "failed to process comment", http.StatusInternalServerError)
+}
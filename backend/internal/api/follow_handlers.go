@@ -0,0 +1,280 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// FollowArtistHandler godoc
+// @Summary Follow an artist
+// @Description Follows an artist, a user with published tracks or albums, so their new releases show up in the feed
+// @Tags Follows
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Artist ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/artists/{id}/follow [post]
+func (r *Router) FollowArtistHandler(w http.ResponseWriter, req *http.Request) {
+	r.setFollow(w, req, true, true)
+}
+
+// UnfollowArtistHandler godoc
+// @Summary Unfollow an artist
+// @Description Stops following an artist
+// @Tags Follows
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Artist ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/artists/{id}/follow [delete]
+func (r *Router) UnfollowArtistHandler(w http.ResponseWriter, req *http.Request) {
+	r.setFollow(w, req, false, true)
+}
+
+// FollowUserHandler godoc
+// @Summary Follow a user
+// @Description Follows another user
+// @Tags Follows
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/users/{id}/follow [post]
+func (r *Router) FollowUserHandler(w http.ResponseWriter, req *http.Request) {
+	r.setFollow(w, req, true, false)
+}
+
+// UnfollowUserHandler godoc
+// @Summary Unfollow a user
+// @Description Stops following another user
+// @Tags Follows
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/users/{id}/follow [delete]
+func (r *Router) UnfollowUserHandler(w http.ResponseWriter, req *http.Request) {
+	r.setFollow(w, req, false, false)
+}
+
+// Artists are users, so artist and user follows share the same table. Only artists can be
+// followed as one, but an artist can always be unfollowed, even once they stopped publishing.
+func (r *Router) setFollow(w http.ResponseWriter, req *http.Request, follow bool, artist bool) {
+	notFoundCode, notFoundMessage := api_errors.ErrUserNotFound, "user not found"
+	if artist {
+		notFoundCode, notFoundMessage = api_errors.ErrArtistNotFound, "artist not found"
+	}
+
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	targetID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if targetID == userID {
+		utils.JSONError(w, api_errors.ErrBadRequest, "you cannot follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	isArtist, err := repo.IsArtist(targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.JSONError(w, notFoundCode, notFoundMessage, http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to get follow target", "error", err, "target_id", targetID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to update follow", http.StatusInternalServerError)
+		return
+	}
+	if artist && follow && !isArtist {
+		utils.JSONError(w, notFoundCode, notFoundMessage, http.StatusNotFound)
+		return
+	}
+
+	message := "followed"
+	created := false
+	if follow {
+		created, err = repo.FollowUser(userID, targetID)
+	} else {
+		err = repo.UnfollowUser(userID, targetID)
+		message = "unfollowed"
+	}
+	if err != nil {
+		slog.Error("Failed to update follow", "error", err, "user_id", userID, "target_id", targetID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to update follow", http.StatusInternalServerError)
+		return
+	}
+
+	if created {
+		if follower, err := repo.GetUserByID(userID); err == nil {
+			r.notify(models.NewNotification{
+				UserID:     targetID,
+				Type:       models.NotificationNewFollower,
+				ActorID:    &userID,
+				EntityType: "user",
+				EntityID:   &userID,
+				Message:    fmt.Sprintf("%s started following you", follower.Username),
+			})
+		}
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": message}, http.StatusOK)
+}
+
+// GetFollowersHandler godoc
+// @Summary Get followers
+// @Description Retrieves the users following an artist or user, most recent first
+// @Tags Follows
+// @Produce json
+// @Param id path int true "Artist or user ID"
+// @Param limit query int false "Number of users to return (default 50, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.FollowListResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/users/{id}/followers [get]
+// @Router /api/artists/{id}/followers [get]
+func (r *Router) GetFollowersHandler(w http.ResponseWriter, req *http.Request) {
+	r.getFollowList(w, req, true)
+}
+
+// GetFollowingHandler godoc
+// @Summary Get followed users
+// @Description Retrieves the artists and users followed by a user, most recent first
+// @Tags Follows
+// @Produce json
+// @Param id path int true "Artist or user ID"
+// @Param limit query int false "Number of users to return (default 50, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.FollowListResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/users/{id}/following [get]
+// @Router /api/artists/{id}/following [get]
+func (r *Router) GetFollowingHandler(w http.ResponseWriter, req *http.Request) {
+	r.getFollowList(w, req, false)
+}
+
+func (r *Router) getFollowList(w http.ResponseWriter, req *http.Request, followers bool) {
+	userID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := req.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	var users []models.FollowUser
+	var total int
+	if followers {
+		users, total, err = repo.GetFollowers(userID, limit, offset)
+	} else {
+		users, total, err = repo.GetFollowing(userID, limit, offset)
+	}
+	if err != nil {
+		slog.Error("Failed to get follow list", "error", err, "user_id", userID, "followers", followers)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get follow list", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.FollowListResponse{
+		Users:  users,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, http.StatusOK)
+}
+
+// GetReleaseFeedHandler godoc
+// @Summary Get new releases feed
+// @Description Retrieves new tracks and albums from followed artists in reverse chronological order
+// @Tags Follows
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Number of releases to return (default 20, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} models.Release
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/feed/releases [get]
+func (r *Router) GetReleaseFeedHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	offset := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := req.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	releases, err := repo.GetReleaseFeed(userID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get release feed", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get release feed", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, releases, http.StatusOK)
+}
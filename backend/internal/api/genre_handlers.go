@@ -0,0 +1,448 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/listing"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxGenreLength is the maximum number of characters in a genre name or alias
+const MaxGenreLength = 50
+
+// cleanGenreName collapses the whitespace in a genre name or alias and checks its length.
+// Matching it to the taxonomy is left to the database.
+func cleanGenreName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("genre name is required")
+	}
+	if len([]rune(name)) > MaxGenreLength {
+		return "", fmt.Errorf("genre names must be at most %d characters", MaxGenreLength)
+	}
+	return name, nil
+}
+
+// resolveGenre returns how a track's genre field is stored: a known genre or alias as the
+// genre's name and ID, and an unknown spelling as given with no ID, so that only curators
+// grow the taxonomy. An empty field is stored as nil. Otherwise it writes an error response
+// and returns false.
+func resolveGenre(w http.ResponseWriter, repo *repository.Repository, name string) (*string, *int, bool) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil, true
+	}
+	name, err := cleanGenreName(name)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	genre, err := repo.ResolveGenre(name)
+	if err != nil {
+		slog.Error("Failed to resolve genre", "error", err, "genre", name)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to resolve genre", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if genre == nil {
+		return &name, nil, true
+	}
+	return &genre.Name, &genre.ID, true
+}
+
+// GetGenresHandler godoc
+// @Summary Genre tree
+// @Description Returns the genre taxonomy as a tree: top-level genres with their sub-genres nested, each level in alphabetical order. Each genre counts the published tracks in it and, in total, in it and its sub-genres, and lists the other spellings that resolve to it.
+// @Tags Genres
+// @Produce json
+// @Success 200 {array} models.Genre
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/genres [get]
+func (r *Router) GetGenresHandler(w http.ResponseWriter, req *http.Request) {
+	repo := repository.NewRepository(r.Db)
+	genres, err := repo.GetGenreTree()
+	if err != nil {
+		slog.Error("Failed to get genres", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get genres", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, genres, http.StatusOK)
+}
+
+// GetGenreHandler godoc
+// @Summary Get a genre
+// @Description Returns a genre with its sub-genres and its ancestors, root first, for a genre browse page
+// @Tags Genres
+// @Produce json
+// @Param slug path string true "Genre slug"
+// @Success 200 {object} models.GenreDetail
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/genres/{slug} [get]
+func (r *Router) GetGenreHandler(w http.ResponseWriter, req *http.Request) {
+	genre := r.getGenreBySlug(w, mux.Vars(req)["slug"])
+	if genre == nil {
+		return
+	}
+
+	utils.JSONSuccess(w, genre, http.StatusOK)
+}
+
+// GetGenreTracksHandler godoc
+// @Summary Get tracks in a genre
+// @Description Returns published tracks in a genre or any of its sub-genres, newest first unless sorted otherwise. Accepts the same filters and sorts as the track listing.
+// @Tags Genres
+// @Produce json
+// @Param slug path string true "Genre slug"
+// @Param tag query []string false "Only tracks with one of these tags, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum duration in seconds"
+// @Param max_duration query int false "Maximum duration in seconds"
+// @Param year query int false "Only tracks released in this year"
+// @Param artist_id query int false "Only tracks by this artist"
+// @Param has_lyrics query bool false "Only tracks with (true) or without (false) lyrics"
+// @Param min_bitrate query int false "Minimum bitrate in kbps"
+// @Param max_bitrate query int false "Maximum bitrate in kbps"
+// @Param sort query string false "popularity, title, duration, newest or most_liked (default newest)"
+// @Param order query string false "asc or desc (default depends on sort)"
+// @Param limit query int false "Number of tracks to return (default 50)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} models.TrackWithArtist
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/genres/{slug}/tracks [get]
+func (r *Router) GetGenreTracksHandler(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	if params.Has("genre") || params.Has("genre_id") {
+		utils.JSONError(w, api_errors.ErrBadRequest, "the genre comes from the path", http.StatusBadRequest)
+		return
+	}
+
+	genre := r.getGenreBySlug(w, mux.Vars(req)["slug"])
+	if genre == nil {
+		return
+	}
+
+	params.Set("genre_id", strconv.Itoa(genre.ID))
+	opts, err := listing.Tracks.Parse(params)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := params.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := params.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	// Anonymous callers get no favorite flags
+	userID, _ := middleware.GetUserID(req.Context())
+
+	repo := repository.NewRepository(r.Db)
+	tracks, err := repo.GetAllTracksWithFavorites(opts, userID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get genre tracks", "error", err, "genre_id", genre.ID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get tracks", http.StatusInternalServerError)
+		return
+	}
+	if tracks == nil {
+		tracks = []models.TrackWithArtist{}
+	}
+
+	utils.JSONSuccess(w, tracks, http.StatusOK)
+}
+
+// getGenreBySlug returns the genre with the slug. Otherwise it writes an error response and
+// returns nil.
+func (r *Router) getGenreBySlug(w http.ResponseWriter, slug string) *models.GenreDetail {
+	repo := repository.NewRepository(r.Db)
+	genre, err := repo.GetGenreBySlug(slug)
+	if err != nil {
+		slog.Error("Failed to get genre", "error", err, "slug", slug)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get genre", http.StatusInternalServerError)
+		return nil
+	}
+	if genre == nil {
+		utils.JSONError(w, api_errors.ErrGenreNotFound, "genre not found", http.StatusNotFound)
+	}
+	return genre
+}
+
+// CreateGenreHandler godoc
+// @Summary Create a genre
+// @Description Adds a genre to the taxonomy, at the top level or under a parent genre. Its name must not match an existing genre or alias.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param genre body models.GenreRequest true "The genre"
+// @Success 201 {object} models.Genre
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/genres [post]
+func (r *Router) CreateGenreHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.GenreRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, err := cleanGenreName(request.Name)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	genre, err := repo.CreateGenre(name, request.ParentID)
+	if err != nil {
+		writeGenreError(w, err, "failed to create genre")
+		return
+	}
+
+	if err := repo.LogAdminAction("genre.create:"+name, actorID, &genre.ID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, genre, http.StatusCreated)
+}
+
+// UpdateGenreHandler godoc
+// @Summary Update a genre
+// @Description Renames a genre and sets its parent; a missing parent_id makes it a top-level genre. Its tracks take the new name, and the old name keeps resolving to it as an alias.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Genre ID"
+// @Param genre body models.GenreRequest true "The genre's name and parent"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/genres/{id} [put]
+func (r *Router) UpdateGenreHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	genreID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.GenreRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, err := cleanGenreName(request.Name)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	genre, err := repo.UpdateGenre(genreID, name, request.ParentID)
+	if err != nil {
+		writeGenreError(w, err, "failed to update genre")
+		return
+	}
+
+	if err := repo.LogAdminAction("genre.update:"+name, actorID, &genreID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, genre, http.StatusOK)
+}
+
+// MergeGenreHandler godoc
+// @Summary Merge a genre into another
+// @Description Folds a genre into the target genre: its tracks, aliases and sub-genres move to the target and the genre is deleted. Use it to clean up duplicate genres.
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Genre ID"
+// @Param target path int true "ID of the genre to merge into"
+// @Success 200 {object} models.Genre
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/genres/{id}/merge/{target} [post]
+func (r *Router) MergeGenreHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(req)
+	genreID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+	targetID, err := strconv.Atoi(vars["target"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid target genre ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	genre, err := repo.MergeGenre(genreID, targetID)
+	if err != nil {
+		if errors.Is(err, repository.ErrGenreParentNotFound) {
+			utils.JSONError(w, api_errors.ErrGenreNotFound, "target genre not found", http.StatusNotFound)
+			return
+		}
+		writeGenreError(w, err, "failed to merge genre")
+		return
+	}
+
+	if err := repo.LogAdminAction(fmt.Sprintf("genre.merge:%d", genreID), actorID, &targetID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, genre, http.StatusOK)
+}
+
+// AddGenreAliasHandler godoc
+// @Summary Add a genre alias
+// @Description Makes another spelling resolve to a genre when tracks are uploaded or edited, for instance "dnb" for Drum and Bass. Case, accents, punctuation and spacing are ignored.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Genre ID"
+// @Param alias body models.GenreAliasRequest true "The alias"
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/genres/{id}/aliases [post]
+func (r *Router) AddGenreAliasHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	genreID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.GenreAliasRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	alias, err := cleanGenreName(request.Alias)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.AddGenreAlias(genreID, alias); err != nil {
+		writeGenreError(w, err, "failed to add alias")
+		return
+	}
+
+	if err := repo.LogAdminAction("genre.alias.add:"+alias, actorID, &genreID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "alias added"}, http.StatusCreated)
+}
+
+// DeleteGenreAliasHandler godoc
+// @Summary Remove a genre alias
+// @Description Stops a spelling from resolving to a genre. A genre's own name cannot be removed.
+// @Tags Admin
+// @Security ApiKeyAuth
+// @Param id path int true "Genre ID"
+// @Param alias path string true "The alias"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/genres/{id}/aliases/{alias} [delete]
+func (r *Router) DeleteGenreAliasHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(req)
+	genreID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.DeleteGenreAlias(genreID, vars["alias"]); err != nil {
+		writeGenreError(w, err, "failed to remove alias")
+		return
+	}
+
+	if err := repo.LogAdminAction("genre.alias.remove:"+vars["alias"], actorID, &genreID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeGenreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrGenreNotFound), errors.Is(err, repository.ErrGenreAliasNotFound):
+		utils.JSONError(w, api_errors.ErrGenreNotFound, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrGenreExists):
+		utils.JSONError(w, api_errors.ErrGenreAlreadyExists, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrGenreParentNotFound), errors.Is(err, repository.ErrGenreCycle),
+		errors.Is(err, repository.ErrInvalidGenreName), errors.Is(err, repository.ErrGenreAliasIsName):
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(message, "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, message, http.StatusInternalServerError)
+	}
+}
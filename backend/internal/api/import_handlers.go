@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/dataimport"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// importFileTypes are the accepted upload extensions and their content types
+var importFileTypes = map[string]string{
+	".zip":  "application/zip",
+	".json": "application/json",
+	".csv":  "text/csv",
+}
+
+// StartImportHandler godoc
+// @Summary Import data from another service
+// @Description Uploads a Spotify account data export (the ZIP, or StreamingHistory*.json / Playlist1.json) or a Last.fm scrobble export (CSV or JSON) and queues an import job, which a background worker picks up. Tracks are fuzzy-matched to the catalog; playlists are created as private playlists and matched plays are added to the listening history with their original timestamps, flagged as imported. Poll the job for its report.
+// @Tags Account
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param source formData string true "spotify or lastfm"
+// @Param file formData file true "Export file (.zip, .json or .csv)"
+// @Success 202 {object} models.ImportJob
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/imports [post]
+func (r *Router) StartImportHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, MaxDataImportSize)
+	if err := req.ParseMultipartForm(MaxUploadSize); err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, fmt.Sprintf("failed to parse form; uploads may be at most %d MB", MaxDataImportSize>>20), http.StatusBadRequest)
+		return
+	}
+
+	source := req.FormValue("source")
+	if source != dataimport.SourceSpotify && source != dataimport.SourceLastfm {
+		utils.JSONError(w, api_errors.ErrValidationError, "source must be spotify or lastfm", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	filename := sanitizeFilename(header.Filename)
+	ext := strings.ToLower(filepath.Ext(filename))
+	contentType, ok := importFileTypes[ext]
+	if !ok {
+		utils.JSONError(w, api_errors.ErrValidationError, "file must be a .zip, .json or .csv export", http.StatusBadRequest)
+		return
+	}
+
+	objectName := fmt.Sprintf("imports/%s%s", uuid.New().String(), ext)
+	if err := r.Storage.PutObject(req.Context(), objectName, file, header.Size, contentType); err != nil {
+		slog.Error("Failed to store import upload", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to start import", http.StatusInternalServerError)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	job, err := repo.CreateImportJob(userID, source, filename, objectName)
+	if err != nil {
+		slog.Error("Failed to create import job", "error", err, "user_id", userID)
+		if err := r.Storage.DeleteFile(req.Context(), objectName); err != nil {
+			slog.Warn("Failed to delete import upload", "error", err, "object_name", objectName)
+		}
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to start import", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, job, http.StatusAccepted)
+}
+
+// GetImportJobsHandler godoc
+// @Summary List my imports
+// @Description Returns the current user's import jobs, newest first, with the report of each completed one
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ImportJob
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/imports [get]
+func (r *Router) GetImportJobsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	importJobs, err := repo.GetImportJobs(userID)
+	if err != nil {
+		slog.Error("Failed to get import jobs", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get imports", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, importJobs, http.StatusOK)
+}
+
+// GetImportJobHandler godoc
+// @Summary Get an import
+// @Description Returns the status of one of the current user's import jobs. Completed jobs include a report of matched and imported plays, created playlists and the most played tracks that could not be matched.
+// @Tags Account
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Import job ID"
+// @Success 200 {object} models.ImportJob
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/imports/{id} [get]
+func (r *Router) GetImportJobHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid import job ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	job, err := repo.GetImportJob(jobID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrImportJobNotFound) {
+			utils.JSONError(w, api_errors.ErrImportJobNotFound, "import job not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to get import job", "error", err, "job_id", jobID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get import", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, job, http.StatusOK)
+}
@@ -0,0 +1,453 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxLibraryFolderNameLength limits the length of a library folder name
+const MaxLibraryFolderNameLength = 255
+
+// libraryTypes maps the type filter of GET /library to item types
+var libraryTypes = map[string]string{
+	"playlists": models.LibraryItemPlaylist,
+	"albums":    models.LibraryItemAlbum,
+	"artists":   models.LibraryItemArtist,
+}
+
+// writeLibraryError maps library repository errors to responses
+func writeLibraryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrLibraryFolderNotFound):
+		utils.JSONError(w, api_errors.ErrFolderNotFound, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrLibraryItemNotFound):
+		utils.JSONError(w, api_errors.ErrNotFound, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrInvalidLibraryMove):
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, fallback, http.StatusInternalServerError)
+	}
+}
+
+// GetLibraryHandler godoc
+// @Summary Get my library
+// @Description Returns the current user's folders, playlists (owned and collaborative), saved albums and followed artists as a tree. Folders hold their subfolders and playlists in children. Pinned items come first at every level, most recently pinned first. sort is custom (the positions set with POST /library/move; items without a position follow, newest first), name or recent. type limits the library to playlists, albums or artists and q to items whose name or subtitle contains it; folders are kept when their name matches or they contain a match.
+// @Tags Library
+// @Produce json
+// @Security ApiKeyAuth
+// @Param type query string false "playlists, albums or artists"
+// @Param q query string false "Name filter"
+// @Param sort query string false "custom (default), name or recent"
+// @Success 200 {object} models.LibraryResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library [get]
+func (r *Router) GetLibraryHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	params := req.URL.Query()
+	response := models.LibraryResponse{
+		Sort:  params.Get("sort"),
+		Type:  params.Get("type"),
+		Query: strings.TrimSpace(params.Get("q")),
+	}
+	switch response.Sort {
+	case "":
+		response.Sort = models.LibrarySortCustom
+	case models.LibrarySortCustom, models.LibrarySortName, models.LibrarySortRecent:
+	default:
+		utils.JSONError(w, api_errors.ErrValidationError, "sort must be custom, name or recent", http.StatusBadRequest)
+		return
+	}
+	itemType := ""
+	if response.Type != "" {
+		if itemType, ok = libraryTypes[response.Type]; !ok {
+			utils.JSONError(w, api_errors.ErrValidationError, "type must be playlists, albums or artists", http.StatusBadRequest)
+			return
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	items, err := repo.GetLibraryItems(userID)
+	if err != nil {
+		slog.Error("Failed to get library", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get library", http.StatusInternalServerError)
+		return
+	}
+
+	response.Items = buildLibraryTree(items, itemType, strings.ToLower(response.Query), response.Sort)
+	utils.JSONSuccess(w, response, http.StatusOK)
+}
+
+// buildLibraryTree nests items under their folders, drops those that don't match itemType
+// and query, and sorts every level
+func buildLibraryTree(items []models.LibraryItem, itemType, query, sortBy string) []models.LibraryItem {
+	children := map[int][]models.LibraryItem{} // by folder ID, 0 for the top level
+	for _, item := range items {
+		parent := 0
+		if item.FolderID != nil {
+			parent = *item.FolderID
+		}
+		children[parent] = append(children[parent], item)
+	}
+
+	matches := func(item models.LibraryItem) bool {
+		return query == "" ||
+			strings.Contains(strings.ToLower(item.Name), query) ||
+			strings.Contains(strings.ToLower(item.Subtitle), query)
+	}
+
+	var build func(parent int) []models.LibraryItem
+	build = func(parent int) []models.LibraryItem {
+		level := []models.LibraryItem{}
+		for _, item := range children[parent] {
+			if item.Type == models.LibraryItemFolder {
+				// Albums and artists are never in folders
+				if itemType != "" && itemType != models.LibraryItemPlaylist {
+					continue
+				}
+				item.Children = build(item.ID)
+				if len(item.Children) == 0 && !matches(item) {
+					continue
+				}
+			} else if (itemType != "" && item.Type != itemType) || !matches(item) {
+				continue
+			}
+			level = append(level, item)
+		}
+		sortLibraryItems(level, sortBy)
+		return level
+	}
+	return build(0)
+}
+
+func sortLibraryItems(items []models.LibraryItem, sortBy string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if a.Pinned && !a.PinnedAt.Equal(*b.PinnedAt) {
+			return a.PinnedAt.After(*b.PinnedAt)
+		}
+
+		switch sortBy {
+		case models.LibrarySortName:
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn
+			}
+		case models.LibrarySortCustom:
+			if (a.Position == nil) != (b.Position == nil) {
+				return a.Position != nil
+			}
+			if a.Position != nil && *a.Position != *b.Position {
+				return *a.Position < *b.Position
+			}
+		}
+		if !a.AddedAt.Equal(b.AddedAt) {
+			return a.AddedAt.After(b.AddedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// validFolderName trims a folder name and reports whether it is usable
+func validFolderName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && len(name) <= MaxLibraryFolderNameLength
+}
+
+// CreateLibraryFolderHandler godoc
+// @Summary Create a library folder
+// @Description Creates a folder at the end of the top level of the library, or of parent_id
+// @Tags Library
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param folder body models.CreateLibraryFolderRequest true "Folder"
+// @Success 201 {object} models.LibraryItem
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library/folders [post]
+func (r *Router) CreateLibraryFolderHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.CreateLibraryFolderRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, ok := validFolderName(request.Name)
+	if !ok {
+		utils.JSONError(w, api_errors.ErrValidationError, "name must be 1 to 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	folder, err := repo.CreateLibraryFolder(userID, name, request.ParentID)
+	if err != nil {
+		writeLibraryError(w, err, "failed to create folder")
+		return
+	}
+
+	utils.JSONSuccess(w, folder, http.StatusCreated)
+}
+
+// UpdateLibraryFolderHandler godoc
+// @Summary Rename a library folder
+// @Description Renames one of the current user's folders. Use POST /library/move to move it.
+// @Tags Library
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Folder ID"
+// @Param folder body models.UpdateLibraryFolderRequest true "Folder"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library/folders/{id} [put]
+func (r *Router) UpdateLibraryFolderHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	folderID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid folder ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.UpdateLibraryFolderRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, ok := validFolderName(request.Name)
+	if !ok {
+		utils.JSONError(w, api_errors.ErrValidationError, "name must be 1 to 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.RenameLibraryFolder(userID, folderID, name); err != nil {
+		writeLibraryError(w, err, "failed to rename folder")
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "folder renamed"}, http.StatusOK)
+}
+
+// DeleteLibraryFolderHandler godoc
+// @Summary Delete a library folder
+// @Description Deletes one of the current user's folders. Its subfolders and playlists move up to the folder's parent; nothing else is deleted.
+// @Tags Library
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Folder ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library/folders/{id} [delete]
+func (r *Router) DeleteLibraryFolderHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	folderID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid folder ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.DeleteLibraryFolder(userID, folderID); err != nil {
+		writeLibraryError(w, err, "failed to delete folder")
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "folder deleted"}, http.StatusOK)
+}
+
+// MoveLibraryItemHandler godoc
+// @Summary Move a folder or playlist
+// @Description Places a folder or playlist in a folder (folder_id null for the top level) at position, shifting the items at and after it, or at the end when position is null. This defines the custom sort order of the library.
+// @Tags Library
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param move body models.MoveLibraryItemRequest true "Item and target"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library/move [post]
+func (r *Router) MoveLibraryItemHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.MoveLibraryItemRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	if request.Type != models.LibraryItemFolder && request.Type != models.LibraryItemPlaylist {
+		utils.JSONError(w, api_errors.ErrValidationError, "only folders and playlists can be moved", http.StatusBadRequest)
+		return
+	}
+	if request.Position != nil && *request.Position < 0 {
+		utils.JSONError(w, api_errors.ErrValidationError, "position must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.MoveLibraryItem(userID, request.Type, request.ID, request.FolderID, request.Position); err != nil {
+		writeLibraryError(w, err, "failed to move library item")
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "moved"}, http.StatusOK)
+}
+
+// PinLibraryItemHandler godoc
+// @Summary Pin or unpin a library item
+// @Description Pins a folder, playlist, saved album or followed artist to the top of its level of the library, or unpins it
+// @Tags Library
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pin body models.PinLibraryItemRequest true "Item"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/library/pin [post]
+func (r *Router) PinLibraryItemHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.PinLibraryItemRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	switch request.Type {
+	case models.LibraryItemFolder, models.LibraryItemPlaylist, models.LibraryItemAlbum, models.LibraryItemArtist:
+	default:
+		utils.JSONError(w, api_errors.ErrValidationError, "type must be folder, playlist, album or artist", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.PinLibraryItem(userID, request.Type, request.ID, request.Pinned); err != nil {
+		writeLibraryError(w, err, "failed to pin library item")
+		return
+	}
+
+	message := "unpinned"
+	if request.Pinned {
+		message = "pinned"
+	}
+	utils.JSONSuccess(w, map[string]string{"message": message}, http.StatusOK)
+}
+
+// SaveAlbumHandler godoc
+// @Summary Save an album
+// @Description Adds an album to the current user's library
+// @Tags Library
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Album ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/albums/{id}/save [post]
+func (r *Router) SaveAlbumHandler(w http.ResponseWriter, req *http.Request) {
+	r.setAlbumSaved(w, req, true)
+}
+
+// UnsaveAlbumHandler godoc
+// @Summary Remove a saved album
+// @Description Removes an album from the current user's library
+// @Tags Library
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Album ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/albums/{id}/save [delete]
+func (r *Router) UnsaveAlbumHandler(w http.ResponseWriter, req *http.Request) {
+	r.setAlbumSaved(w, req, false)
+}
+
+func (r *Router) setAlbumSaved(w http.ResponseWriter, req *http.Request, save bool) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	albumID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid album ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	message := "saved"
+	if save {
+		err = repo.SaveAlbum(userID, albumID)
+	} else {
+		err = repo.UnsaveAlbum(userID, albumID)
+		message = "removed"
+	}
+	if err != nil {
+		if err.Error() == "album not found" {
+			utils.JSONError(w, api_errors.ErrAlbumNotFound, "album not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to update saved album", "error", err, "user_id", userID, "album_id", albumID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to update library", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": message}, http.StatusOK)
+}
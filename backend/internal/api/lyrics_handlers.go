@@ -0,0 +1,200 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/lyrics"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// lyricsDurationSlack is how far past a track's known duration a synced line may start,
+// since durations are rounded down to the second and uploads may be trimmed
+const lyricsDurationSlack = 5000 // milliseconds
+
+// GetTrackLyricsHandler godoc
+// @Summary Get track lyrics
+// @Description Returns a track's lyrics. Synced lyrics come with their lines in time order, each with the time it is sung at in milliseconds, so a player can highlight the current line; plain lyrics only have their text.
+// @Tags Tracks
+// @Produce json
+// @Param id path int true "Track ID"
+// @Success 200 {object} models.TrackLyrics
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/lyrics [get]
+func (r *Router) GetTrackLyricsHandler(w http.ResponseWriter, req *http.Request) {
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if r.getVisibleTrack(w, req, repo, trackID) == nil {
+		return
+	}
+
+	trackLyrics, err := repo.GetTrackLyrics(trackID)
+	if err != nil {
+		slog.Error("Failed to get lyrics", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get lyrics", http.StatusInternalServerError)
+		return
+	}
+	if trackLyrics == nil {
+		utils.JSONError(w, api_errors.ErrNotFound, "track has no lyrics", http.StatusNotFound)
+		return
+	}
+
+	utils.JSONSuccess(w, trackLyrics, http.StatusOK)
+}
+
+// SetTrackLyricsHandler godoc
+// @Summary Upload track lyrics
+// @Description Replaces a track's lyrics, as plain text or as time-synced LRC ([mm:ss.xx] before each line; several timestamps per line, ID tags, [offset:] and word timings are supported). The format is detected when not given. Synced lines must not start after the end of the track. Only the track's artist, or a user who can manage all tracks, may set its lyrics. The lyrics' text is indexed for search.
+// @Tags Tracks
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Param lyrics body models.SetLyricsRequest true "The lyrics"
+// @Success 200 {object} models.TrackLyrics
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/lyrics [put]
+func (r *Router) SetTrackLyricsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.SetLyricsRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+
+	parsed, err := lyrics.Parse(request.Lyrics, request.Format)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	track := r.getEditableTrack(w, repo, trackID, userID)
+	if track == nil {
+		return
+	}
+
+	if n := len(parsed.Lines); n > 0 && track.Duration > 0 && parsed.Lines[n-1].TimeMs > track.Duration*1000+lyricsDurationSlack {
+		message := fmt.Sprintf("synced lines must start within the track's %d seconds", track.Duration)
+		utils.JSONError(w, api_errors.ErrValidationError, message, http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.SetTrackLyrics(trackID, parsed.Text, parsed.Lines); err != nil {
+		slog.Error("Failed to set lyrics", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to set lyrics", http.StatusInternalServerError)
+		return
+	}
+
+	trackLyrics, err := repo.GetTrackLyrics(trackID)
+	if err != nil || trackLyrics == nil {
+		slog.Error("Failed to get lyrics", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "lyrics set but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, trackLyrics, http.StatusOK)
+}
+
+// DeleteTrackLyricsHandler godoc
+// @Summary Remove track lyrics
+// @Description Removes a track's lyrics. Only the track's artist, or a user who can manage all tracks, may remove them.
+// @Tags Tracks
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/lyrics [delete]
+func (r *Router) DeleteTrackLyricsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if r.getEditableTrack(w, repo, trackID, userID) == nil {
+		return
+	}
+
+	if err := repo.DeleteTrackLyrics(trackID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.JSONError(w, api_errors.ErrNotFound, "track not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to delete lyrics", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to delete lyrics", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getEditableTrack returns the track if it exists and the user is its artist or may manage
+// all tracks. Otherwise it writes an error response and returns nil.
+func (r *Router) getEditableTrack(w http.ResponseWriter, repo *repository.Repository, trackID, userID int) *models.Track {
+	track, err := repo.GetTrackByID(trackID)
+	if err != nil {
+		slog.Error("Failed to get track", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get track", http.StatusInternalServerError)
+		return nil
+	}
+	if track == nil {
+		utils.JSONError(w, api_errors.ErrNotFound, "track not found", http.StatusNotFound)
+		return nil
+	}
+
+	if track.ArtistID != userID {
+		canManage, err := repo.UserHasPermission(userID, models.PermTracksManageAll)
+		if err != nil {
+			slog.Error("Failed to check permission", "error", err, "user_id", userID)
+			utils.JSONError(w, api_errors.ErrInternalServer, "failed to check permissions", http.StatusInternalServerError)
+			return nil
+		}
+		if !canManage {
+			utils.JSONError(w, api_errors.ErrForbidden, "you don't have permission to edit this track", http.StatusForbidden)
+			return nil
+		}
+	}
+	return track
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// TakedownTrackHandler godoc
+// @Summary Take down a track
+// @Description Removes a track from the public catalog without deleting it. Requires the tracks.takedown permission.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Param body body models.TakedownTrackRequest false "Takedown reason"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/moderation/tracks/{id}/takedown [post]
+func (r *Router) TakedownTrackHandler(w http.ResponseWriter, req *http.Request) {
+	r.setTrackModerationStatus(w, req, "taken_down", "track taken down")
+}
+
+// RestoreTrackHandler godoc
+// @Summary Restore a taken down track
+// @Description Publishes a previously taken down track again. Requires the tracks.takedown permission.
+// @Tags Moderation
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/moderation/tracks/{id}/restore [post]
+func (r *Router) RestoreTrackHandler(w http.ResponseWriter, req *http.Request) {
+	r.setTrackModerationStatus(w, req, "published", "track restored")
+}
+
+func (r *Router) setTrackModerationStatus(w http.ResponseWriter, req *http.Request, status, message string) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	// The reason is optional, so an empty body is accepted
+	var body models.TakedownTrackRequest
+	if req.ContentLength > 0 {
+		if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+			return
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.UpdateTrackStatus(trackID, status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.JSONError(w, api_errors.ErrTrackNotFound, "track not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to update track status", "error", err, "track_id", trackID, "status", status)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to update track status", http.StatusInternalServerError)
+		return
+	}
+
+	action := "track." + status
+	if body.Reason != "" {
+		action += ":" + body.Reason
+	}
+	if len(action) > 255 {
+		action = action[:255]
+	}
+	if err := repo.LogAdminAction(action, actorID, &trackID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": message}, http.StatusOK)
+}
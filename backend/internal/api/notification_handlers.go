@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// notificationHeartbeat keeps idle streams alive through proxies
+const notificationHeartbeat = 30 * time.Second
+
+// GetNotificationsHandler godoc
+// @Summary Get notifications
+// @Description Retrieves the current user's notifications, newest first, with the unread count
+// @Tags Notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Param unread query bool false "Only return unread notifications"
+// @Param limit query int false "Number of notifications to return (default 20, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.NotificationsResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/notifications [get]
+func (r *Router) GetNotificationsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := req.URL.Query()
+	unreadOnly, _ := strconv.ParseBool(query.Get("unread"))
+
+	limit := 20
+	offset := 0
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	notifications, total, unread, err := repo.GetNotifications(userID, unreadOnly, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get notifications", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.NotificationsResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+		Total:         total,
+		Limit:         limit,
+		Offset:        offset,
+	}, http.StatusOK)
+}
+
+// MarkNotificationReadHandler godoc
+// @Summary Mark a notification as read
+// @Tags Notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/notifications/{id}/read [post]
+func (r *Router) MarkNotificationReadHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.MarkNotificationRead(userID, notificationID); err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			utils.JSONError(w, api_errors.ErrNotificationNotFound, "notification not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to mark notification as read", "error", err, "notification_id", notificationID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to mark notification as read", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "notification marked as read"}, http.StatusOK)
+}
+
+// MarkAllNotificationsReadHandler godoc
+// @Summary Mark all notifications as read
+// @Tags Notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]int64
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/notifications/read-all [post]
+func (r *Router) MarkAllNotificationsReadHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	updated, err := repo.MarkAllNotificationsRead(userID)
+	if err != nil {
+		slog.Error("Failed to mark notifications as read", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to mark notifications as read", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]int64{"updated": updated}, http.StatusOK)
+}
+
+// NotificationStreamHandler godoc
+// @Summary Stream notifications
+// @Description Server-sent events stream of the current user's new notifications. Each event is named "notification" and carries the notification ID as the event ID; reconnecting with Last-Event-ID replays missed notifications. Browsers may pass the access token as the access_token query parameter.
+// @Tags Notifications
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param access_token query string false "Access token, for clients that cannot set the Authorization header"
+// @Param last_event_id query int false "Replay notifications after this ID when the Last-Event-ID header cannot be set"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/notifications/stream [get]
+func (r *Router) NotificationStreamHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.JSONError(w, api_errors.ErrInternalServer, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before replaying so nothing created in between is missed
+	events, unsubscribe := r.Notifications.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("last_event_id")
+	}
+
+	lastID := 0
+	if id, err := strconv.Atoi(lastEventID); err == nil && id > 0 {
+		repo := repository.NewRepository(r.Db)
+		missed, err := repo.GetNotificationsAfter(userID, id, 100)
+		if err != nil {
+			slog.Error("Failed to replay notifications", "error", err, "user_id", userID)
+		}
+		lastID = id
+		for _, n := range missed {
+			if err := writeNotificationEvent(w, n); err != nil {
+				return
+			}
+			lastID = n.ID
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case n, ok := <-events:
+			if !ok {
+				return
+			}
+			if n.ID <= lastID {
+				continue
+			}
+			if err := writeNotificationEvent(w, n); err != nil {
+				return
+			}
+			lastID = n.ID
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeNotificationEvent(w http.ResponseWriter, n models.Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", n.ID, data)
+	return err
+}
+
+// notify delivers a notification without failing the request that triggered it
+func (r *Router) notify(n models.NewNotification) {
+	repo := repository.NewRepository(r.Db)
+	if err := repo.CreateNotification(n); err != nil {
+		slog.Error("Failed to create notification", "error", err, "user_id", n.UserID, "type", n.Type)
+	}
+}
+
+// notifyFollowers delivers a notification to all followers of a user without failing the request
+func (r *Router) notifyFollowers(followeeID int, n models.NewNotification) {
+	repo := repository.NewRepository(r.Db)
+	if err := repo.NotifyFollowers(followeeID, n); err != nil {
+		slog.Error("Failed to notify followers", "error", err, "followee_id", followeeID, "type", n.Type)
+	}
+}
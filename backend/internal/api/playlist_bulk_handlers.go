@@ -0,0 +1,153 @@
+package api
+
+import (
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxBulkPlaylistItems limits how many items a single bulk playlist request may touch
+const MaxBulkPlaylistItems = 500
+
+// BulkAddTracksHandler godoc
+// @Summary Bulk Add Tracks to Playlist
+// @Description Add a list of tracks, every track of an album, or every track of another playlist in one transaction (owner or editor). Tracks keep their order and are inserted at position or appended. dedupe controls duplicates: "none" adds everything, "existing" (default) skips tracks already in the playlist, "all" also skips repeats within the request. Each item's outcome is reported in results.
+// @Tags Playlists
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param tracks body models.BulkAddTracksRequest true "Tracks to add"
+// @Success 200 {object} models.BulkTracksResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/tracks/bulk [post]
+func (r *Router) BulkAddTracksHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.BulkAddTracksRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+
+	sources := 0
+	if request.TrackIDs != nil {
+		sources++
+	}
+	if request.AlbumID != nil {
+		sources++
+	}
+	if request.SourcePlaylistID != nil {
+		sources++
+	}
+	if sources != 1 {
+		utils.JSONError(w, "INVALID_REQUEST", "exactly one of track_ids, album_id or source_playlist_id is required", http.StatusBadRequest)
+		return
+	}
+
+	switch request.Dedupe {
+	case "":
+		request.Dedupe = models.DedupeExisting
+	case models.DedupeNone, models.DedupeExisting, models.DedupeAll:
+	default:
+		utils.JSONError(w, "INVALID_REQUEST", "dedupe must be none, existing or all", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	trackIDs := request.TrackIDs
+	switch {
+	case request.AlbumID != nil:
+		trackIDs, err = playlistRepo.GetAlbumTrackIDs(*request.AlbumID)
+	case request.SourcePlaylistID != nil:
+		trackIDs, err = playlistRepo.GetSourcePlaylistTrackIDs(*request.SourcePlaylistID, userID)
+	}
+	if err != nil {
+		writePlaylistTracksError(w, err, "Failed to get tracks to add")
+		return
+	}
+
+	if len(trackIDs) > MaxBulkPlaylistItems {
+		utils.JSONError(w, "INVALID_REQUEST", "too many tracks in one request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := playlistRepo.BulkAddTracks(playlistID, userID, trackIDs, request.Position, request.Version, request.Dedupe)
+	if err != nil {
+		writePlaylistTracksError(w, err, "Failed to add tracks to playlist")
+		return
+	}
+
+	slog.Info("Tracks added to playlist in bulk", "playlistID", playlistID, "added", response.Succeeded, "skipped", response.Skipped)
+	utils.JSONSuccess(w, response, http.StatusOK)
+}
+
+// BulkRemoveTracksHandler godoc
+// @Summary Bulk Remove Tracks from Playlist
+// @Description Remove entries by entry ID and every entry of the given track IDs in one transaction (owner or editor). Each item's outcome is reported in results.
+// @Tags Playlists
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param tracks body models.BulkRemoveTracksRequest true "Entries and tracks to remove"
+// @Success 200 {object} models.BulkTracksResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/tracks/bulk [delete]
+func (r *Router) BulkRemoveTracksHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.BulkRemoveTracksRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+
+	items := len(request.EntryIDs) + len(request.TrackIDs)
+	if items == 0 {
+		utils.JSONError(w, "INVALID_REQUEST", "entry_ids or track_ids is required", http.StatusBadRequest)
+		return
+	}
+	if items > MaxBulkPlaylistItems {
+		utils.JSONError(w, "INVALID_REQUEST", "too many items in one request", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	response, err := playlistRepo.BulkRemoveTracks(playlistID, userID, request.EntryIDs, request.TrackIDs, request.Version)
+	if err != nil {
+		writePlaylistTracksError(w, err, "Failed to remove tracks from playlist")
+		return
+	}
+
+	utils.JSONSuccess(w, response, http.StatusOK)
+}
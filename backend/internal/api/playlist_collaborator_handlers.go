@@ -0,0 +1,282 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// writeCollaboratorError maps collaborator repository errors to responses
+func writeCollaboratorError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found", "user not found", "invite not found", "collaborator not found":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "you don't have permission to view this playlist", "you don't have permission to manage collaborators":
+		utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	case "user is already a collaborator":
+		utils.JSONError(w, "CONFLICT", err.Error(), http.StatusConflict)
+	case "cannot invite the playlist owner":
+		utils.JSONError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
+}
+
+func validCollaboratorRole(role string) bool {
+	return role == models.PlaylistRoleViewer || role == models.PlaylistRoleEditor
+}
+
+// GetPlaylistCollaboratorsHandler godoc
+// @Summary Get Playlist Collaborators
+// @Description List the collaborators of a playlist, including pending invites. Visible to the owner and collaborators.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {array} models.PlaylistCollaborator
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/collaborators [get]
+func (r *Router) GetPlaylistCollaboratorsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	collaborators, err := playlistRepo.GetCollaborators(playlistID, userID)
+	if err != nil {
+		writeCollaboratorError(w, err, "Failed to get collaborators")
+		return
+	}
+
+	utils.JSONSuccess(w, collaborators, http.StatusOK)
+}
+
+// InvitePlaylistCollaboratorHandler godoc
+// @Summary Invite Playlist Collaborator
+// @Description Invite a user to collaborate on a playlist as a viewer or editor. Only the owner can invite. The invite is pending until the user accepts it.
+// @Tags Playlists
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param invite body models.InviteCollaboratorRequest true "User and role"
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/collaborators [post]
+func (r *Router) InvitePlaylistCollaboratorHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.InviteCollaboratorRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	if request.Role == "" {
+		request.Role = models.PlaylistRoleViewer
+	}
+	if request.UserID == 0 || !validCollaboratorRole(request.Role) {
+		utils.JSONError(w, "INVALID_REQUEST", "user_id and a role of viewer or editor are required", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.InviteCollaborator(playlistID, userID, request.UserID, request.Role); err != nil {
+		writeCollaboratorError(w, err, "Failed to invite collaborator")
+		return
+	}
+
+	if playlist, err := playlistRepo.GetPlaylistByID(playlistID); err == nil {
+		r.notify(models.NewNotification{
+			UserID:     request.UserID,
+			Type:       models.NotificationPlaylistInvite,
+			ActorID:    &userID,
+			EntityType: "playlist",
+			EntityID:   &playlistID,
+			Message:    fmt.Sprintf("You were invited to collaborate on %s as %s", playlist.Title, request.Role),
+		})
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "invite sent"}, http.StatusCreated)
+}
+
+// AcceptPlaylistInviteHandler godoc
+// @Summary Accept Playlist Invite
+// @Description Accept a pending invite to collaborate on a playlist
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/collaborators/accept [post]
+func (r *Router) AcceptPlaylistInviteHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.AcceptInvite(playlistID, userID); err != nil {
+		writeCollaboratorError(w, err, "Failed to accept invite")
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "invite accepted"}, http.StatusOK)
+}
+
+// UpdatePlaylistCollaboratorHandler godoc
+// @Summary Update Playlist Collaborator
+// @Description Change a collaborator's role. Only the owner can change roles.
+// @Tags Playlists
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param userId path int true "Collaborator user ID"
+// @Param role body models.UpdateCollaboratorRequest true "New role"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/collaborators/{userId} [put]
+func (r *Router) UpdatePlaylistCollaboratorHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(req)
+	playlistID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+	collaboratorID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.UpdateCollaboratorRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	if !validCollaboratorRole(request.Role) {
+		utils.JSONError(w, "INVALID_REQUEST", "role must be viewer or editor", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.UpdateCollaboratorRole(playlistID, userID, collaboratorID, request.Role); err != nil {
+		writeCollaboratorError(w, err, "Failed to update collaborator")
+		return
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "collaborator updated"}, http.StatusOK)
+}
+
+// RemovePlaylistCollaboratorHandler godoc
+// @Summary Remove Playlist Collaborator
+// @Description Remove a collaborator or pending invite. The owner can remove anyone; collaborators can remove themselves to leave the playlist or decline an invite.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param userId path int true "Collaborator user ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/collaborators/{userId} [delete]
+func (r *Router) RemovePlaylistCollaboratorHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(req)
+	playlistID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+	collaboratorID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.RemoveCollaborator(playlistID, userID, collaboratorID); err != nil {
+		writeCollaboratorError(w, err, "Failed to remove collaborator")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPlaylistInvitesHandler godoc
+// @Summary Get Playlist Invites
+// @Description List the authenticated user's pending playlist invites
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.PlaylistInvite
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/playlist-invites [get]
+func (r *Router) GetPlaylistInvitesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	invites, err := playlistRepo.GetPendingInvites(userID)
+	if err != nil {
+		slog.Error("Failed to get playlist invites", "error", err, "user_id", userID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get invites", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, invites, http.StatusOK)
+}
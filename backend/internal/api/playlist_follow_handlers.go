@@ -0,0 +1,139 @@
+package api
+
+import (
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// writePlaylistFollowError maps fork and follow repository errors to responses
+func writePlaylistFollowError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found", "you don't follow this playlist":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "playlist is already in your library":
+		utils.JSONError(w, "CONFLICT", err.Error(), http.StatusConflict)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
+}
+
+// ForkPlaylistHandler godoc
+// @Summary Fork Playlist
+// @Description Create a copy of a public playlist, or of one the caller collaborates on, owned by the caller. The copy keeps a reference to its source, takes the source's tracks or smart playlist rules, and can be edited independently. The body is optional; the title defaults to the source's.
+// @Tags Playlists
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param playlist body models.ForkPlaylistRequest false "Title and privacy of the copy"
+// @Success 201 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/fork [post]
+func (r *Router) ForkPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.ForkPlaylistRequest
+	if req.ContentLength > 0 {
+		if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+			return
+		}
+	}
+	if !validPlaylistPrivacy(request.Privacy) {
+		utils.JSONError(w, "INVALID_REQUEST", "Privacy must be public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	fork, err := playlistRepo.ForkPlaylist(playlistID, userID, &request)
+	if err != nil {
+		writePlaylistFollowError(w, err, "Failed to fork playlist")
+		return
+	}
+
+	playlist, err := playlistRepo.GetPlaylistByIDWithFavorites(fork.ID, userID)
+	if err != nil {
+		slog.Error("Failed to get playlist", "error", err, "playlistID", fork.ID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+	playlist.Role = models.PlaylistRoleOwner
+
+	utils.JSONSuccess(w, playlist, http.StatusCreated)
+}
+
+// FollowPlaylistHandler godoc
+// @Summary Follow Playlist
+// @Description Add another user's public playlist to the caller's library. Followed playlists stay in sync with their owner's changes, and drop out of the library while they are not public.
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/follow [post]
+func (r *Router) FollowPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	r.setPlaylistFollowed(w, req, true)
+}
+
+// UnfollowPlaylistHandler godoc
+// @Summary Unfollow Playlist
+// @Description Remove a followed playlist from the caller's library
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/follow [delete]
+func (r *Router) UnfollowPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	r.setPlaylistFollowed(w, req, false)
+}
+
+func (r *Router) setPlaylistFollowed(w http.ResponseWriter, req *http.Request, follow bool) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if follow {
+		err = playlistRepo.FollowPlaylist(playlistID, userID)
+	} else {
+		err = playlistRepo.UnfollowPlaylist(playlistID, userID)
+	}
+	if err != nil {
+		writePlaylistFollowError(w, err, "Failed to update playlist follow")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,11 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"log/slog"
 	"music-app/backend/internal/middleware"
 	"music-app/backend/internal/models"
 	"music-app/backend/internal/repository"
+	"music-app/backend/internal/smartplaylist"
 	"music-app/backend/internal/utils"
 	"net/http"
 	"strconv"
@@ -15,6 +15,15 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// validPlaylistPrivacy reports whether privacy is a known privacy setting or empty (unchanged/default)
+func validPlaylistPrivacy(privacy string) bool {
+	switch privacy {
+	case "", models.PlaylistPrivacyPublic, models.PlaylistPrivacyUnlisted, models.PlaylistPrivacyPrivate:
+		return true
+	}
+	return false
+}
+
 // Helper to extract userID with proper error handling
 func getUserID(w http.ResponseWriter, req *http.Request) (int, bool) {
 	userID, ok := middleware.GetUserID(req.Context())
@@ -27,7 +36,7 @@ func getUserID(w http.ResponseWriter, req *http.Request) (int, bool) {
 
 // UploadPlaylistCoverHandler godoc
 // @Summary Upload Playlist Cover
-// @Description Upload a cover image for a playlist
+// @Description Upload a cover image for a playlist (owner or editor). Playlists without an uploaded cover get a mosaic of their first tracks' covers; an upload replaces it until removed.
 // @Tags Playlists
 // @Accept multipart/form-data
 // @Produce json
@@ -111,6 +120,8 @@ func (r *Router) UploadPlaylistCoverHandler(w http.ResponseWriter, req *http.Req
 		CoverURL:  updated.CoverURL,
 		Privacy:   updated.Privacy,
 		CreatedAt: updated.CreatedAt,
+
+		FollowerCount: updated.FollowerCount,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -118,6 +129,48 @@ func (r *Router) UploadPlaylistCoverHandler(w http.ResponseWriter, req *http.Req
 	json.NewEncoder(w).Encode(response)
 }
 
+// RemovePlaylistCoverHandler godoc
+// @Summary Remove Playlist Cover
+// @Description Remove a playlist's uploaded cover (owner or editor). A mosaic of its first tracks' covers is generated in its place shortly after.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 204
+// @Failure 400 {object} api_errors.ErrorResponse
+// @Failure 401 {object} api_errors.ErrorResponse
+// @Failure 403 {object} api_errors.ErrorResponse
+// @Failure 404 {object} api_errors.ErrorResponse
+// @Router /api/playlists/{id}/cover [delete]
+func (r *Router) RemovePlaylistCoverHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.RemovePlaylistCover(playlistID, userID); err != nil {
+		switch err.Error() {
+		case "playlist not found", "playlist has no uploaded cover":
+			utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+		case "you don't have permission to update this playlist":
+			utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+		default:
+			slog.Error("Failed to remove playlist cover", "error", err)
+			utils.JSONError(w, "INTERNAL_ERROR", "Failed to remove playlist cover", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // CreatePlaylistHandler godoc
 // @Summary Create Playlist
 // @Description Create a new playlist for the authenticated user
@@ -148,11 +201,24 @@ func (r *Router) CreatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	if !validPlaylistPrivacy(request.Privacy) {
+		utils.JSONError(w, "INVALID_REQUEST", "Privacy must be public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+
+	if request.Rules != nil {
+		if err := smartplaylist.Validate(request.Rules); err != nil {
+			utils.JSONError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	playlistRepo := repository.NewPlaylistRepository(r.Db)
 	playlist := &models.Playlist{
 		Title:     request.Title,
 		CreatorID: userID,
 		Privacy:   request.Privacy,
+		Rules:     request.Rules,
 	}
 
 	created, err := playlistRepo.CreatePlaylist(playlist)
@@ -162,12 +228,21 @@ func (r *Router) CreatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	// Fill a new smart playlist right away; if that fails it is filled on first read
+	if created.IsSmart {
+		if err := playlistRepo.RefreshSmartPlaylist(created.ID); err != nil {
+			slog.Error("Failed to refresh smart playlist", "error", err, "playlistID", created.ID)
+		}
+	}
+
 	response := models.PlaylistResponse{
 		ID:        created.ID,
 		Title:     created.Title,
 		CreatorID: created.CreatorID,
 		CoverURL:  created.CoverURL,
 		Privacy:   created.Privacy,
+		Role:      models.PlaylistRoleOwner,
+		IsSmart:   created.IsSmart,
 		CreatedAt: created.CreatedAt,
 	}
 
@@ -178,23 +253,19 @@ func (r *Router) CreatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 
 // GetPlaylistHandler godoc
 // @Summary Get Playlist
-// @Description Get a playlist with all its tracks
+// @Description Get a playlist with all its tracks and who added them. Private and unlisted playlists are only visible to their creator and collaborators; unlisted playlists can also be read through a share link.
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param id path int true "Playlist ID"
 // @Success 200 {object} models.PlaylistWithTracks
 // @Failure 404 {object} utils.ErrorResponse
 // @Router /api/playlists/{id} [get]
 func (r *Router) GetPlaylistHandler(w http.ResponseWriter, req *http.Request) {
-	fmt.Println("=== GetPlaylistHandler START ===")
-
 	vars := mux.Vars(req)
-	fmt.Printf("mux.Vars result: %v\n", vars)
-
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		fmt.Printf("PARSE ERROR: %v, vars=%v\n", err, vars)
 		slog.Error("Failed to parse ID", "error", err, "vars", vars)
 		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
 		return
@@ -207,21 +278,52 @@ func (r *Router) GetPlaylistHandler(w http.ResponseWriter, req *http.Request) {
 	// Get user ID from context if authenticated
 	userID, isAuthenticated := middleware.GetUserID(req.Context())
 
-	var playlist *models.PlaylistWithTracks
-	if isAuthenticated {
-		// Get playlist with favorite status for authenticated users
-		playlist, err = playlistRepo.GetPlaylistByIDWithFavorites(id, userID)
-	} else {
+	getPlaylist := func() (*models.PlaylistWithTracks, error) {
+		if isAuthenticated {
+			// Get playlist with favorite status for authenticated users
+			return playlistRepo.GetPlaylistByIDWithFavorites(id, userID)
+		}
 		// Get playlist without favorite status for unauthenticated users
-		playlist, err = playlistRepo.GetPlaylistByID(id)
+		return playlistRepo.GetPlaylistByID(id)
 	}
 
+	playlist, err := getPlaylist()
 	if err != nil {
 		slog.Error("Failed to get playlist", "error", err, "playlistID", id)
 		utils.JSONError(w, "NOT_FOUND", "Playlist not found", http.StatusNotFound)
 		return
 	}
 
+	role := ""
+	if isAuthenticated {
+		role, err = playlistRepo.GetPlaylistRole(id, userID)
+		if err != nil {
+			slog.Error("Failed to get playlist role", "error", err, "playlistID", id)
+			utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Private and unlisted playlists are only visible to their creator and collaborators; respond as if they don't exist
+	if playlist.Privacy != models.PlaylistPrivacyPublic && role == "" {
+		utils.JSONError(w, "NOT_FOUND", "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	// Re-evaluate the rules of a visible smart playlist whose cached tracks are stale. On
+	// failure the cached tracks are served.
+	refreshed, err := playlistRepo.RefreshSmartPlaylistIfStale(id, smartplaylist.MaxAge)
+	if err != nil {
+		slog.Error("Failed to refresh smart playlist", "error", err, "playlistID", id)
+	} else if refreshed {
+		if playlist, err = getPlaylist(); err != nil {
+			slog.Error("Failed to get playlist", "error", err, "playlistID", id)
+			utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+			return
+		}
+	}
+	playlist.Role = role
+
 	slog.Info("Playlist retrieved successfully", "playlistID", id)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -230,12 +332,12 @@ func (r *Router) GetPlaylistHandler(w http.ResponseWriter, req *http.Request) {
 
 // GetUserPlaylistsHandler godoc
 // @Summary Get User Playlists
-// @Description Get all playlists for the authenticated user
+// @Description Get all playlists the authenticated user owns or collaborates on, with their role and share status on each
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
-// @Success 200 {array} models.PlaylistResponse
+// @Success 200 {array} models.PlaylistWithShareStatus
 // @Failure 401 {object} utils.ErrorResponse
 // @Router /api/playlists [get]
 func (r *Router) GetUserPlaylistsHandler(w http.ResponseWriter, req *http.Request) {
@@ -256,15 +358,26 @@ func (r *Router) GetUserPlaylistsHandler(w http.ResponseWriter, req *http.Reques
 		playlists = []models.Playlist{}
 	}
 
-	response := make([]models.PlaylistResponse, len(playlists))
+	response := make([]models.PlaylistWithShareStatus, len(playlists))
 	for i, p := range playlists {
-		response[i] = models.PlaylistResponse{
-			ID:        p.ID,
-			Title:     p.Title,
-			CreatorID: p.CreatorID,
-			CoverURL:  p.CoverURL,
-			Privacy:   p.Privacy,
-			CreatedAt: p.CreatedAt,
+		response[i] = models.PlaylistWithShareStatus{
+			PlaylistResponse: models.PlaylistResponse{
+				ID:        p.ID,
+				Title:     p.Title,
+				CreatorID: p.CreatorID,
+				CoverURL:  p.CoverURL,
+				Privacy:   p.Privacy,
+				Role:      p.Role,
+				IsSmart:   p.IsSmart,
+				CreatedAt: p.CreatedAt,
+
+				FollowerCount: p.FollowerCount,
+			},
+			ShareLinkCount:    p.ShareLinkCount,
+			CollaboratorCount: p.CollaboratorCount,
+			IsShared: p.Privacy == models.PlaylistPrivacyPublic ||
+				(p.Privacy == models.PlaylistPrivacyUnlisted && p.ShareLinkCount > 0) ||
+				p.CollaboratorCount > 0,
 		}
 	}
 
@@ -275,7 +388,7 @@ func (r *Router) GetUserPlaylistsHandler(w http.ResponseWriter, req *http.Reques
 
 // UpdatePlaylistHandler godoc
 // @Summary Update Playlist
-// @Description Update a playlist (only for the creator)
+// @Description Update a playlist. Editors may rename it; only the owner may change its privacy.
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
@@ -307,6 +420,11 @@ func (r *Router) UpdatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	if !validPlaylistPrivacy(request.Privacy) {
+		utils.JSONError(w, "INVALID_REQUEST", "Privacy must be public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+
 	playlistRepo := repository.NewPlaylistRepository(r.Db)
 	updated, err := playlistRepo.UpdatePlaylist(id, userID, &request)
 	if err != nil {
@@ -328,6 +446,8 @@ func (r *Router) UpdatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 		CoverURL:  updated.CoverURL,
 		Privacy:   updated.Privacy,
 		CreatedAt: updated.CreatedAt,
+
+		FollowerCount: updated.FollowerCount,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -337,7 +457,7 @@ func (r *Router) UpdatePlaylistHandler(w http.ResponseWriter, req *http.Request)
 
 // DeletePlaylistHandler godoc
 // @Summary Delete Playlist
-// @Description Delete a playlist (only for the creator)
+// @Description Move a playlist to the trash (only for the creator). It can be recovered from /api/me/deleted-playlists until it is purged, 30 days later by default.
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
@@ -380,14 +500,14 @@ func (r *Router) DeletePlaylistHandler(w http.ResponseWriter, req *http.Request)
 
 // AddTrackToPlaylistHandler godoc
 // @Summary Add Track to Playlist
-// @Description Add a track to a playlist
+// @Description Add a track to a playlist (owner or editor). The track is attributed to the caller and inserted at position, or appended when no position is given. The same track may be added more than once.
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
 // @Param id path int true "Playlist ID"
-// @Param track body models.AddTrackToPlaylistRequest true "Track ID"
-// @Success 201
+// @Param track body models.AddTrackToPlaylistRequest true "Track ID and optional position"
+// @Success 201 {object} models.PlaylistTrack
 // @Failure 400 {object} api_errors.ErrorResponse
 // @Failure 401 {object} api_errors.ErrorResponse
 // @Failure 403 {object} api_errors.ErrorResponse
@@ -415,34 +535,38 @@ func (r *Router) AddTrackToPlaylistHandler(w http.ResponseWriter, req *http.Requ
 	slog.Info("Adding track to playlist", "playlistID", playlistID, "trackID", request.TrackID, "userID", userID)
 
 	playlistRepo := repository.NewPlaylistRepository(r.Db)
-	err = playlistRepo.AddTrackToPlaylist(playlistID, request.TrackID, userID)
+	entry, err := playlistRepo.AddTrackToPlaylist(playlistID, request.TrackID, userID, request.Position, request.Version)
 	if err != nil {
 		slog.Error("Failed to add track to playlist", "error", err, "playlistID", playlistID, "trackID", request.TrackID)
-		if err.Error() == "playlist not found" || err.Error() == "track not found" {
-			utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
-		} else if err.Error() == "you don't have permission to modify this playlist" {
-			utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
-		} else if err.Error() == "track already in playlist" {
-			utils.JSONError(w, "CONFLICT", err.Error(), http.StatusBadRequest)
-		} else {
-			slog.Error("Failed to add track to playlist", "error", err)
-			utils.JSONError(w, "INTERNAL_ERROR", "Failed to add track to playlist", http.StatusInternalServerError)
-		}
+		writePlaylistTracksError(w, err, "Failed to add track to playlist")
 		return
 	}
 
 	slog.Info("Track added to playlist successfully", "playlistID", playlistID, "trackID", request.TrackID)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Track added to playlist",
-	})
+	utils.JSONSuccess(w, entry, http.StatusCreated)
+}
+
+// writePlaylistTracksError maps errors from track list changes to responses
+func writePlaylistTracksError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found", "track not found", "track not found in playlist", "entry not found in playlist",
+		"album not found", "source playlist not found":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "you don't have permission to modify this playlist":
+		utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	case "playlist was modified, reload and try again", "smart playlist tracks are managed by its rules":
+		utils.JSONError(w, "CONFLICT", err.Error(), http.StatusConflict)
+	case "position out of range", "order must list every entry exactly once":
+		utils.JSONError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
 }
 
 // RemoveTrackFromPlaylistHandler godoc
 // @Summary Remove Track from Playlist
-// @Description Remove a track from a playlist
+// @Description Remove every entry of a track from a playlist (owner or editor)
 // @Tags Playlists
 // @Accept  json
 // @Produce  json
@@ -476,17 +600,112 @@ func (r *Router) RemoveTrackFromPlaylistHandler(w http.ResponseWriter, req *http
 	playlistRepo := repository.NewPlaylistRepository(r.Db)
 	err = playlistRepo.RemoveTrackFromPlaylist(playlistID, trackID, userID)
 	if err != nil {
-		if err.Error() == "playlist not found" || err.Error() == "track not found in playlist" {
-			utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
-		} else if err.Error() == "you don't have permission to modify this playlist" {
-			utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
-		} else {
-			slog.Error("Failed to remove track from playlist", "error", err)
-			utils.JSONError(w, "INTERNAL_ERROR", "Failed to remove track from playlist", http.StatusInternalServerError)
-		}
+		writePlaylistTracksError(w, err, "Failed to remove track from playlist")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// RemovePlaylistEntryHandler godoc
+// @Summary Remove Playlist Entry
+// @Description Remove a single entry from a playlist, leaving other entries of the same track in place (owner or editor)
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param entryId path int true "Entry ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/entries/{entryId} [delete]
+func (r *Router) RemovePlaylistEntryHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(req)
+	playlistID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	entryID, err := strconv.Atoi(vars["entryId"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.RemovePlaylistEntry(playlistID, entryID, userID); err != nil {
+		writePlaylistTracksError(w, err, "Failed to remove entry from playlist")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderPlaylistTracksHandler godoc
+// @Summary Reorder Playlist Tracks
+// @Description Move one entry to a new zero-based position with entry_id and to_position, or replace the whole order by listing every entry ID in order. The version must match the playlist's current version; a 409 means someone else changed the playlist first.
+// @Tags Playlists
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param reorder body models.ReorderPlaylistRequest true "Move or new order"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/tracks [patch]
+func (r *Router) ReorderPlaylistTracksHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.ReorderPlaylistRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	if request.Version == nil {
+		utils.JSONError(w, "INVALID_REQUEST", "version is required", http.StatusBadRequest)
+		return
+	}
+	if request.Order == nil && (request.EntryID == nil || request.ToPosition == nil) {
+		utils.JSONError(w, "INVALID_REQUEST", "either order or entry_id and to_position are required", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if _, err := playlistRepo.ReorderPlaylistTracks(playlistID, userID, &request); err != nil {
+		writePlaylistTracksError(w, err, "Failed to reorder playlist")
+		return
+	}
+
+	playlist, err := playlistRepo.GetPlaylistByIDWithFavorites(playlistID, userID)
+	if err == nil {
+		playlist.Role, err = playlistRepo.GetPlaylistRole(playlistID, userID)
+	}
+	if err != nil {
+		slog.Error("Failed to get reordered playlist", "error", err, "playlistID", playlistID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, playlist, http.StatusOK)
+}
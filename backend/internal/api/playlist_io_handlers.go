@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"music-app/backend/internal/matching"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/playlistio"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxImportedPlaylistTracks limits how many entries an imported playlist may have
+const MaxImportedPlaylistTracks = 1000
+
+// ExportPlaylistHandler godoc
+// @Summary Export Playlist
+// @Description Download a playlist as M3U8, XSPF or JSPF with absolute stream URLs, titles, artists, durations and cover images. Visibility rules are the same as for reading the playlist.
+// @Tags Playlists
+// @Produce  plain
+// @Param id path int true "Playlist ID"
+// @Param format query string false "m3u8 (default), xspf or jspf"
+// @Success 200 {file} binary "Playlist file"
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/export [get]
+func (r *Router) ExportPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(req.URL.Query().Get("format"))
+	if format == "" {
+		format = playlistio.FormatM3U8
+	}
+	if format != playlistio.FormatM3U8 && format != playlistio.FormatXSPF && format != playlistio.FormatJSPF {
+		utils.JSONError(w, "INVALID_REQUEST", "format must be m3u8, xspf or jspf", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	userID, isAuthenticated := middleware.GetUserID(req.Context())
+
+	var playlist *models.PlaylistWithTracks
+	if isAuthenticated {
+		playlist, err = playlistRepo.GetPlaylistByIDWithFavorites(id, userID)
+	} else {
+		playlist, err = playlistRepo.GetPlaylistByID(id)
+	}
+	if err != nil {
+		utils.JSONError(w, "NOT_FOUND", "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	if isAuthenticated {
+		playlist.Role, err = playlistRepo.GetPlaylistRole(id, userID)
+		if err != nil {
+			slog.Error("Failed to get playlist role", "error", err, "playlistID", id)
+			utils.JSONError(w, "INTERNAL_ERROR", "Failed to export playlist", http.StatusInternalServerError)
+			return
+		}
+	}
+	if playlist.Privacy != models.PlaylistPrivacyPublic && playlist.Role == "" {
+		utils.JSONError(w, "NOT_FOUND", "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	baseURL := requestBaseURL(req)
+	export := &playlistio.Playlist{Title: playlist.Title}
+	for _, entry := range playlist.Tracks {
+		track := playlistio.Track{
+			Location:   fmt.Sprintf("%s/api/tracks/%d/stream", baseURL, entry.ID),
+			Identifier: fmt.Sprintf("%s/api/tracks/%d", baseURL, entry.ID),
+			Title:      entry.Title,
+			Artist:     entry.ArtistName,
+			Duration:   entry.Duration,
+		}
+		if entry.CoverImageURL != nil {
+			track.Image = *entry.CoverImageURL
+		}
+		export.Tracks = append(export.Tracks, track)
+	}
+
+	var body bytes.Buffer
+	if err := playlistio.Write(&body, format, export); err != nil {
+		slog.Error("Failed to write playlist export", "error", err, "playlistID", id, "format", format)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to export playlist", http.StatusInternalServerError)
+		return
+	}
+
+	filename := sanitizeFilename(playlist.Title) + "." + format
+	w.Header().Set("Content-Type", playlistio.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// ImportPlaylistHandler godoc
+// @Summary Import Playlist
+// @Description Create a playlist from an uploaded M3U8, XSPF or JSPF file. Each entry is matched to a catalog track by title, artist and duration; confident matches are added in order, and the response reports every entry as matched, ambiguous (with the best candidates) or missing.
+// @Tags Playlists
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param file formData file true "Playlist file"
+// @Param format formData string false "m3u8, xspf or jspf; detected from the file when omitted"
+// @Param title formData string false "Playlist title; defaults to the title in the file"
+// @Param privacy formData string false "public (default), unlisted or private"
+// @Success 201 {object} models.PlaylistImportResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api/playlists/import [post]
+func (r *Router) ImportPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseMultipartForm(MaxUploadSize); err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxUploadSize))
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(req.FormValue("format"))
+	if format == "" {
+		format = playlistio.DetectFormat(header.Filename, data)
+	}
+	parsed, err := playlistio.Parse(bytes.NewReader(data), format)
+	if err != nil {
+		if err == playlistio.ErrUnsupportedFormat {
+			utils.JSONError(w, "INVALID_REQUEST", "format must be m3u8, xspf or jspf", http.StatusBadRequest)
+			return
+		}
+		utils.JSONError(w, "INVALID_REQUEST", "failed to parse playlist: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(parsed.Tracks) == 0 {
+		utils.JSONError(w, "INVALID_REQUEST", "playlist has no tracks", http.StatusBadRequest)
+		return
+	}
+	if len(parsed.Tracks) > MaxImportedPlaylistTracks {
+		utils.JSONError(w, "INVALID_REQUEST", fmt.Sprintf("playlist may have at most %d tracks", MaxImportedPlaylistTracks), http.StatusBadRequest)
+		return
+	}
+
+	privacy := req.FormValue("privacy")
+	if !validPlaylistPrivacy(privacy) {
+		utils.JSONError(w, "INVALID_REQUEST", "privacy must be public, unlisted or private", http.StatusBadRequest)
+		return
+	}
+
+	title := strings.TrimSpace(req.FormValue("title"))
+	if title == "" {
+		title = parsed.Title
+	}
+	if title == "" {
+		title = "Imported playlist"
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	items, err := matching.NewMatcher(playlistRepo).MatchPlaylist(parsed.Tracks)
+	if err != nil {
+		slog.Error("Failed to match imported playlist", "error", err, "user_id", userID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to import playlist", http.StatusInternalServerError)
+		return
+	}
+
+	playlist, err := playlistRepo.ImportPlaylist(&models.Playlist{Title: title, CreatorID: userID, Privacy: privacy}, matching.MatchedTrackIDs(items))
+	if err != nil {
+		slog.Error("Failed to create imported playlist", "error", err, "user_id", userID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to import playlist", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.PlaylistImportResponse{
+		Playlist: models.PlaylistResponse{
+			ID:        playlist.ID,
+			Title:     playlist.Title,
+			CreatorID: playlist.CreatorID,
+			Privacy:   playlist.Privacy,
+			Role:      models.PlaylistRoleOwner,
+			CreatedAt: playlist.CreatedAt,
+		},
+		Format: format,
+		Total:  len(items),
+		Items:  items,
+	}
+
+	for _, item := range items {
+		switch item.Status {
+		case models.MatchStatusMatched:
+			response.Matched++
+		case models.MatchStatusAmbiguous:
+			response.Ambiguous++
+		default:
+			response.Missing++
+		}
+	}
+
+	slog.Info("Playlist imported", "playlistID", playlist.ID, "format", format, "total", response.Total, "matched", response.Matched)
+	utils.JSONSuccess(w, response, http.StatusCreated)
+}
+
+// requestBaseURL returns the scheme and host the client used to reach the API, honouring
+// X-Forwarded-Proto from a TLS-terminating proxy.
+func requestBaseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + req.Host
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"log/slog"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// writePlaylistHistoryError maps playlist history repository errors to responses
+func writePlaylistHistoryError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found", "revision not found":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "you don't have permission to view this playlist's history", "you don't have permission to modify this playlist":
+		utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
+}
+
+// parseRevisionVars reads the playlist and revision IDs from the path
+func parseRevisionVars(w http.ResponseWriter, req *http.Request) (int, int, bool) {
+	vars := mux.Vars(req)
+	playlistID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	revisionID, err := strconv.Atoi(vars["revisionId"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid revision ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return playlistID, revisionID, true
+}
+
+// GetPlaylistHistoryHandler godoc
+// @Summary Get Playlist History
+// @Description Get a page of a playlist's revisions, newest first (owner or collaborator). Every change to the playlist is recorded with who made it and a short summary.
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param limit query int false "Number of revisions to return (default 50, max 100)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {object} models.PlaylistHistoryResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/history [get]
+func (r *Router) GetPlaylistHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+	if o := req.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	history, err := playlistRepo.GetPlaylistHistory(playlistID, userID, limit, offset)
+	if err != nil {
+		writePlaylistHistoryError(w, err, "Failed to get playlist history")
+		return
+	}
+
+	utils.JSONSuccess(w, history, http.StatusOK)
+}
+
+// GetPlaylistRevisionHandler godoc
+// @Summary Get Playlist Revision
+// @Description Get one revision of a playlist with a snapshot of its title, privacy, cover, rules and tracks as they were after the change (owner or collaborator)
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param revisionId path int true "Revision ID"
+// @Success 200 {object} models.PlaylistRevision
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/history/{revisionId} [get]
+func (r *Router) GetPlaylistRevisionHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, revisionID, ok := parseRevisionVars(w, req)
+	if !ok {
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	revision, err := playlistRepo.GetPlaylistRevision(playlistID, revisionID, userID)
+	if err != nil {
+		writePlaylistHistoryError(w, err, "Failed to get playlist revision")
+		return
+	}
+
+	utils.JSONSuccess(w, revision, http.StatusOK)
+}
+
+// RestorePlaylistRevisionHandler godoc
+// @Summary Restore Playlist Revision
+// @Description Put a playlist back into the state recorded by a revision (owner or editor). Tracks that have since been deleted are skipped, and privacy is only restored for the owner. The restore is recorded as a new revision, so it can be undone in turn.
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param revisionId path int true "Revision ID"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/history/{revisionId}/restore [post]
+func (r *Router) RestorePlaylistRevisionHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, revisionID, ok := parseRevisionVars(w, req)
+	if !ok {
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if _, err := playlistRepo.RestorePlaylistRevision(playlistID, revisionID, userID); err != nil {
+		writePlaylistHistoryError(w, err, "Failed to restore playlist")
+		return
+	}
+
+	r.writeRefreshedPlaylist(w, playlistRepo, playlistID, userID)
+}
+
+// RecoverPlaylistHandler godoc
+// @Summary Recover Deleted Playlist
+// @Description Take a deleted playlist out of the trash (only for the creator). Deleted playlists can be recovered until they are purged.
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/recover [post]
+func (r *Router) RecoverPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.RecoverPlaylist(playlistID, userID); err != nil {
+		writePlaylistHistoryError(w, err, "Failed to recover playlist")
+		return
+	}
+
+	r.writeRefreshedPlaylist(w, playlistRepo, playlistID, userID)
+}
+
+// GetDeletedPlaylistsHandler godoc
+// @Summary Get Deleted Playlists
+// @Description List the current user's playlists in the trash, most recently deleted first, with when each will be purged
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.DeletedPlaylist
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api/me/deleted-playlists [get]
+func (r *Router) GetDeletedPlaylistsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	retention := time.Duration(r.Config.DeletedPlaylistRetentionDays) * 24 * time.Hour
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	playlists, err := playlistRepo.GetDeletedPlaylists(userID, retention)
+	if err != nil {
+		slog.Error("Failed to get deleted playlists", "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get deleted playlists", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, playlists, http.StatusOK)
+}
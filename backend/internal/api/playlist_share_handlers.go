@@ -0,0 +1,185 @@
+package api
+
+import (
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/smartplaylist"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// writeShareLinkError maps share link repository errors to responses
+func writeShareLinkError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found", "share link not found":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "you don't have permission to share this playlist":
+		utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	case "private playlists cannot be shared":
+		utils.JSONError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
+}
+
+// CreatePlaylistShareLinkHandler godoc
+// @Summary Create Playlist Share Link
+// @Description Create a secret link granting read access to a public or unlisted playlist without login. Only the owner can share a playlist.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 201 {object} models.PlaylistShareLink
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/share-links [post]
+func (r *Router) CreatePlaylistShareLinkHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	link, err := playlistRepo.CreateShareLink(playlistID, userID)
+	if err != nil {
+		writeShareLinkError(w, err, "Failed to create share link")
+		return
+	}
+
+	utils.JSONSuccess(w, link, http.StatusCreated)
+}
+
+// GetPlaylistShareLinksHandler godoc
+// @Summary Get Playlist Share Links
+// @Description List a playlist's active share links. Only the owner can see them.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {array} models.PlaylistShareLink
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/share-links [get]
+func (r *Router) GetPlaylistShareLinksHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	links, err := playlistRepo.GetShareLinks(playlistID, userID)
+	if err != nil {
+		writeShareLinkError(w, err, "Failed to get share links")
+		return
+	}
+
+	utils.JSONSuccess(w, links, http.StatusOK)
+}
+
+// RevokePlaylistShareLinkHandler godoc
+// @Summary Revoke Playlist Share Link
+// @Description Revoke a share link so it no longer grants access. Only the owner can revoke links.
+// @Tags Playlists
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param linkId path int true "Share link ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/share-links/{linkId} [delete]
+func (r *Router) RevokePlaylistShareLinkHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(req)
+	playlistID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+	linkID, err := strconv.Atoi(vars["linkId"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid share link ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.RevokeShareLink(playlistID, userID, linkID); err != nil {
+		writeShareLinkError(w, err, "Failed to revoke share link")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedPlaylistHandler godoc
+// @Summary Get Shared Playlist
+// @Description Get a playlist through a share link. No login is required; revoked links and private playlists are not found.
+// @Tags Playlists
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/shared/playlists/{token} [get]
+func (r *Router) GetSharedPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	token := mux.Vars(req)["token"]
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	playlistID, err := playlistRepo.GetPlaylistIDByShareToken(token)
+	if err != nil {
+		if err.Error() != "share link not found" {
+			slog.Error("Failed to resolve share link", "error", err)
+		}
+		utils.JSONError(w, "NOT_FOUND", "Playlist not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := playlistRepo.RefreshSmartPlaylistIfStale(playlistID, smartplaylist.MaxAge); err != nil {
+		slog.Error("Failed to refresh smart playlist", "error", err, "playlistID", playlistID)
+	}
+
+	var playlist *models.PlaylistWithTracks
+	userID, isAuthenticated := middleware.GetUserID(req.Context())
+	if isAuthenticated {
+		playlist, err = playlistRepo.GetPlaylistByIDWithFavorites(playlistID, userID)
+		if err == nil {
+			playlist.Role, err = playlistRepo.GetPlaylistRole(playlistID, userID)
+		}
+	} else {
+		playlist, err = playlistRepo.GetPlaylistByID(playlistID)
+	}
+	if err != nil {
+		slog.Error("Failed to get shared playlist", "error", err, "playlistID", playlistID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, playlist, http.StatusOK)
+}
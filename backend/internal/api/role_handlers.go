@@ -0,0 +1,322 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetRolesHandler godoc
+// @Summary List roles
+// @Description Retrieves all roles with the permissions they grant
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Role
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/roles [get]
+func (r *Router) GetRolesHandler(w http.ResponseWriter, req *http.Request) {
+	repo := repository.NewRepository(r.Db)
+	roles, err := repo.GetRoles()
+	if err != nil {
+		slog.Error("Failed to get roles", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get roles", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, roles, http.StatusOK)
+}
+
+// GetPermissionsHandler godoc
+// @Summary List permissions
+// @Description Retrieves all known permissions
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Permission
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/permissions [get]
+func (r *Router) GetPermissionsHandler(w http.ResponseWriter, req *http.Request) {
+	repo := repository.NewRepository(r.Db)
+	permissions, err := repo.GetPermissions()
+	if err != nil {
+		slog.Error("Failed to get permissions", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get permissions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, permissions, http.StatusOK)
+}
+
+// GetUserRolesHandler godoc
+// @Summary Get user roles
+// @Description Retrieves the roles assigned to a user and the permissions they grant
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserRolesResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/users/{id}/roles [get]
+func (r *Router) GetUserRolesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if _, err := repo.GetUserByID(userID); err != nil {
+		utils.JSONError(w, api_errors.ErrUserNotFound, "user not found", http.StatusNotFound)
+		return
+	}
+
+	roles, err := repo.GetUserRoles(userID)
+	if err != nil {
+		slog.Error("Failed to get user roles", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get user roles", http.StatusInternalServerError)
+		return
+	}
+
+	permissions, err := repo.GetUserPermissions(userID)
+	if err != nil {
+		slog.Error("Failed to get user permissions", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get user permissions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.UserRolesResponse{
+		UserID:      userID,
+		Roles:       roles,
+		Permissions: permissions,
+	}, http.StatusOK)
+}
+
+// AssignUserRoleHandler godoc
+// @Summary Assign role to user
+// @Description Assigns a role to a user. Assigning a role the user already has is a no-op.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param body body models.AssignRoleRequest true "Role to assign"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/users/{id}/roles [post]
+func (r *Router) AssignUserRoleHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var body models.AssignRoleRequest
+	if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+		return
+	}
+	if body.Role == "" {
+		utils.JSONError(w, api_errors.ErrMissingFields, "role is required", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if _, err := repo.GetUserByID(userID); err != nil {
+		utils.JSONError(w, api_errors.ErrUserNotFound, "user not found", http.StatusNotFound)
+		return
+	}
+
+	if err := repo.AssignRole(userID, body.Role, &actorID); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			utils.JSONError(w, api_errors.ErrRoleNotFound, "role not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to assign role", "error", err, "user_id", userID, "role", body.Role)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to assign role", http.StatusInternalServerError)
+		return
+	}
+
+	if err := repo.LogAdminAction(fmt.Sprintf("role.assign:%s", body.Role), actorID, &userID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "role assigned"}, http.StatusOK)
+}
+
+// RevokeUserRoleHandler godoc
+// @Summary Revoke role from user
+// @Description Removes a role from a user
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/users/{id}/roles/{role} [delete]
+func (r *Router) RevokeUserRoleHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(req)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+	role := vars["role"]
+
+	// Prevent admins from locking themselves out
+	if userID == actorID && role == models.RoleAdmin {
+		utils.JSONError(w, api_errors.ErrBadRequest, "you cannot revoke your own admin role", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.RevokeRole(userID, role); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			utils.JSONError(w, api_errors.ErrRoleNotFound, "role not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.JSONError(w, api_errors.ErrNotFound, "user does not have this role", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to revoke role", "error", err, "user_id", userID, "role", role)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to revoke role", http.StatusInternalServerError)
+		return
+	}
+
+	if err := repo.LogAdminAction(fmt.Sprintf("role.revoke:%s", role), actorID, &userID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "role revoked"}, http.StatusOK)
+}
+
+// GrantRolePermissionHandler godoc
+// @Summary Grant permission to role
+// @Description Adds a permission to a role
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role path string true "Role name"
+// @Param body body models.GrantPermissionRequest true "Permission to grant"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/roles/{role}/permissions [post]
+func (r *Router) GrantRolePermissionHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	role := mux.Vars(req)["role"]
+
+	var body models.GrantPermissionRequest
+	if err := utils.DecodeJSONBody(w, req, &body); err != nil {
+		return
+	}
+	if body.Permission == "" {
+		utils.JSONError(w, api_errors.ErrMissingFields, "permission is required", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.GrantPermissionToRole(role, body.Permission); err != nil {
+		writeRolePermissionError(w, err, "failed to grant permission")
+		return
+	}
+
+	if err := repo.LogAdminAction(fmt.Sprintf("permission.grant:%s:%s", role, body.Permission), actorID, nil); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "permission granted"}, http.StatusOK)
+}
+
+// RevokeRolePermissionHandler godoc
+// @Summary Revoke permission from role
+// @Description Removes a permission from a role
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param role path string true "Role name"
+// @Param permission path string true "Permission name"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/roles/{role}/permissions/{permission} [delete]
+func (r *Router) RevokeRolePermissionHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(req)
+	role := vars["role"]
+	permission := vars["permission"]
+
+	// The admin role must always be able to manage roles
+	if role == models.RoleAdmin && permission == models.PermRolesManage {
+		utils.JSONError(w, api_errors.ErrBadRequest, "cannot revoke roles.manage from the admin role", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.RevokePermissionFromRole(role, permission); err != nil {
+		writeRolePermissionError(w, err, "failed to revoke permission")
+		return
+	}
+
+	if err := repo.LogAdminAction(fmt.Sprintf("permission.revoke:%s:%s", role, permission), actorID, nil); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, map[string]string{"message": "permission revoked"}, http.StatusOK)
+}
+
+func writeRolePermissionError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrRoleNotFound):
+		utils.JSONError(w, api_errors.ErrRoleNotFound, "role not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrPermissionNotFound):
+		utils.JSONError(w, api_errors.ErrPermissionNotFound, "permission not found", http.StatusNotFound)
+	default:
+		slog.Error(message, "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, message, http.StatusInternalServerError)
+	}
+}
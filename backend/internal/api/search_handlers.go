@@ -0,0 +1,286 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SearchAllHandler godoc
+// @Summary Search everything
+// @Description Search tracks, artists, albums, public playlists and listener profiles at once. Each requested type gets a page of results, paged alike by limit and offset, with its total match count; the first page also names the best match across types. Facets count the matches per type, per genre and per year, each with the other filters applied, so a client can offer them as refinements. Genre narrows to tracks, and year to tracks (by upload year) and albums (by release year); types without the attribute have no matches while the filter is set.
+// @Tags Search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param type query string false "Comma-separated types to return: track, artist, album, playlist, user (default all)"
+// @Param genre query string false "Only tracks of this genre"
+// @Param year query int false "Only tracks and albums from this year"
+// @Param limit query int false "Number of results per type (default 5, max 50)"
+// @Param offset query int false "Offset into each type's results (default 0)"
+// @Success 200 {object} models.SearchAllResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/search/all [get]
+func (r *Router) SearchAllHandler(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	filter := models.SearchFilter{
+		Query: strings.TrimSpace(params.Get("q")),
+		Genre: strings.TrimSpace(params.Get("genre")),
+	}
+	if filter.Query == "" {
+		utils.JSONError(w, api_errors.ErrBadRequest, "search query is required", http.StatusBadRequest)
+		return
+	}
+
+	if y := params.Get("year"); y != "" {
+		year, err := strconv.Atoi(y)
+		if err != nil || year <= 0 {
+			utils.JSONError(w, api_errors.ErrBadRequest, "year must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Year = year
+	}
+
+	types := models.SearchTypes
+	if t := params.Get("type"); t != "" {
+		types = []string{}
+		for _, searchType := range strings.Split(t, ",") {
+			searchType = strings.TrimSpace(searchType)
+			if !slices.Contains(models.SearchTypes, searchType) {
+				utils.JSONError(w, api_errors.ErrBadRequest, "type must be a comma-separated list of track, artist, album, playlist and user", http.StatusBadRequest)
+				return
+			}
+			if !slices.Contains(types, searchType) {
+				types = append(types, searchType)
+			}
+		}
+	}
+
+	limit := 5
+	offset := 0
+	if l := params.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 50 {
+				limit = 50
+			}
+		}
+	}
+	if o := params.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	// Anonymous callers get no favorite or following flags
+	userID, _ := middleware.GetUserID(req.Context())
+
+	repo := repository.NewRepository(r.Db)
+	results, err := repo.SearchAll(filter, types, userID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to search", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, results, http.StatusOK)
+}
+
+// MaxRecentSearchLength limits the length of a recorded search
+const MaxRecentSearchLength = 200
+
+// SearchSuggestHandler godoc
+// @Summary Search suggestions
+// @Description Suggest tracks, artists and albums while the user types, from an in-memory prefix index that is rebuilt from the catalog every few minutes. A name is suggested when one of its words starts with the query, ignoring case and accents; popular names (by listens) come first, and names that start with or equal the query are favoured. Signed-in users also get their recent searches starting with the query, or their latest ones when the query is empty.
+// @Tags Search
+// @Produce json
+// @Param q query string false "What the user has typed so far"
+// @Param limit query int false "Number of suggestions to return (default 8, max 20)"
+// @Success 200 {object} models.SearchSuggestResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/search/suggest [get]
+func (r *Router) SearchSuggestHandler(w http.ResponseWriter, req *http.Request) {
+	query := strings.TrimSpace(req.URL.Query().Get("q"))
+
+	limit := 8
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 20 {
+				limit = 20
+			}
+		}
+	}
+
+	response := models.SearchSuggestResponse{
+		Query:       query,
+		Recent:      []models.RecentSearch{},
+		Suggestions: r.Suggestions.Lookup(query, limit),
+	}
+
+	if userID, ok := middleware.GetUserID(req.Context()); ok {
+		// A few recent searches alongside suggestions, more when there is nothing to suggest
+		recentLimit := 3
+		if query == "" {
+			recentLimit = 10
+		}
+		repo := repository.NewRepository(r.Db)
+		recent, err := repo.GetRecentSearches(userID, query, recentLimit)
+		if err != nil {
+			slog.Error("Failed to get recent searches", "error", err, "user_id", userID)
+			utils.JSONError(w, api_errors.ErrInternalServer, "failed to get recent searches", http.StatusInternalServerError)
+			return
+		}
+		response.Recent = recent
+	}
+
+	utils.JSONSuccess(w, response, http.StatusOK)
+}
+
+// GetRecentSearchesHandler godoc
+// @Summary Get my recent searches
+// @Description Returns the current user's recent searches, most recent first
+// @Tags Search
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.RecentSearch
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/recent-searches [get]
+func (r *Router) GetRecentSearchesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	searches, err := repo.GetRecentSearches(userID, "", 20)
+	if err != nil {
+		slog.Error("Failed to get recent searches", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get recent searches", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, searches, http.StatusOK)
+}
+
+// AddRecentSearchHandler godoc
+// @Summary Record a search
+// @Description Records a search the current user ran, for instance when they submit the search bar or pick a suggestion. Running a search again moves it to the front; only the latest 20 are kept.
+// @Tags Search
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param search body models.AddRecentSearchRequest true "The search"
+// @Success 201 {object} models.RecentSearch
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/recent-searches [post]
+func (r *Router) AddRecentSearchHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.AddRecentSearchRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	request.Query = strings.TrimSpace(request.Query)
+	if request.Query == "" {
+		utils.JSONError(w, api_errors.ErrValidationError, "query is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(request.Query)) > MaxRecentSearchLength {
+		utils.JSONError(w, api_errors.ErrValidationError, "query is too long", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	search, err := repo.AddRecentSearch(userID, request.Query)
+	if err != nil {
+		slog.Error("Failed to record search", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to record search", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, search, http.StatusCreated)
+}
+
+// DeleteRecentSearchHandler godoc
+// @Summary Forget a recent search
+// @Description Removes one of the current user's recent searches
+// @Tags Search
+// @Security ApiKeyAuth
+// @Param id path int true "Recent search ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/recent-searches/{id} [delete]
+func (r *Router) DeleteRecentSearchHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	searchID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid recent search ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.DeleteRecentSearch(userID, searchID); err != nil {
+		if errors.Is(err, repository.ErrRecentSearchNotFound) {
+			utils.JSONError(w, api_errors.ErrNotFound, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to delete recent search", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to delete recent search", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClearRecentSearchesHandler godoc
+// @Summary Clear my recent searches
+// @Description Removes all of the current user's recent searches
+// @Tags Search
+// @Security ApiKeyAuth
+// @Success 204
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/me/recent-searches [delete]
+func (r *Router) ClearRecentSearchesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.ClearRecentSearches(userID); err != nil {
+		slog.Error("Failed to clear recent searches", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to clear recent searches", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
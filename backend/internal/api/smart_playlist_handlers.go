@@ -0,0 +1,166 @@
+package api
+
+import (
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/smartplaylist"
+	"music-app/backend/internal/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// writeSmartPlaylistError maps smart playlist repository errors to responses
+func writeSmartPlaylistError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "playlist not found":
+		utils.JSONError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case "you don't have permission to update this playlist":
+		utils.JSONError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	case "playlist is not a smart playlist":
+		utils.JSONError(w, "CONFLICT", err.Error(), http.StatusConflict)
+	default:
+		slog.Error(fallback, "error", err)
+		utils.JSONError(w, "INTERNAL_ERROR", fallback, http.StatusInternalServerError)
+	}
+}
+
+// writeRefreshedPlaylist responds with a playlist as the user sees it after a rules change
+func (r *Router) writeRefreshedPlaylist(w http.ResponseWriter, playlistRepo *repository.PlaylistRepository, playlistID int, userID int) {
+	playlist, err := playlistRepo.GetPlaylistByIDWithFavorites(playlistID, userID)
+	if err == nil {
+		playlist.Role, err = playlistRepo.GetPlaylistRole(playlistID, userID)
+	}
+	if err != nil {
+		slog.Error("Failed to get playlist", "error", err, "playlistID", playlistID)
+		utils.JSONError(w, "INTERNAL_ERROR", "Failed to get playlist", http.StatusInternalServerError)
+		return
+	}
+	utils.JSONSuccess(w, playlist, http.StatusOK)
+}
+
+// SetPlaylistRulesHandler godoc
+// @Summary Set Smart Playlist Rules
+// @Description Turn a playlist into a smart playlist or change its rules (owner or editor). Conditions compare a field (title, artist, genre, duration, play_count, my_play_count, liked, last_played, created_at) with a value and can be nested in "all"/"any" groups; liked and my_ fields refer to the playlist owner. The rules are evaluated right away and the matching tracks replace the track list, which can no longer be edited by hand.
+// @Tags Playlists
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Param rules body models.SmartPlaylistRules true "Playlist rules"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/rules [put]
+func (r *Router) SetPlaylistRulesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	var rules models.SmartPlaylistRules
+	if err := utils.DecodeJSONBody(w, req, &rules); err != nil {
+		return
+	}
+	if err := smartplaylist.Validate(&rules); err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.SetPlaylistRules(playlistID, userID, &rules); err != nil {
+		writeSmartPlaylistError(w, err, "Failed to update playlist rules")
+		return
+	}
+
+	r.writeRefreshedPlaylist(w, playlistRepo, playlistID, userID)
+}
+
+// ClearPlaylistRulesHandler godoc
+// @Summary Remove Smart Playlist Rules
+// @Description Turn a smart playlist back into a regular playlist (owner or editor). Its current tracks are kept and can be edited by hand.
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/rules [delete]
+func (r *Router) ClearPlaylistRulesHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	if err := playlistRepo.ClearPlaylistRules(playlistID, userID); err != nil {
+		writeSmartPlaylistError(w, err, "Failed to update playlist rules")
+		return
+	}
+
+	r.writeRefreshedPlaylist(w, playlistRepo, playlistID, userID)
+}
+
+// RefreshSmartPlaylistHandler godoc
+// @Summary Refresh Smart Playlist
+// @Description Evaluate a smart playlist's rules now instead of waiting for its cached tracks to expire (owner or collaborator)
+// @Tags Playlists
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path int true "Playlist ID"
+// @Success 200 {object} models.PlaylistWithTracks
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /api/playlists/{id}/refresh [post]
+func (r *Router) RefreshSmartPlaylistHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := getUserID(w, req)
+	if !ok {
+		return
+	}
+
+	playlistID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, "INVALID_REQUEST", "Invalid playlist ID", http.StatusBadRequest)
+		return
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(r.Db)
+	role, err := playlistRepo.GetPlaylistRole(playlistID, userID)
+	if err != nil {
+		writeSmartPlaylistError(w, err, "Failed to refresh playlist")
+		return
+	}
+	// Respond as if playlists the user has no part in don't exist
+	if role == "" {
+		utils.JSONError(w, "NOT_FOUND", "playlist not found", http.StatusNotFound)
+		return
+	}
+
+	if err := playlistRepo.RefreshSmartPlaylist(playlistID); err != nil {
+		writeSmartPlaylistError(w, err, "Failed to refresh playlist")
+		return
+	}
+
+	r.writeRefreshedPlaylist(w, playlistRepo, playlistID, userID)
+}
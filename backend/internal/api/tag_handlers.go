@@ -0,0 +1,458 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-app/backend/internal/listing"
+	"music-app/backend/internal/middleware"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/utils"
+	"music-app/backend/pkg/api_errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Tag limits
+const (
+	MaxTagLength    = 50
+	MaxTagsPerTrack = 10
+)
+
+// normalizeTagName lowercases a tag name and collapses its whitespace. Commas and slashes
+// are rejected, since tag lists are comma-separated and tag names appear in URL paths.
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	switch {
+	case name == "":
+		return "", errors.New("tag name is required")
+	case len([]rune(name)) > MaxTagLength:
+		return "", fmt.Errorf("tag names must be at most %d characters", MaxTagLength)
+	case strings.ContainsAny(name, ",/"):
+		return "", errors.New("tag names cannot contain commas or slashes")
+	}
+	return name, nil
+}
+
+// GetTagsHandler godoc
+// @Summary List tags
+// @Description Returns every tag in the taxonomy in alphabetical order, with the number of published tracks that carry it
+// @Tags Tags
+// @Produce json
+// @Success 200 {array} models.Tag
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tags [get]
+func (r *Router) GetTagsHandler(w http.ResponseWriter, req *http.Request) {
+	repo := repository.NewRepository(r.Db)
+	tags, err := repo.GetTags()
+	if err != nil {
+		slog.Error("Failed to get tags", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get tags", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, tags, http.StatusOK)
+}
+
+// GetPopularTagsHandler godoc
+// @Summary Popular tags
+// @Description Returns the tags on the most published tracks, most used first, for a tag cloud. Clients can scale each tag by its track count.
+// @Tags Tags
+// @Produce json
+// @Param limit query int false "Number of tags to return (default 30, max 100)"
+// @Success 200 {array} models.Tag
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tags/popular [get]
+func (r *Router) GetPopularTagsHandler(w http.ResponseWriter, req *http.Request) {
+	limit := 30
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	tags, err := repo.GetPopularTags(limit)
+	if err != nil {
+		slog.Error("Failed to get popular tags", "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get popular tags", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, tags, http.StatusOK)
+}
+
+// GetTagTracksHandler godoc
+// @Summary Get tracks with a tag
+// @Description Returns published tracks carrying a tag, newest first unless sorted otherwise. Accepts the same filters and sorts as the track listing.
+// @Tags Tags
+// @Produce json
+// @Param name path string true "Tag name"
+// @Param genre query []string false "Only these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param genre_id query int false "Only tracks in this genre or its sub-genres"
+// @Param min_duration query int false "Minimum duration in seconds"
+// @Param max_duration query int false "Maximum duration in seconds"
+// @Param year query int false "Only tracks released in this year"
+// @Param artist_id query int false "Only tracks by this artist"
+// @Param has_lyrics query bool false "Only tracks with (true) or without (false) lyrics"
+// @Param min_bitrate query int false "Minimum bitrate in kbps"
+// @Param max_bitrate query int false "Maximum bitrate in kbps"
+// @Param sort query string false "popularity, title, duration, newest or most_liked (default newest)"
+// @Param order query string false "asc or desc (default depends on sort)"
+// @Param limit query int false "Number of tracks to return (default 50)"
+// @Param offset query int false "Offset for pagination (default 0)"
+// @Success 200 {array} models.TrackWithArtist
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tags/{name}/tracks [get]
+func (r *Router) GetTagTracksHandler(w http.ResponseWriter, req *http.Request) {
+	name, err := normalizeTagName(mux.Vars(req)["name"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := req.URL.Query()
+	if params.Has("tag") {
+		utils.JSONError(w, api_errors.ErrBadRequest, "the tag comes from the path", http.StatusBadRequest)
+		return
+	}
+	params.Set("tag", name)
+	opts, err := listing.Tracks.Parse(params)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := params.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := params.Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	repo := repository.NewRepository(r.Db)
+	tag, err := repo.GetTagByName(name)
+	if err != nil {
+		slog.Error("Failed to get tag", "error", err, "tag", name)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get tag", http.StatusInternalServerError)
+		return
+	}
+	if tag == nil {
+		utils.JSONError(w, api_errors.ErrTagNotFound, "tag not found", http.StatusNotFound)
+		return
+	}
+
+	// Anonymous callers get no favorite flags
+	userID, _ := middleware.GetUserID(req.Context())
+	tracks, err := repo.GetAllTracksWithFavorites(opts, userID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to get tag tracks", "error", err, "tag", name)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get tracks", http.StatusInternalServerError)
+		return
+	}
+	if tracks == nil {
+		tracks = []models.TrackWithArtist{}
+	}
+
+	utils.JSONSuccess(w, tracks, http.StatusOK)
+}
+
+// SetTrackTagsHandler godoc
+// @Summary Set track tags
+// @Description Replaces a track's tags. Tags come from the taxonomy, which admins curate; unknown names are rejected. Only the track's artist, or a user who can manage all tracks, may tag it.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Param tags body models.SetTrackTagsRequest true "The track's tags"
+// @Success 200 {object} models.TrackTagsResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/tags [put]
+func (r *Router) SetTrackTagsHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.SetTrackTagsRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+
+	names := []string{}
+	for _, tag := range request.Tags {
+		name, err := normalizeTagName(tag)
+		if err != nil {
+			utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > MaxTagsPerTrack {
+		utils.JSONError(w, api_errors.ErrValidationError, fmt.Sprintf("a track can have at most %d tags", MaxTagsPerTrack), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if r.getEditableTrack(w, repo, trackID, userID) == nil {
+		return
+	}
+
+	unknown, err := repo.GetUnknownTags(names)
+	if err != nil {
+		slog.Error("Failed to look up tags", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to set tags", http.StatusInternalServerError)
+		return
+	}
+	if len(unknown) > 0 {
+		utils.JSONError(w, api_errors.ErrValidationError, "unknown tags: "+strings.Join(unknown, ", "), http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.SetTrackTags(trackID, names); err != nil {
+		slog.Error("Failed to set track tags", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to set tags", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := repo.GetTrackTags(trackID)
+	if err != nil {
+		slog.Error("Failed to get track tags", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "tags set but failed to retrieve", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONSuccess(w, models.TrackTagsResponse{TrackID: trackID, Tags: tags}, http.StatusOK)
+}
+
+// RemoveTrackTagHandler godoc
+// @Summary Remove a tag from a track
+// @Description Removes one tag from a track. Only the track's artist, or a user who can manage all tracks, may untag it.
+// @Tags Tags
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Param name path string true "Tag name"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/tags/{name} [delete]
+func (r *Router) RemoveTrackTagHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(req)
+	trackID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+	name, err := normalizeTagName(vars["name"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if r.getEditableTrack(w, repo, trackID, userID) == nil {
+		return
+	}
+
+	if err := repo.RemoveTrackTag(trackID, name); err != nil {
+		if errors.Is(err, repository.ErrTagNotFound) {
+			utils.JSONError(w, api_errors.ErrTagNotFound, "track does not have this tag", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to remove track tag", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to remove tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTagHandler godoc
+// @Summary Create a tag
+// @Description Adds a tag to the taxonomy. Names are lowercased and their whitespace collapsed.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param tag body models.TagRequest true "The tag"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/tags [post]
+func (r *Router) CreateTagHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.TagRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, err := normalizeTagName(request.Name)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	tag, err := repo.CreateTag(name)
+	if err != nil {
+		writeTagError(w, err, "failed to create tag")
+		return
+	}
+
+	if err := repo.LogAdminAction("tag.create:"+name, actorID, &tag.ID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, tag, http.StatusCreated)
+}
+
+// UpdateTagHandler godoc
+// @Summary Rename a tag
+// @Description Renames a tag; tracks keep it under its new name
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Tag ID"
+// @Param tag body models.TagRequest true "The new name"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/tags/{id} [put]
+func (r *Router) UpdateTagHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	tagID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var request models.TagRequest
+	if err := utils.DecodeJSONBody(w, req, &request); err != nil {
+		return
+	}
+	name, err := normalizeTagName(request.Name)
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	tag, err := repo.RenameTag(tagID, name)
+	if err != nil {
+		writeTagError(w, err, "failed to rename tag")
+		return
+	}
+
+	if err := repo.LogAdminAction("tag.rename:"+name, actorID, &tagID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	utils.JSONSuccess(w, tag, http.StatusOK)
+}
+
+// DeleteTagHandler godoc
+// @Summary Delete a tag
+// @Description Removes a tag from the taxonomy and from every track that carries it
+// @Tags Admin
+// @Security ApiKeyAuth
+// @Param id path int true "Tag ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/admin/tags/{id} [delete]
+func (r *Router) DeleteTagHandler(w http.ResponseWriter, req *http.Request) {
+	actorID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	tagID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	if err := repo.DeleteTag(tagID); err != nil {
+		writeTagError(w, err, "failed to delete tag")
+		return
+	}
+
+	if err := repo.LogAdminAction("tag.delete", actorID, &tagID); err != nil {
+		slog.Warn("Failed to log admin action", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTagError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrTagNotFound):
+		utils.JSONError(w, api_errors.ErrTagNotFound, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrTagExists):
+		utils.JSONError(w, api_errors.ErrTagAlreadyExists, err.Error(), http.StatusConflict)
+	default:
+		slog.Error(message, "error", err)
+		utils.JSONError(w, api_errors.ErrInternalServer, message, http.StatusInternalServerError)
+	}
+}
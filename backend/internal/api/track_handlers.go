@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/middleware"
 	"music-app/backend/internal/models"
 	"music-app/backend/internal/repository"
@@ -31,7 +32,8 @@ import (
 // @Param title formData string true "Track Title"
 // @Param duration formData int false "Duration in seconds"
 // @Param cover_image_url formData string false "Cover Image URL"
-// @Param genre formData string false "Genre"
+// @Param draft formData bool false "Save the track as a draft instead of publishing it. Uploads by users without the tracks.publish permission are always drafts."
+// @Param genre formData string false "Genre; spellings of a known genre or its aliases are stored as that genre, and unknown genres are stored as given until curators add them"
 // @Success 201 {object} models.Track
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
@@ -54,15 +56,27 @@ func (r *Router) CreateTrackHandler(w http.ResponseWriter, req *http.Request) {
 	// Determine Artist ID
 	artistID := userID
 	if artistIDStr := req.FormValue("artist_id"); artistIDStr != "" {
-		// Check if user is admin, if so allow overriding artist_id
-		role, err := repo.GetUserRoleByID(userID)
-		if err == nil && role == "admin" {
+		// Users who can manage other users' tracks may upload on behalf of an artist
+		canManage, err := repo.UserHasPermission(userID, models.PermTracksManageAll)
+		if err == nil && canManage {
 			if aid, err := strconv.Atoi(artistIDStr); err == nil {
 				artistID = aid
 			}
 		}
 	}
 
+	// Uploads are published right away by users who may publish, and saved as drafts otherwise
+	canPublish, err := repo.UserHasPermission(userID, models.PermTracksPublish)
+	if err != nil {
+		slog.Error("Failed to check permission", "error", err, "user_id", userID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	status := "published"
+	if !canPublish || req.FormValue("draft") == "true" {
+		status = "draft"
+	}
+
 	// Get file
 	file, header, err := req.FormFile("file")
 	if err != nil {
@@ -148,12 +162,10 @@ func (r *Router) CreateTrackHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	// Helper to convert empty string to nil pointer
-	stringPtr := func(s string) *string {
-		if s == "" {
-			return nil
-		}
-		return &s
+	// Spellings of a known genre are stored as that genre
+	genre, genreID, ok := resolveGenre(w, repo, req.FormValue("genre"))
+	if !ok {
+		return
 	}
 
 	track := &models.Track{
@@ -162,7 +174,9 @@ func (r *Router) CreateTrackHandler(w http.ResponseWriter, req *http.Request) {
 		FileURL:       fileURL,
 		Duration:      duration,
 		CoverImageURL: coverImageURL,
-		Genre:         stringPtr(req.FormValue("genre")),
+		Genre:         genre,
+		GenreID:       genreID,
+		Status:        status,
 	}
 
 	if err := repo.CreateTrack(track); err != nil {
@@ -177,9 +191,93 @@ func (r *Router) CreateTrackHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	message := fmt.Sprintf("Your upload %q has been processed and published", track.Title)
+	if status == "draft" {
+		message = fmt.Sprintf("Your upload %q has been processed and saved as a draft", track.Title)
+	}
+	r.notify(models.NewNotification{
+		UserID:     userID,
+		Type:       models.NotificationUploadProcessed,
+		EntityType: "track",
+		EntityID:   &track.ID,
+		Message:    message,
+	})
+
+	// Tracks released as part of an album are announced with the album, and drafts when
+	// they are published
+	if albumID == 0 && status == "published" {
+		r.announceTrack(repo, track)
+	}
+
 	utils.JSONSuccess(w, track, http.StatusCreated)
 }
 
+// PublishTrackHandler godoc
+// @Summary Publish a draft track
+// @Description Publishes a track uploaded as a draft and announces it to the artist's followers. Requires the tracks.publish permission; only the track's artist, or a user who can manage all tracks, may publish it. Taken down tracks can only be restored by moderators.
+// @Tags Protected
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Track ID"
+// @Success 200 {object} models.Track
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /api/tracks/{id}/publish [post]
+func (r *Router) PublishTrackHandler(w http.ResponseWriter, req *http.Request) {
+	userID, ok := middleware.GetUserID(req.Context())
+	if !ok {
+		utils.JSONError(w, api_errors.ErrUnauthorized, "no user in context", http.StatusUnauthorized)
+		return
+	}
+
+	trackID, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrBadRequest, "invalid track ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repository.NewRepository(r.Db)
+	track := r.getEditableTrack(w, repo, trackID, userID)
+	if track == nil {
+		return
+	}
+
+	published, err := repo.PublishDraftTrack(trackID)
+	if err != nil {
+		slog.Error("Failed to publish track", "error", err, "track_id", trackID)
+		utils.JSONError(w, api_errors.ErrInternalServer, "failed to publish track", http.StatusInternalServerError)
+		return
+	}
+	if !published {
+		utils.JSONError(w, api_errors.ErrBadRequest, fmt.Sprintf("only drafts can be published; this track is %s", track.Status), http.StatusConflict)
+		return
+	}
+	track.Status = "published"
+
+	r.announceTrack(repo, track)
+
+	utils.JSONSuccess(w, track, http.StatusOK)
+}
+
+// announceTrack tells the followers of a track's artist about its release
+func (r *Router) announceTrack(repo *repository.Repository, track *models.Track) {
+	artist, err := repo.GetUserByID(track.ArtistID)
+	if err != nil {
+		slog.Error("Failed to get artist", "error", err, "artist_id", track.ArtistID)
+		return
+	}
+	r.notifyFollowers(track.ArtistID, models.NewNotification{
+		Type:       models.NotificationNewRelease,
+		ActorID:    &track.ArtistID,
+		EntityType: "track",
+		EntityID:   &track.ID,
+		Message:    fmt.Sprintf("%s released a new track: %s", artist.Username, track.Title),
+	})
+}
+
 // sanitizeFilename removes path separators and problematic characters from filenames
 func sanitizeFilename(filename string) string {
 	// Get just the base filename, removing any directory paths
@@ -192,7 +290,7 @@ func sanitizeFilename(filename string) string {
 
 // StreamTrackHandler godoc
 // @Summary Stream a track
-// @Description Streams an audio track by ID. Supports HTTP Range requests for seeking.
+// @Description Streams an audio track by ID. Supports HTTP Range requests for seeking. Tracks that are not published only stream to their artist and to users who can manage all tracks.
 // @Tags Tracks
 // @Produce audio/mpeg
 // @Produce audio/wav
@@ -214,16 +312,11 @@ func (r *Router) StreamTrackHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Get track from database
+	// Tracks that are not published, such as taken down ones, only stream to their artist
+	// and to users who can manage all tracks
 	repo := repository.NewRepository(r.Db)
-	track, err := repo.GetTrackByID(trackID)
-	if err != nil {
-		slog.Error("Failed to get track", "error", err, "track_id", trackID)
-		utils.JSONError(w, api_errors.ErrInternalServer, "failed to get track", http.StatusInternalServerError)
-		return
-	}
+	track := r.getVisibleTrack(w, req, repo, trackID)
 	if track == nil {
-		utils.JSONError(w, api_errors.ErrNotFound, "track not found", http.StatusNotFound)
 		return
 	}
 
@@ -323,15 +416,34 @@ func (r *Router) StreamTrackHandler(w http.ResponseWriter, req *http.Request) {
 
 // GetTracksHandler godoc
 // @Summary Get all tracks
-// @Description Retrieves a list of all published tracks with artist information
+// @Description Retrieves published tracks with artist information, newest first unless sorted otherwise
 // @Tags Tracks
 // @Produce json
+// @Param genre query []string false "Only these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param genre_id query int false "Only tracks in this genre or its sub-genres"
+// @Param tag query []string false "Only tracks with one of these tags, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum duration in seconds"
+// @Param max_duration query int false "Maximum duration in seconds"
+// @Param year query int false "Only tracks released in this year"
+// @Param artist_id query int false "Only tracks by this artist"
+// @Param has_lyrics query bool false "Only tracks with (true) or without (false) lyrics"
+// @Param min_bitrate query int false "Minimum bitrate in kbps"
+// @Param max_bitrate query int false "Maximum bitrate in kbps"
+// @Param sort query string false "popularity, title, duration, newest or most_liked"
+// @Param order query string false "asc or desc (default depends on sort)"
 // @Param limit query int false "Number of tracks to return (default 50)"
 // @Param offset query int false "Offset for pagination (default 0)"
 // @Success 200 {array} models.TrackWithArtist
+// @Failure 400 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
 // @Router /api/tracks [get]
 func (r *Router) GetTracksHandler(w http.ResponseWriter, req *http.Request) {
+	opts, err := listing.Tracks.Parse(req.URL.Query())
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Parse query parameters
 	limit := 50
 	offset := 0
@@ -354,14 +466,13 @@ func (r *Router) GetTracksHandler(w http.ResponseWriter, req *http.Request) {
 	userID, isAuthenticated := middleware.GetUserID(req.Context())
 
 	var tracks []models.TrackWithArtist
-	var err error
 
 	if isAuthenticated {
 		// Get tracks with favorite status for authenticated users
-		tracks, err = repo.GetAllTracksWithFavorites(userID, limit, offset)
+		tracks, err = repo.GetAllTracksWithFavorites(opts, userID, limit, offset)
 	} else {
 		// Get tracks without favorite status for unauthenticated users
-		tracks, err = repo.GetAllTracks(limit, offset)
+		tracks, err = repo.GetAllTracks(opts, limit, offset)
 	}
 
 	if err != nil {
@@ -380,10 +491,22 @@ func (r *Router) GetTracksHandler(w http.ResponseWriter, req *http.Request) {
 
 // SearchHandler godoc
 // @Summary Search tracks
-// @Description Search for tracks by title, artist, or genre
+// @Description Search published tracks by title, artist, genre and lyrics, best matches first. Matching ignores case and accents, treats each word as a prefix and tolerates small typos in titles and artist names. Each result carries highlights: the matched fields as HTML with matches wrapped in <mark>, and a snippet of the lyrics when they matched.
 // @Tags Tracks
 // @Produce json
 // @Param q query string true "Search query"
+// @Param genre query []string false "Only these genres, comma-separated or repeated" collectionFormat(multi)
+// @Param genre_id query int false "Only tracks in this genre or its sub-genres"
+// @Param tag query []string false "Only tracks with one of these tags, comma-separated or repeated" collectionFormat(multi)
+// @Param min_duration query int false "Minimum duration in seconds"
+// @Param max_duration query int false "Maximum duration in seconds"
+// @Param year query int false "Only tracks released in this year"
+// @Param artist_id query int false "Only tracks by this artist"
+// @Param has_lyrics query bool false "Only tracks with (true) or without (false) lyrics"
+// @Param min_bitrate query int false "Minimum bitrate in kbps"
+// @Param max_bitrate query int false "Maximum bitrate in kbps"
+// @Param sort query string false "popularity, title, duration, newest or most_liked (default best match)"
+// @Param order query string false "asc or desc (default depends on sort)"
 // @Param limit query int false "Number of tracks to return (default 50)"
 // @Param offset query int false "Offset for pagination (default 0)"
 // @Success 200 {array} models.TrackWithArtist
@@ -397,6 +520,12 @@ func (r *Router) SearchHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	opts, err := listing.Tracks.Parse(req.URL.Query())
+	if err != nil {
+		utils.JSONError(w, api_errors.ErrValidationError, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	limit := 50
 	offset := 0
 
@@ -418,14 +547,13 @@ func (r *Router) SearchHandler(w http.ResponseWriter, req *http.Request) {
 	userID, isAuthenticated := middleware.GetUserID(req.Context())
 
 	var tracks []models.TrackWithArtist
-	var err error
 
 	if isAuthenticated {
 		// Search tracks with favorite status for authenticated users
-		tracks, err = repo.SearchTracksWithFavorites(query, userID, limit, offset)
+		tracks, err = repo.SearchTracksWithFavorites(query, opts, userID, limit, offset)
 	} else {
 		// Search tracks without favorite status for unauthenticated users
-		tracks, err = repo.SearchTracks(query, limit, offset)
+		tracks, err = repo.SearchTracks(query, opts, limit, offset)
 	}
 
 	if err != nil {
@@ -570,7 +698,7 @@ func (r *Router) DeleteTrackHandler(w http.ResponseWriter, req *http.Request) {
 
 // UpdateTrackHandler godoc
 // @Summary Update a track's details
-// @Description Updates the title, genre, or cover image URL of a track owned by the current user
+// @Description Updates the title, genre, or cover image URL of a track owned by the current user. The genre is resolved against the genre taxonomy as on upload.
 // @Tags Protected
 // @Accept json
 // @Produce json
@@ -640,8 +768,16 @@ func (r *Router) UpdateTrackHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	var genre *string
+	var genreID *int
+	if updateData.Genre != nil {
+		if genre, genreID, ok = resolveGenre(w, repo, *updateData.Genre); !ok {
+			return
+		}
+	}
+
 	// Update track
-	if err := repo.UpdateTrack(trackID, updateData.Title, updateData.Genre, updateData.CoverImageURL); err != nil {
+	if err := repo.UpdateTrack(trackID, updateData.Title, genre, genreID, updateData.CoverImageURL); err != nil {
 		slog.Error("Failed to update track", "error", err, "track_id", trackID)
 		utils.JSONError(w, api_errors.ErrInternalServer, "failed to update track", http.StatusInternalServerError)
 		return
@@ -0,0 +1,139 @@
+// Package dataimport reads the account data exports of other music services: listening
+// history and playlists from Spotify, and scrobbles from Last.fm.
+package dataimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"music-app/backend/internal/playlistio"
+)
+
+// Supported sources
+const (
+	SourceSpotify = "spotify"
+	SourceLastfm  = "lastfm"
+)
+
+// Limits on uploaded ZIPs, so a small archive cannot expand into more than the worker can hold
+const (
+	maxArchiveFileSize  = 200 << 20 // read from a single file
+	maxArchiveTotalSize = 500 << 20 // read from all files together
+	maxArchiveEntries   = 10000     // entries, including the ones that are skipped
+)
+
+var (
+	ErrUnsupportedSource = errors.New("unsupported import source")
+	ErrNothingToImport   = errors.New("no listening history or playlists found")
+)
+
+// Listen is one play of a track in the other service
+type Listen struct {
+	Title         string
+	Artist        string
+	Album         string
+	PlayedAt      time.Time
+	PlayedSeconds int // 0 if unknown
+}
+
+// Export is everything read from an upload
+type Export struct {
+	Listens   []Listen
+	Playlists []playlistio.Playlist
+}
+
+// Read parses an uploaded export for source. The upload is either a single file or a ZIP
+// archive such as the one the service lets users download; files that are not part of a
+// known export are ignored.
+func Read(source, filename string, data []byte) (*Export, error) {
+	if source != SourceSpotify && source != SourceLastfm {
+		return nil, ErrUnsupportedSource
+	}
+
+	export := &Export{}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		if len(archive.File) > maxArchiveEntries {
+			return nil, fmt.Errorf("archive has more than %d files", maxArchiveEntries)
+		}
+		remaining := int64(maxArchiveTotalSize)
+		for _, f := range archive.File {
+			// Skip everything else before reading it, since exports hold many unrelated files
+			if f.FileInfo().IsDir() || !isExportFile(source, f.Name) {
+				continue
+			}
+			content, err := readArchiveFile(f, remaining)
+			if err != nil {
+				return nil, err
+			}
+			remaining -= int64(len(content))
+			if err := export.readFile(source, f.Name, content); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+	} else if err := export.readFile(source, filename, data); err != nil {
+		return nil, err
+	}
+
+	if len(export.Listens) == 0 && len(export.Playlists) == 0 {
+		return nil, ErrNothingToImport
+	}
+	return export, nil
+}
+
+func (e *Export) readFile(source, name string, data []byte) error {
+	if !isExportFile(source, name) {
+		return nil
+	}
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if source == SourceSpotify {
+		return e.readSpotifyFile(base, data)
+	}
+	return e.readLastfmFile(base, data)
+}
+
+// isExportFile reports whether a file, by its name, can be part of an export of source
+func isExportFile(source, name string) bool {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+	if source == SourceSpotify {
+		return strings.HasSuffix(base, ".json") &&
+			(strings.HasPrefix(base, "streaminghistory") || strings.HasPrefix(base, "endsong") || strings.HasPrefix(base, "playlist"))
+	}
+	return strings.HasSuffix(base, ".csv") || strings.HasSuffix(base, ".json")
+}
+
+// readArchiveFile reads a file from an archive, failing if it holds more than
+// maxArchiveFileSize bytes or more than the remaining budget of the archive
+func readArchiveFile(f *zip.File, remaining int64) ([]byte, error) {
+	limit := min(int64(maxArchiveFileSize), remaining)
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if int64(len(data)) > limit {
+		if limit < maxArchiveFileSize {
+			return nil, errors.New("archive is too large once extracted")
+		}
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	return data, nil
+}
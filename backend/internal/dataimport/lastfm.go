@@ -0,0 +1,181 @@
+package dataimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastfmDateLayouts are the date formats written by the common Last.fm export tools
+var lastfmDateLayouts = []string{
+	"02 Jan 2006 15:04",
+	"2 Jan 2006 15:04",
+	"02 Jan 2006, 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+}
+
+// lastfmTrack is a scrobble as returned by user.getRecentTracks, which JSON exports are built from
+type lastfmTrack struct {
+	Name   string    `json:"name"`
+	Artist lastfmRef `json:"artist"`
+	Album  lastfmRef `json:"album"`
+	Date   *struct {
+		UTS string `json:"uts"`
+	} `json:"date"`
+}
+
+// lastfmRef is an artist or album, given as {"#text": ...} or, in extended responses, {"name": ...}
+type lastfmRef struct {
+	Text string `json:"#text"`
+	Name string `json:"name"`
+}
+
+func (r lastfmRef) String() string {
+	if r.Text != "" {
+		return r.Text
+	}
+	return r.Name
+}
+
+func (e *Export) readLastfmFile(name string, data []byte) error {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".csv"):
+		return e.readLastfmCSV(data)
+	case strings.HasSuffix(lower, ".json"):
+		return e.readLastfmJSON(data)
+	}
+	return nil
+}
+
+// readLastfmCSV reads scrobbles as "artist,album,track,date" rows, or by column name when
+// the file has a header row
+func (e *Export) readLastfmCSV(data []byte) error {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	columns := map[string]int{"artist": 0, "album": 1, "track": 2, "date": 3}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if first {
+			if header, ok := lastfmCSVHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		playedAt, ok := parseLastfmDate(field("date"))
+		if !ok || field("track") == "" {
+			continue
+		}
+		e.Listens = append(e.Listens, Listen{Title: field("track"), Artist: field("artist"), Album: field("album"), PlayedAt: playedAt})
+	}
+}
+
+// lastfmCSVHeader maps column names to indexes if record is a header row
+func lastfmCSVHeader(record []string) (map[string]int, bool) {
+	columns := map[string]int{}
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "artist", "artist_name":
+			columns["artist"] = i
+		case "album", "album_name":
+			columns["album"] = i
+		case "track", "title", "name", "track_name":
+			columns["track"] = i
+		case "uts", "timestamp", "date", "utc_time", "played_at":
+			// Prefer the unix timestamp when both are present
+			if _, ok := columns["date"]; !ok || name == "uts" {
+				columns["date"] = i
+			}
+		}
+	}
+	_, hasArtist := columns["artist"]
+	_, hasTrack := columns["track"]
+	return columns, hasArtist && hasTrack
+}
+
+// readLastfmJSON reads user.getRecentTracks responses: a single response, a list of pages
+// (as saved by export tools), or a plain list of tracks
+func (e *Export) readLastfmJSON(data []byte) error {
+	type page struct {
+		RecentTracks *struct {
+			Track []lastfmTrack `json:"track"`
+		} `json:"recenttracks"`
+		Track []lastfmTrack `json:"track"`
+	}
+
+	var pages []page
+	var single page
+	var tracks []lastfmTrack
+	switch {
+	case json.Unmarshal(data, &single) == nil:
+		pages = []page{single}
+	case json.Unmarshal(data, &pages) == nil && len(pages) > 0 && (pages[0].RecentTracks != nil || pages[0].Track != nil):
+	default:
+		pages = nil
+		if err := json.Unmarshal(data, &tracks); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range pages {
+		if p.RecentTracks != nil {
+			tracks = append(tracks, p.RecentTracks.Track...)
+		}
+		tracks = append(tracks, p.Track...)
+	}
+
+	for _, t := range tracks {
+		// The currently playing track has no date
+		if t.Date == nil || t.Name == "" {
+			continue
+		}
+		playedAt, ok := parseLastfmDate(t.Date.UTS)
+		if !ok {
+			continue
+		}
+		e.Listens = append(e.Listens, Listen{Title: t.Name, Artist: t.Artist.String(), Album: t.Album.String(), PlayedAt: playedAt})
+	}
+	return nil
+}
+
+func parseLastfmDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Some tools write milliseconds
+		if seconds > 1e12 {
+			seconds /= 1000
+		}
+		return time.Unix(seconds, 0).UTC(), seconds > 0
+	}
+	for _, layout := range lastfmDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,116 @@
+package dataimport
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"music-app/backend/internal/playlistio"
+)
+
+// minSpotifyPlay is the shortest stream Spotify counts as a play; anything shorter is a skip
+const minSpotifyPlay = 30 * time.Second
+
+// spotifyStream is an entry of StreamingHistory*.json from the basic account data export
+type spotifyStream struct {
+	EndTime    string `json:"endTime"` // "2006-01-02 15:04", UTC
+	ArtistName string `json:"artistName"`
+	TrackName  string `json:"trackName"`
+	MsPlayed   int    `json:"msPlayed"`
+}
+
+// spotifyExtendedStream is an entry of the extended streaming history
+// (Streaming_History_Audio_*.json, formerly endsong_*.json). Podcast plays have no track name.
+type spotifyExtendedStream struct {
+	Timestamp string  `json:"ts"`
+	MsPlayed  int     `json:"ms_played"`
+	TrackName *string `json:"master_metadata_track_name"`
+	Artist    *string `json:"master_metadata_album_artist_name"`
+	Album     *string `json:"master_metadata_album_album_name"`
+}
+
+type spotifyPlaylists struct {
+	Playlists []struct {
+		Name  string `json:"name"`
+		Items []struct {
+			Track *struct {
+				TrackName  string `json:"trackName"`
+				ArtistName string `json:"artistName"`
+				AlbumName  string `json:"albumName"`
+				TrackURI   string `json:"trackUri"`
+			} `json:"track"`
+		} `json:"items"`
+	} `json:"playlists"`
+}
+
+func (e *Export) readSpotifyFile(name string, data []byte) error {
+	lower := strings.ToLower(name)
+	if !strings.HasSuffix(lower, ".json") {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "streaminghistory_audio") || strings.HasPrefix(lower, "endsong"):
+		var streams []spotifyExtendedStream
+		if err := json.Unmarshal(data, &streams); err != nil {
+			return err
+		}
+		for _, s := range streams {
+			if s.TrackName == nil || s.Artist == nil || time.Duration(s.MsPlayed)*time.Millisecond < minSpotifyPlay {
+				continue
+			}
+			playedAt, err := time.Parse(time.RFC3339, s.Timestamp)
+			if err != nil {
+				continue
+			}
+			listen := Listen{Title: *s.TrackName, Artist: *s.Artist, PlayedAt: playedAt, PlayedSeconds: s.MsPlayed / 1000}
+			if s.Album != nil {
+				listen.Album = *s.Album
+			}
+			e.Listens = append(e.Listens, listen)
+		}
+
+	case strings.HasPrefix(lower, "streaminghistory"):
+		var streams []spotifyStream
+		if err := json.Unmarshal(data, &streams); err != nil {
+			return err
+		}
+		for _, s := range streams {
+			if s.TrackName == "" || time.Duration(s.MsPlayed)*time.Millisecond < minSpotifyPlay {
+				continue
+			}
+			playedAt, err := time.Parse("2006-01-02 15:04", s.EndTime)
+			if err != nil {
+				continue
+			}
+			// endTime is when the stream ended; record when it started
+			playedAt = playedAt.Add(-time.Duration(s.MsPlayed) * time.Millisecond)
+			e.Listens = append(e.Listens, Listen{Title: s.TrackName, Artist: s.ArtistName, PlayedAt: playedAt, PlayedSeconds: s.MsPlayed / 1000})
+		}
+
+	case strings.HasPrefix(lower, "playlist"):
+		var doc spotifyPlaylists
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		for _, p := range doc.Playlists {
+			playlist := playlistio.Playlist{Title: p.Name}
+			for _, item := range p.Items {
+				// Episodes and local files have no track
+				if item.Track == nil || item.Track.TrackName == "" {
+					continue
+				}
+				playlist.Tracks = append(playlist.Tracks, playlistio.Track{
+					Title:      item.Track.TrackName,
+					Artist:     item.Track.ArtistName,
+					Album:      item.Track.AlbumName,
+					Identifier: item.Track.TrackURI,
+				})
+			}
+			if len(playlist.Tracks) > 0 {
+				e.Playlists = append(e.Playlists, playlist)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"music-app/backend/pkg/config"
+	"music-app/backend/pkg/storage"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	dataExportJobBatch       = 10        // exports one scheduled run builds at most
+	dataExportJobTimeout     = time.Hour // how long an export may stay processing before it is assumed lost
+	maxDataExportJobAttempts = 3         // how often a lost export is retried before it fails
+)
+
+// AccountJobs runs the background work behind personal data exports and account deletion
+type AccountJobs struct {
+	Db      *sql.DB
+	Storage *storage.MinioClient
+	Config  *config.Config
+}
+
+func NewAccountJobs(db *sql.DB, storage *storage.MinioClient, cfg *config.Config) *AccountJobs {
+	return &AccountJobs{
+		Db:      db,
+		Storage: storage,
+		Config:  cfg,
+	}
+}
+
+// ProcessDataExports builds queued personal data exports one at a time. Jobs left
+// processing by an instance that stopped are queued again first, or failed once they have
+// been tried too often.
+func (j *AccountJobs) ProcessDataExports(ctx context.Context) error {
+	repo := repository.NewRepository(j.Db)
+
+	requeued, failed, err := repo.RequeueStaleDataExportJobs(dataExportJobTimeout, maxDataExportJobAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to requeue stale export jobs: %w", err)
+	}
+	if requeued > 0 || failed > 0 {
+		slog.Warn("Stale export jobs recovered", "requeued", requeued, "failed", failed)
+	}
+
+	for i := 0; i < dataExportJobBatch; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		job, err := repo.ClaimDataExportJob()
+		if err != nil {
+			return fmt.Errorf("failed to claim export job: %w", err)
+		}
+		if job == nil {
+			return nil
+		}
+		j.runDataExport(ctx, repo, job)
+	}
+	return nil
+}
+
+// runDataExport builds the export archive for a claimed job and stores it
+func (j *AccountJobs) runDataExport(ctx context.Context, repo *repository.Repository, job *models.DataExportJob) {
+	objectName, err := j.buildDataExport(ctx, repo, job.UserID)
+	if err != nil {
+		slog.Error("Data export failed", "error", err, "job_id", job.ID, "user_id", job.UserID)
+		if err := repo.FailDataExportJob(job.ID, "failed to build export"); err != nil {
+			slog.Error("Failed to mark export job as failed", "error", err, "job_id", job.ID)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(j.Config.DataExportTTLHours) * time.Hour)
+	if err := repo.CompleteDataExportJob(job.ID, objectName, expiresAt); err != nil {
+		slog.Error("Failed to complete export job", "error", err, "job_id", job.ID)
+		return
+	}
+
+	if err := repo.CreateNotification(models.NewNotification{
+		UserID:     job.UserID,
+		Type:       models.NotificationDataExportReady,
+		EntityType: "data_export",
+		EntityID:   &job.ID,
+		Message:    "Your data export is ready to download",
+	}); err != nil {
+		slog.Error("Failed to create notification", "error", err, "user_id", job.UserID, "type", models.NotificationDataExportReady)
+	}
+
+	slog.Info("Data export completed", "job_id", job.ID, "user_id", job.UserID)
+}
+
+// buildDataExport writes the ZIP to a temporary file and uploads it, returning the object name
+func (j *AccountJobs) buildDataExport(ctx context.Context, repo *repository.Repository, userID int) (string, error) {
+	data, err := repo.GetUserDataExport(userID)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+
+	files := map[string]interface{}{
+		"profile.json":   map[string]interface{}{"profile": data.Profile, "roles": data.Roles},
+		"playlists.json": data.Playlists,
+		"likes.json":     data.Likes,
+		"listens.json":   data.Listens,
+		"comments.json":  data.Comments,
+		"uploads.json":   data.Uploads,
+	}
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(content); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	// Uploaded audio files are copied from storage; a missing object should not fail the whole export
+	for _, upload := range data.Uploads {
+		objectName := j.Storage.ExtractObjectName(upload.FileURL)
+		if err := j.copyObject(ctx, zw, objectName, fmt.Sprintf("uploads/%d-%s", upload.TrackID, path.Base(objectName))); err != nil {
+			slog.Warn("Failed to add upload to export", "error", err, "track_id", upload.TrackID)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	objectName := fmt.Sprintf("exports/%s.zip", uuid.New().String())
+	if err := j.Storage.PutObject(ctx, objectName, tmp, size, "application/zip"); err != nil {
+		return "", err
+	}
+	return objectName, nil
+}
+
+func (j *AccountJobs) copyObject(ctx context.Context, zw *zip.Writer, objectName, entryName string) error {
+	obj, err := j.Storage.GetObject(ctx, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	f, err := zw.Create(entryName)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, obj)
+	return err
+}
+
+// PurgeDueAccounts deletes accounts whose cooling-off period has ended and
+// removes expired export archives
+func (j *AccountJobs) PurgeDueAccounts(ctx context.Context) error {
+	repo := repository.NewRepository(j.Db)
+
+	transferTo := 0
+	if j.Config.DeletedAccountTrackPolicy == "transfer" {
+		transferTo = j.Config.DeletedAccountTransferUserID
+	}
+
+	userIDs, err := repo.GetDueAccountDeletions()
+	if err != nil {
+		return fmt.Errorf("failed to get due account deletions: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		// The transfer account itself can only delete its tracks
+		to := transferTo
+		if to == userID {
+			to = 0
+		}
+		objects, err := repo.PurgeUser(userID, to)
+		if err != nil {
+			slog.Error("Failed to purge user", "error", err, "user_id", userID)
+			continue
+		}
+		j.deleteObjects(ctx, objects)
+		slog.Info("Account deleted", "user_id", userID, "removed_objects", len(objects))
+	}
+
+	expired, err := repo.ExpireDataExports()
+	if err != nil {
+		return fmt.Errorf("failed to expire data exports: %w", err)
+	}
+	j.deleteObjects(ctx, expired)
+
+	return nil
+}
+
+// deleteObjects removes storage objects given as URLs or object names, logging failures
+func (j *AccountJobs) deleteObjects(ctx context.Context, objects []string) {
+	for _, object := range objects {
+		objectName := j.Storage.ExtractObjectName(object)
+		if err := j.Storage.DeleteFile(ctx, objectName); err != nil {
+			slog.Warn("Failed to delete storage object", "error", err, "object_name", objectName)
+		}
+	}
+}
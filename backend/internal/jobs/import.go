@@ -0,0 +1,269 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"music-app/backend/internal/dataimport"
+	"music-app/backend/internal/matching"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/playlistio"
+	"music-app/backend/internal/repository"
+	"music-app/backend/pkg/storage"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// maxUnmatchedReported is how many unmatched tracks an import report lists
+const maxUnmatchedReported = 50
+
+const (
+	importJobBatch       = 10        // jobs one scheduled run processes at most
+	importJobTimeout     = time.Hour // how long a job may stay processing before it is assumed lost
+	maxImportJobAttempts = 3         // how often a lost job is retried before it fails
+)
+
+// ImportJobs runs imports of listening history and playlists from other services
+type ImportJobs struct {
+	Db      *sql.DB
+	Storage *storage.MinioClient
+}
+
+func NewImportJobs(db *sql.DB, storage *storage.MinioClient) *ImportJobs {
+	return &ImportJobs{
+		Db:      db,
+		Storage: storage,
+	}
+}
+
+// ProcessImports runs queued import jobs one at a time. Jobs left processing by an instance
+// that stopped are queued again first, or failed once they have been tried too often.
+func (j *ImportJobs) ProcessImports(ctx context.Context) error {
+	repo := repository.NewRepository(j.Db)
+
+	requeued, failed, err := repo.RequeueStaleImportJobs(importJobTimeout, maxImportJobAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to requeue stale import jobs: %w", err)
+	}
+	if requeued > 0 {
+		slog.Warn("Stale import jobs requeued", "count", requeued)
+	}
+	for _, job := range failed {
+		slog.Error("Import failed", "error", "gave up after repeated attempts", "job_id", job.ID, "user_id", job.UserID)
+		j.deleteUpload(ctx, &job)
+	}
+
+	for i := 0; i < importJobBatch; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		job, err := repo.ClaimImportJob()
+		if err != nil {
+			return fmt.Errorf("failed to claim import job: %w", err)
+		}
+		if job == nil {
+			return nil
+		}
+		j.runJob(ctx, repo, job)
+	}
+	return nil
+}
+
+// runJob reads the uploaded export of a claimed job, matches its tracks to the catalog,
+// backfills its listens and creates its playlists
+func (j *ImportJobs) runJob(ctx context.Context, repo *repository.Repository, job *models.ImportJob) {
+	report, err := j.runImport(ctx, repo, job)
+	j.deleteUpload(ctx, job)
+	if err != nil {
+		slog.Error("Import failed", "error", err, "job_id", job.ID, "user_id", job.UserID)
+		message := "failed to import data"
+		if errors.Is(err, dataimport.ErrNothingToImport) {
+			message = "the upload contains no listening history or playlists"
+		}
+		if err := repo.FailImportJob(job.ID, message); err != nil {
+			slog.Error("Failed to mark import job as failed", "error", err, "job_id", job.ID)
+		}
+		return
+	}
+
+	if err := repo.CompleteImportJob(job.ID, report); err != nil {
+		slog.Error("Failed to complete import job", "error", err, "job_id", job.ID)
+		return
+	}
+
+	if err := repo.CreateNotification(models.NewNotification{
+		UserID:     job.UserID,
+		Type:       models.NotificationImportCompleted,
+		EntityType: "import",
+		EntityID:   &job.ID,
+		Message:    fmt.Sprintf("Your import finished: %d plays and %d playlists added", report.ListensImported, len(report.Playlists)),
+	}); err != nil {
+		slog.Error("Failed to create notification", "error", err, "user_id", job.UserID, "type", models.NotificationImportCompleted)
+	}
+
+	slog.Info("Import completed", "job_id", job.ID, "user_id", job.UserID,
+		"listens_imported", report.ListensImported, "playlists", len(report.Playlists))
+}
+
+// deleteUpload removes the uploaded export of a job that has finished
+func (j *ImportJobs) deleteUpload(ctx context.Context, job *models.ImportJob) {
+	if job.ObjectName == nil {
+		return
+	}
+	if err := j.Storage.DeleteFile(ctx, *job.ObjectName); err != nil {
+		slog.Warn("Failed to delete import upload", "error", err, "object_name", *job.ObjectName)
+	}
+}
+
+func (j *ImportJobs) runImport(ctx context.Context, repo *repository.Repository, job *models.ImportJob) (*models.ImportReport, error) {
+	if job.ObjectName == nil {
+		return nil, fmt.Errorf("import upload is missing")
+	}
+
+	obj, err := j.Storage.GetObject(ctx, *job.ObjectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import upload: %w", err)
+	}
+
+	filename := ""
+	if job.Filename != nil {
+		filename = *job.Filename
+	}
+	export, err := dataimport.Read(job.Source, filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	playlistRepo := repository.NewPlaylistRepository(j.Db)
+	matcher := matching.NewMatcher(playlistRepo)
+	report := &models.ImportReport{Playlists: []models.ImportedPlaylist{}}
+
+	// Listens are inserted in one transaction and skipped when already imported, so they go
+	// first: a failure there leaves nothing behind for a retry to duplicate
+	if err := importListens(repo, matcher, job, export.Listens, report); err != nil {
+		return nil, err
+	}
+
+	// Playlists are recorded on the job as they are created, so a retry fills the ones it
+	// created before instead of creating them again
+	for i, p := range export.Playlists {
+		var created *models.ImportedPlaylist
+		if i < len(job.Playlists) {
+			created = &job.Playlists[i]
+		}
+		imported, err := importPlaylist(repo, playlistRepo, matcher, job, created, p.Title, p.Tracks)
+		if err != nil {
+			return nil, err
+		}
+		report.Playlists = append(report.Playlists, *imported)
+	}
+	return report, nil
+}
+
+// importPlaylist creates a private playlist with the matched tracks of an imported one, or
+// adds them to created, the playlist an earlier attempt of the job made for it
+func importPlaylist(repo *repository.Repository, playlistRepo *repository.PlaylistRepository, matcher *matching.Matcher, job *models.ImportJob, created *models.ImportedPlaylist, title string, tracks []playlistio.Track) (*models.ImportedPlaylist, error) {
+	items, err := matcher.MatchPlaylist(tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := created
+	if imported == nil {
+		if title == "" {
+			title = "Imported playlist"
+		}
+		playlist, err := playlistRepo.CreatePlaylist(&models.Playlist{Title: title, CreatorID: job.UserID, Privacy: models.PlaylistPrivacyPrivate})
+		if err != nil {
+			return nil, err
+		}
+
+		imported = &models.ImportedPlaylist{PlaylistID: playlist.ID, Title: playlist.Title, Total: len(items)}
+		for _, item := range items {
+			switch item.Status {
+			case models.MatchStatusMatched:
+				imported.Matched++
+			case models.MatchStatusAmbiguous:
+				imported.Ambiguous++
+			default:
+				imported.Missing++
+			}
+		}
+		if err := repo.AddImportJobPlaylist(job.ID, *imported); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tracks are added in one transaction, so the playlist has either all of them or none;
+	// skipping those already in it keeps a retry from adding them twice
+	if matched := matching.MatchedTrackIDs(items); len(matched) > 0 {
+		if _, err := playlistRepo.BulkAddTracks(imported.PlaylistID, job.UserID, matched, nil, nil, models.DedupeExisting); err != nil {
+			return nil, err
+		}
+	}
+	return imported, nil
+}
+
+// importListens matches each distinct track of the history once and backfills the plays
+// of matched tracks
+func importListens(repo *repository.Repository, matcher *matching.Matcher, job *models.ImportJob, listens []dataimport.Listen, report *models.ImportReport) error {
+	type trackKey struct{ title, artist string }
+	unmatched := map[trackKey]int{}
+	matchedTracks := map[trackKey]bool{}
+	toInsert := []models.ImportedListen{}
+
+	for _, l := range listens {
+		report.ListensTotal++
+		key := trackKey{l.Title, l.Artist}
+
+		// Plays may have been cut short, so their length says nothing about the track's length
+		result, err := matcher.Find(matching.Query{Title: l.Title, Artist: l.Artist})
+		if err != nil {
+			return err
+		}
+		if result.Status != models.MatchStatusMatched {
+			unmatched[key]++
+			continue
+		}
+
+		matchedTracks[key] = true
+		report.ListensMatched++
+		toInsert = append(toInsert, models.ImportedListen{
+			TrackID:        result.Best.TrackID,
+			PlayedAt:       l.PlayedAt,
+			ListenDuration: l.PlayedSeconds,
+		})
+	}
+
+	inserted, err := repo.InsertImportedListens(job.UserID, job.ID, toInsert)
+	if err != nil {
+		return err
+	}
+	report.ListensImported = inserted
+	report.TracksMatched = len(matchedTracks)
+	report.TracksMissing = len(unmatched)
+
+	for key, plays := range unmatched {
+		report.Unmatched = append(report.Unmatched, models.UnmatchedTrack{Title: key.title, Artist: key.artist, Plays: plays})
+	}
+	sort.Slice(report.Unmatched, func(a, b int) bool {
+		if report.Unmatched[a].Plays != report.Unmatched[b].Plays {
+			return report.Unmatched[a].Plays > report.Unmatched[b].Plays
+		}
+		return report.Unmatched[a].Title < report.Unmatched[b].Title
+	})
+	if len(report.Unmatched) > maxUnmatchedReported {
+		report.Unmatched = report.Unmatched[:maxUnmatchedReported]
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"log/slog"
+	"music-app/backend/internal/repository"
+	"music-app/backend/internal/smartplaylist"
+	"music-app/backend/pkg/config"
+	"music-app/backend/pkg/storage"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+)
+
+// smartPlaylistRefreshBatch is how many smart playlists one scheduled run refreshes at most
+const smartPlaylistRefreshBatch = 200
+
+const (
+	coverGenerationBatch = 100 // playlists one scheduled run generates covers for at most
+	mosaicSize           = 640 // width and height of generated covers in pixels
+	mosaicCandidates     = 8   // leading covers considered, so unreadable ones can be skipped
+)
+
+// PlaylistJobs runs background maintenance of playlists
+type PlaylistJobs struct {
+	Db      *sql.DB
+	Storage *storage.MinioClient
+	Config  *config.Config
+}
+
+func NewPlaylistJobs(db *sql.DB, storage *storage.MinioClient, cfg *config.Config) *PlaylistJobs {
+	return &PlaylistJobs{
+		Db:      db,
+		Storage: storage,
+		Config:  cfg,
+	}
+}
+
+// RefreshSmartPlaylists re-evaluates the rules of smart playlists whose cached tracks are
+// stale, so reads rarely have to wait for a refresh
+func (j *PlaylistJobs) RefreshSmartPlaylists(ctx context.Context) error {
+	playlistRepo := repository.NewPlaylistRepository(j.Db)
+
+	playlistIDs, err := playlistRepo.GetStaleSmartPlaylistIDs(smartplaylist.MaxAge, smartPlaylistRefreshBatch)
+	if err != nil {
+		return fmt.Errorf("failed to get stale smart playlists: %w", err)
+	}
+
+	refreshed := 0
+	for _, playlistID := range playlistIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := playlistRepo.RefreshSmartPlaylist(playlistID); err != nil {
+			slog.Error("Failed to refresh smart playlist", "error", err, "playlist_id", playlistID)
+			continue
+		}
+		refreshed++
+	}
+
+	if refreshed > 0 {
+		slog.Info("Smart playlists refreshed", "count", refreshed)
+	}
+	return nil
+}
+
+// PurgeDeletedPlaylists permanently deletes playlists that have been in the trash for longer
+// than the retention period
+func (j *PlaylistJobs) PurgeDeletedPlaylists(ctx context.Context) error {
+	playlistRepo := repository.NewPlaylistRepository(j.Db)
+
+	retention := time.Duration(j.Config.DeletedPlaylistRetentionDays) * 24 * time.Hour
+	purged, err := playlistRepo.PurgeDeletedPlaylists(retention)
+	if err != nil {
+		return fmt.Errorf("failed to purge deleted playlists: %w", err)
+	}
+
+	if purged > 0 {
+		slog.Info("Deleted playlists purged", "count", purged)
+	}
+	return nil
+}
+
+// GeneratePlaylistCovers gives playlists without an uploaded cover a 2x2 mosaic of the
+// covers of their first four distinct albums or tracks, or the first cover alone when there
+// are fewer. Covers are regenerated when the leading covers change.
+func (j *PlaylistJobs) GeneratePlaylistCovers(ctx context.Context) error {
+	playlistRepo := repository.NewPlaylistRepository(j.Db)
+
+	playlistIDs, err := playlistRepo.GetPlaylistsNeedingCovers(coverGenerationBatch)
+	if err != nil {
+		return fmt.Errorf("failed to get playlists needing covers: %w", err)
+	}
+
+	generated := 0
+	for _, playlistID := range playlistIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		changed, err := j.generatePlaylistCover(ctx, playlistRepo, playlistID)
+		if err != nil {
+			slog.Error("Failed to generate playlist cover", "error", err, "playlist_id", playlistID)
+			continue
+		}
+		if changed {
+			generated++
+		}
+	}
+
+	if generated > 0 {
+		slog.Info("Playlist covers generated", "count", generated)
+	}
+	return nil
+}
+
+// generatePlaylistCover brings one playlist's generated cover up to date and reports
+// whether it changed
+func (j *PlaylistJobs) generatePlaylistCover(ctx context.Context, playlistRepo *repository.PlaylistRepository, playlistID int) (bool, error) {
+	state, err := playlistRepo.GetPlaylistCoverState(playlistID, mosaicCandidates)
+	if err != nil {
+		return false, err
+	}
+
+	// Nothing to do when the leading covers are the ones the current cover was made from
+	if slices.Equal(mosaicSources(state.Leading), state.Sources) && (state.CoverURL != nil) == (len(state.Sources) > 0) {
+		_, err := playlistRepo.SetGeneratedPlaylistCover(playlistID, state.Version, state.CoverURL, state.Sources)
+		return false, err
+	}
+
+	var images []image.Image
+	var sources []string
+	for _, cover := range state.Leading {
+		img, err := j.loadCover(ctx, cover)
+		if err != nil {
+			slog.Warn("Skipping unreadable cover", "error", err, "playlist_id", playlistID, "cover", cover)
+			continue
+		}
+		images = append(images, img)
+		sources = append(sources, cover)
+		if len(images) == 4 {
+			break
+		}
+	}
+	if len(images) < 4 {
+		images, sources = images[:min(len(images), 1)], sources[:min(len(sources), 1)]
+	}
+
+	var coverURL *string
+	if len(images) > 0 {
+		data, err := storage.ComposeMosaic(images, mosaicSize)
+		if err != nil {
+			return false, err
+		}
+		objectName := fmt.Sprintf("playlist-covers/%d-%s.jpg", playlistID, uuid.New().String())
+		if err := j.Storage.PutObject(ctx, objectName, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+			return false, err
+		}
+		url := j.Storage.ObjectURL(objectName)
+		coverURL = &url
+	}
+
+	applied, err := playlistRepo.SetGeneratedPlaylistCover(playlistID, state.Version, coverURL, sources)
+	if err != nil || !applied {
+		// A cover was uploaded meanwhile, or saving failed; the new mosaic is not needed
+		if coverURL != nil {
+			j.deleteCover(ctx, *coverURL)
+		}
+		return false, err
+	}
+
+	if state.Generated && state.CoverURL != nil {
+		j.deleteCover(ctx, *state.CoverURL)
+	}
+	return true, nil
+}
+
+// mosaicSources picks the covers a mosaic is made from when all of them can be read
+func mosaicSources(leading []string) []string {
+	if len(leading) >= 4 {
+		return leading[:4]
+	}
+	return leading[:min(len(leading), 1)]
+}
+
+// loadCover reads and decodes a cover image stored in the bucket
+func (j *PlaylistJobs) loadCover(ctx context.Context, coverURL string) (image.Image, error) {
+	objectName := j.Storage.ExtractObjectName(coverURL)
+	if strings.Contains(objectName, "://") {
+		return nil, fmt.Errorf("cover is not stored in the bucket")
+	}
+
+	obj, err := j.Storage.GetObject(ctx, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return storage.DecodeImage(obj)
+}
+
+// deleteCover removes a generated cover that is no longer used
+func (j *PlaylistJobs) deleteCover(ctx context.Context, coverURL string) {
+	if err := j.Storage.DeleteFile(ctx, j.Storage.ExtractObjectName(coverURL)); err != nil {
+		slog.Warn("Failed to delete generated playlist cover", "error", err, "cover", coverURL)
+	}
+}
@@ -0,0 +1,279 @@
+// Package listing parses the filter and sort parameters shared by catalog listings and
+// compiles them to SQL. Each listing allowlists the filters and sorts it supports; values
+// are always passed as query arguments, so user input never becomes part of the SQL text.
+package listing
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Parameter limits
+const (
+	MaxGenres      = 20 // also the maximum number of tags
+	MaxGenreLength = 100
+	MaxYear        = 9999
+)
+
+// Sort directions
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+var ErrInvalidParams = errors.New("invalid listing parameters")
+
+type filterKind int
+
+const (
+	kindTextList filterKind = iota // comma-separated or repeated, matched case-insensitively
+	kindNumber                     // a non-negative integer
+	kindYear
+	kindBool
+)
+
+// filter is a condition template whose %[1]s is replaced by the value's placeholder
+type filter struct {
+	kind filterKind
+	sql  string
+}
+
+// sortField is an ORDER BY expression and its natural direction
+type sortField struct {
+	expr string
+	desc bool
+}
+
+// Listing is the set of filters and sorts one kind of listing supports
+type Listing struct {
+	name    string
+	filters map[string]filter
+	sorts   map[string]sortField
+}
+
+// filterNames are the filters of every listing, so that one a listing does not support is
+// reported rather than silently ignored among the listing's other parameters
+var filterNames = []string{"genre", "genre_id", "tag", "min_duration", "max_duration", "year", "artist_id", "has_lyrics", "min_bitrate", "max_bitrate"}
+
+// trackPlays and trackLikes count over the tracks in the FROM clause of the query they
+// are used in
+const (
+	trackPlays = "(SELECT COUNT(*) FROM listens ls WHERE ls.track_id = t.id)"
+	trackLikes = "(SELECT COUNT(*) FROM likes lk WHERE lk.track_id = t.id)"
+)
+
+// genreSubtree lists the genre whose ID is the filter value and all of its sub-genres
+const genreSubtree = "WITH RECURSIVE subtree(id) AS (SELECT %[1]s::int UNION SELECT g.id FROM genres g JOIN subtree s ON g.parent_id = s.id) SELECT id FROM subtree"
+
+// Tracks lists published tracks, aliased t
+var Tracks = &Listing{
+	name: "tracks",
+	filters: map[string]filter{
+		"genre":        {kindTextList, "LOWER(t.genre) = ANY(%[1]s)"},
+		"genre_id":     {kindNumber, "t.genre_id IN (" + genreSubtree + ")"},
+		"tag":          {kindTextList, "EXISTS (SELECT 1 FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id AND tg.name = ANY(%[1]s))"},
+		"min_duration": {kindNumber, "COALESCE(t.duration, 0) >= %[1]s"},
+		"max_duration": {kindNumber, "COALESCE(t.duration, 0) <= %[1]s"},
+		"year":         {kindYear, "EXTRACT(YEAR FROM t.created_at) = %[1]s"},
+		"artist_id":    {kindNumber, "t.artist_id = %[1]s"},
+		"has_lyrics":   {kindBool, "(COALESCE(t.lyrics, '') <> '') = %[1]s"},
+		"min_bitrate":  {kindNumber, "COALESCE(t.quality_bitrate, 0) >= %[1]s"},
+		"max_bitrate":  {kindNumber, "COALESCE(t.quality_bitrate, 0) <= %[1]s"},
+	},
+	sorts: map[string]sortField{
+		"popularity": {trackPlays, true},
+		"title":      {"LOWER(t.title)", false},
+		"duration":   {"COALESCE(t.duration, 0)", false},
+		"newest":     {"t.created_at", true},
+		"most_liked": {trackLikes, true},
+	},
+}
+
+// albumTracks joins an album, aliased a, to its tracks, aliased t
+const albumTracks = "FROM album_tracks atr JOIN tracks t ON t.id = atr.track_id WHERE atr.album_id = a.id"
+
+// Albums lists albums, aliased a. Genres, durations, plays and likes are those of the
+// album's tracks.
+var Albums = &Listing{
+	name: "albums",
+	filters: map[string]filter{
+		"genre":        {kindTextList, "EXISTS (SELECT 1 " + albumTracks + " AND LOWER(t.genre) = ANY(%[1]s))"},
+		"min_duration": {kindNumber, "(SELECT COALESCE(SUM(t.duration), 0) " + albumTracks + ") >= %[1]s"},
+		"max_duration": {kindNumber, "(SELECT COALESCE(SUM(t.duration), 0) " + albumTracks + ") <= %[1]s"},
+		"year":         {kindYear, "EXTRACT(YEAR FROM COALESCE(a.release_date, a.created_at)) = %[1]s"},
+		"artist_id":    {kindNumber, "a.artist_id = %[1]s"},
+	},
+	sorts: map[string]sortField{
+		"popularity": {"(SELECT COALESCE(SUM(" + trackPlays + "), 0) " + albumTracks + ")", true},
+		"title":      {"LOWER(a.title)", false},
+		"duration":   {"(SELECT COALESCE(SUM(t.duration), 0) " + albumTracks + ")", false},
+		"newest":     {"COALESCE(a.release_date, a.created_at)", true},
+		"most_liked": {"(SELECT COALESCE(SUM(" + trackLikes + "), 0) " + albumTracks + ")", true},
+	},
+}
+
+// artistTracks selects the published tracks, aliased t, of an artist aliased u
+const artistTracks = "FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published'"
+
+// Artists lists artists, aliased u. An artist matches a genre when they released a track in
+// it and a year when they released a track or album in it; their plays and likes are those
+// of their tracks.
+var Artists = &Listing{
+	name: "artists",
+	filters: map[string]filter{
+		"genre": {kindTextList, "EXISTS (SELECT 1 " + artistTracks + " AND LOWER(t.genre) = ANY(%[1]s))"},
+		"year": {kindYear, "(EXISTS (SELECT 1 " + artistTracks + " AND EXTRACT(YEAR FROM t.created_at) = %[1]s)" +
+			" OR EXISTS (SELECT 1 FROM albums a WHERE a.artist_id = u.id AND EXTRACT(YEAR FROM COALESCE(a.release_date, a.created_at)) = %[1]s))"},
+	},
+	sorts: map[string]sortField{
+		"popularity": {"(SELECT COALESCE(SUM(" + trackPlays + "), 0) " + artistTracks + ")", true},
+		"title":      {"LOWER(u.username)", false},
+		"newest":     {"(SELECT MAX(t.created_at) " + artistTracks + ")", true},
+		"most_liked": {"(SELECT COALESCE(SUM(" + trackLikes + "), 0) " + artistTracks + ")", true},
+	},
+}
+
+// Options are validated listing parameters
+type Options struct {
+	listing    *Listing
+	conditions []condition
+	sort       string
+	desc       bool
+}
+
+type condition struct {
+	filter filter
+	value  interface{}
+}
+
+// Parse validates the filter and sort parameters in params against the listing's
+// allowlists. Other parameters are left to the caller.
+func (l *Listing) Parse(params url.Values) (*Options, error) {
+	opts := &Options{listing: l}
+
+	for _, name := range filterNames {
+		values, ok := params[name]
+		if !ok {
+			continue
+		}
+		f, supported := l.filters[name]
+		if !supported {
+			return nil, fmt.Errorf("%w: %s cannot be filtered by %s", ErrInvalidParams, l.name, name)
+		}
+		value, err := parseValue(name, f.kind, values)
+		if err != nil {
+			return nil, err
+		}
+		opts.conditions = append(opts.conditions, condition{filter: f, value: value})
+	}
+
+	if opts.sort = params.Get("sort"); opts.sort != "" {
+		field, ok := l.sorts[opts.sort]
+		if !ok {
+			return nil, fmt.Errorf("%w: sort must be one of %s", ErrInvalidParams, strings.Join(l.sortNames(), ", "))
+		}
+		opts.desc = field.desc
+	}
+	switch params.Get("order") {
+	case "":
+	case OrderAsc:
+		opts.desc = false
+	case OrderDesc:
+		opts.desc = true
+	default:
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrInvalidParams)
+	}
+	if params.Get("order") != "" && opts.sort == "" {
+		return nil, fmt.Errorf("%w: order needs a sort", ErrInvalidParams)
+	}
+	return opts, nil
+}
+
+func (l *Listing) sortNames() []string {
+	names := make([]string, 0, len(l.sorts))
+	for name := range l.sorts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func parseValue(name string, kind filterKind, values []string) (interface{}, error) {
+	switch kind {
+	case kindTextList:
+		var list pq.StringArray
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				item = strings.ToLower(strings.TrimSpace(item))
+				if item == "" {
+					continue
+				}
+				if len(item) > MaxGenreLength {
+					return nil, fmt.Errorf("%w: %s values must be at most %d characters", ErrInvalidParams, name, MaxGenreLength)
+				}
+				if !slices.Contains(list, item) {
+					list = append(list, item)
+				}
+			}
+		}
+		if len(list) == 0 || len(list) > MaxGenres {
+			return nil, fmt.Errorf("%w: %s needs 1 to %d values", ErrInvalidParams, name, MaxGenres)
+		}
+		return list, nil
+	case kindNumber:
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: %s must be a non-negative whole number", ErrInvalidParams, name)
+		}
+		return n, nil
+	case kindYear:
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 1 || n > MaxYear {
+			return nil, fmt.Errorf("%w: %s must be a year between 1 and %d", ErrInvalidParams, name, MaxYear)
+		}
+		return n, nil
+	default:
+		b, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidParams, name)
+		}
+		return b, nil
+	}
+}
+
+// Compile returns the SQL condition for the options' filters, TRUE when there are none, and
+// the ORDER BY terms: the requested sort followed by fallback, or fallback alone. The
+// filter values are appended to args, the query's existing arguments, and returned.
+// A nil Options compiles to no filters and the fallback order.
+func (o *Options) Compile(args []interface{}, fallback string) (string, string, []interface{}) {
+	if o == nil {
+		return "TRUE", fallback, args
+	}
+
+	where := make([]string, 0, len(o.conditions))
+	for _, c := range o.conditions {
+		args = append(args, c.value)
+		where = append(where, fmt.Sprintf(c.filter.sql, fmt.Sprintf("$%d", len(args))))
+	}
+	whereSQL := "TRUE"
+	if len(where) > 0 {
+		whereSQL = strings.Join(where, " AND ")
+	}
+
+	orderBy := fallback
+	if o.sort != "" {
+		direction := "ASC"
+		if o.desc {
+			direction = "DESC"
+		}
+		// Keep items without a value for the sort at the end whichever the direction
+		orderBy = o.listing.sorts[o.sort].expr + " " + direction + " NULLS LAST, " + fallback
+	}
+	return whereSQL, orderBy, args
+}
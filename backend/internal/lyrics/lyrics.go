@@ -0,0 +1,203 @@
+// Package lyrics parses track lyrics, either plain text or time-synced in the LRC format,
+// where each line starts with the time it is sung at:
+//
+//	[ti:Song title]
+//	[00:12.30]First line
+//	[00:17.85][01:02.10]A line sung twice
+package lyrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"music-app/backend/internal/models"
+)
+
+// Lyrics limits
+const (
+	MaxLength     = 50000 // bytes
+	MaxLines      = 2000
+	MaxLineLength = 500 // characters
+)
+
+// Lyrics formats
+const (
+	FormatPlain = "plain"
+	FormatLRC   = "lrc"
+)
+
+var ErrInvalidLyrics = errors.New("invalid lyrics")
+
+var (
+	// timeTag is a line timestamp: minutes, seconds and optional hundredths (or tenths or
+	// thousandths) of a second
+	timeTag = regexp.MustCompile(`^(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	// metadataTag is an ID tag such as [ar:Artist] or [offset:+250]
+	metadataTag = regexp.MustCompile(`^([A-Za-z#]+):(.*)$`)
+	// wordTime is an enhanced LRC timestamp inside a line, marking when a word is sung
+	wordTime = regexp.MustCompile(`<\d{1,3}:\d{1,2}(?:[.:]\d{1,3})?>`)
+	// leadingTimeTag tells LRC from plain lyrics
+	leadingTimeTag = regexp.MustCompile(`(?m)^\s*\[\d{1,3}:\d{1,2}(?:[.:]\d{1,3})?\]`)
+)
+
+// Parsed is the result of parsing lyrics
+type Parsed struct {
+	Format string
+	// Text is the lyrics without timestamps or tags, one line per line, for display and search
+	Text string
+	// Lines are the synced lines in time order; nil for plain lyrics
+	Lines []models.LyricLine
+}
+
+// Parse validates lyrics in the given format, detecting it when format is empty
+func Parse(text, format string) (*Parsed, error) {
+	if len(text) > MaxLength {
+		return nil, fmt.Errorf("%w: lyrics must be at most %d bytes", ErrInvalidLyrics, MaxLength)
+	}
+	if !utf8.ValidString(text) {
+		return nil, fmt.Errorf("%w: lyrics must be UTF-8 text", ErrInvalidLyrics)
+	}
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	if format == "" {
+		format = FormatPlain
+		if leadingTimeTag.MatchString(text) {
+			format = FormatLRC
+		}
+	}
+
+	var parsed *Parsed
+	var err error
+	switch format {
+	case FormatPlain:
+		parsed, err = parsePlain(text)
+	case FormatLRC:
+		parsed, err = parseLRC(text)
+	default:
+		return nil, fmt.Errorf("%w: format must be plain or lrc", ErrInvalidLyrics)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(parsed.Text) == "" {
+		return nil, fmt.Errorf("%w: lyrics are empty", ErrInvalidLyrics)
+	}
+	return parsed, nil
+}
+
+func parsePlain(text string) (*Parsed, error) {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > MaxLines {
+		return nil, fmt.Errorf("%w: lyrics must have at most %d lines", ErrInvalidLyrics, MaxLines)
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+		if utf8.RuneCountInString(lines[i]) > MaxLineLength {
+			return nil, fmt.Errorf("%w: line %d is longer than %d characters", ErrInvalidLyrics, i+1, MaxLineLength)
+		}
+	}
+	return &Parsed{Format: FormatPlain, Text: strings.Join(lines, "\n")}, nil
+}
+
+func parseLRC(text string) (*Parsed, error) {
+	var lines []models.LyricLine
+	offset := 0
+
+	for n, raw := range strings.Split(text, "\n") {
+		rest := strings.TrimSpace(raw)
+		if rest == "" {
+			continue
+		}
+
+		var times []int
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%w: line %d has an unclosed tag", ErrInvalidLyrics, n+1)
+			}
+			tag := strings.TrimSpace(rest[1:end])
+			rest = strings.TrimSpace(rest[end+1:])
+
+			if m := timeTag.FindStringSubmatch(tag); m != nil {
+				ms, err := parseTime(m)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidLyrics, n+1, err)
+				}
+				times = append(times, ms)
+				continue
+			}
+			m := metadataTag.FindStringSubmatch(tag)
+			if m == nil {
+				return nil, fmt.Errorf("%w: line %d has an invalid tag [%s]", ErrInvalidLyrics, n+1, tag)
+			}
+			// A positive offset makes every line show up earlier
+			if strings.EqualFold(m[1], "offset") {
+				o, err := strconv.Atoi(strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d has an invalid offset", ErrInvalidLyrics, n+1)
+				}
+				offset = o
+			}
+		}
+
+		rest = strings.TrimSpace(wordTime.ReplaceAllString(rest, ""))
+		if len(times) == 0 {
+			if rest != "" {
+				return nil, fmt.Errorf("%w: line %d has no timestamp", ErrInvalidLyrics, n+1)
+			}
+			continue // a line of tags only
+		}
+		if utf8.RuneCountInString(rest) > MaxLineLength {
+			return nil, fmt.Errorf("%w: line %d is longer than %d characters", ErrInvalidLyrics, n+1, MaxLineLength)
+		}
+
+		// Lines without text mark instrumental breaks
+		for _, ms := range times {
+			lines = append(lines, models.LyricLine{TimeMs: ms, Text: rest})
+		}
+		if len(lines) > MaxLines {
+			return nil, fmt.Errorf("%w: lyrics must have at most %d lines", ErrInvalidLyrics, MaxLines)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: LRC lyrics need at least one timed line", ErrInvalidLyrics)
+	}
+
+	// The offset applies to the whole file, wherever its tag is
+	for i := range lines {
+		lines[i].TimeMs = max(0, lines[i].TimeMs-offset)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].TimeMs < lines[j].TimeMs })
+
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+	return &Parsed{Format: FormatLRC, Text: strings.TrimSpace(strings.Join(texts, "\n")), Lines: lines}, nil
+}
+
+// parseTime converts the groups of a timeTag match to milliseconds
+func parseTime(m []string) (int, error) {
+	minutes, _ := strconv.Atoi(m[1])
+	seconds, _ := strconv.Atoi(m[2])
+	if seconds >= 60 {
+		return 0, fmt.Errorf("invalid timestamp %s:%s", m[1], m[2])
+	}
+	ms := (minutes*60 + seconds) * 1000
+	if m[3] != "" {
+		fraction, _ := strconv.Atoi(m[3])
+		for i := len(m[3]); i < 3; i++ {
+			fraction *= 10
+		}
+		ms += fraction
+	}
+	return ms, nil
+}
@@ -0,0 +1,117 @@
+package matching
+
+import (
+	"regexp"
+	"strconv"
+
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/playlistio"
+)
+
+// maxLookupCandidates is how many catalog tracks are scored for each entry
+const maxLookupCandidates = 25
+
+// streamURLPattern recognises track URLs from this service in imported playlists
+var streamURLPattern = regexp.MustCompile(`/api/tracks/(\d+)(?:/stream)?/?(?:[?#].*)?$`)
+
+// Catalog looks up the catalog tracks an entry is scored against
+type Catalog interface {
+	FindTrackCandidates(title string, artist string, limit int) ([]models.TrackMatchCandidate, error)
+	GetTrackCandidatesByID(trackIDs []int) (map[int]models.TrackMatchCandidate, error)
+}
+
+// Matcher matches entries against a catalog. Results are remembered, so an entry that
+// repeats (as tracks do in listening history) is only looked up once.
+type Matcher struct {
+	catalog Catalog
+	cache   map[Query]Result
+}
+
+func NewMatcher(catalog Catalog) *Matcher {
+	return &Matcher{catalog: catalog, cache: map[Query]Result{}}
+}
+
+// Find looks up the catalog track for q
+func (m *Matcher) Find(q Query) (Result, error) {
+	if result, ok := m.cache[q]; ok {
+		return result, nil
+	}
+	if q.Title == "" {
+		return Result{Status: models.MatchStatusMissing}, nil
+	}
+
+	candidates, err := m.catalog.FindTrackCandidates(CleanTitle(q.Title), q.Artist, maxLookupCandidates)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Match(q, candidates)
+	m.cache[q] = result
+	return result, nil
+}
+
+// MatchPlaylist matches the entries of an imported playlist. Entries pointing at one of our
+// own track URLs are matched directly; the rest go through the fuzzy matcher.
+func (m *Matcher) MatchPlaylist(tracks []playlistio.Track) ([]models.PlaylistImportItem, error) {
+	items := make([]models.PlaylistImportItem, len(tracks))
+	linked := make([]int, len(tracks))
+	linkedIDs := []int{}
+	for i, t := range tracks {
+		items[i] = models.PlaylistImportItem{
+			Index:    i,
+			Title:    t.Title,
+			Artist:   t.Artist,
+			Album:    t.Album,
+			Duration: t.Duration,
+			Location: t.Location,
+			Status:   models.MatchStatusMissing,
+		}
+		for _, ref := range []string{t.Location, t.Identifier} {
+			if match := streamURLPattern.FindStringSubmatch(ref); match != nil {
+				linked[i], _ = strconv.Atoi(match[1])
+				linkedIDs = append(linkedIDs, linked[i])
+				break
+			}
+		}
+	}
+
+	direct, err := m.catalog.GetTrackCandidatesByID(linkedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, t := range tracks {
+		item := &items[i]
+		if c, ok := direct[linked[i]]; ok {
+			item.Status = models.MatchStatusMatched
+			item.TrackID = &c.TrackID
+			item.Score = c.Score
+			continue
+		}
+
+		result, err := m.Find(Query{Title: t.Title, Artist: t.Artist, Duration: t.Duration})
+		if err != nil {
+			return nil, err
+		}
+		item.Status = result.Status
+		if result.Best != nil {
+			item.Score = result.Best.Score
+			if result.Status == models.MatchStatusMatched {
+				trackID := result.Best.TrackID
+				item.TrackID = &trackID
+			}
+		}
+		item.Candidates = result.Candidates
+	}
+	return items, nil
+}
+
+// MatchedTrackIDs returns the track IDs of the matched items, in order
+func MatchedTrackIDs(items []models.PlaylistImportItem) []int {
+	ids := []int{}
+	for _, item := range items {
+		if item.Status == models.MatchStatusMatched && item.TrackID != nil {
+			ids = append(ids, *item.TrackID)
+		}
+	}
+	return ids
+}
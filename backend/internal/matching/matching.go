@@ -0,0 +1,232 @@
+// Package matching finds the catalog track that corresponds to a track described by
+// another service, using fuzzy comparison of title, artist and duration.
+package matching
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"music-app/backend/internal/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Score thresholds. A candidate is a match when it scores at least MatchThreshold and
+// beats the runner-up by AmbiguityMargin; anything from AmbiguousThreshold up is offered
+// as a possible match.
+const (
+	MatchThreshold     = 0.85
+	AmbiguousThreshold = 0.6
+	AmbiguityMargin    = 0.05
+	MaxCandidates      = 3
+)
+
+// Relative weight of each field. Fields missing from the query are left out and the
+// remaining weights are scaled up.
+const (
+	titleWeight    = 0.6
+	artistWeight   = 0.3
+	durationWeight = 0.1
+)
+
+// Query describes the track to look for
+type Query struct {
+	Title    string
+	Artist   string
+	Duration int // seconds, 0 if unknown
+}
+
+type Result struct {
+	Status     string // models.MatchStatusMatched, MatchStatusAmbiguous or MatchStatusMissing
+	Best       *models.TrackMatchCandidate
+	Candidates []models.TrackMatchCandidate // best scoring candidates, for ambiguous results
+}
+
+var (
+	bracketed    = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+	featuring    = regexp.MustCompile(`(?i)\s+(feat\.?|ft\.?|featuring)\s+.*$`)
+	versionTag   = regexp.MustCompile(`(?i)\b(remaster(ed)?|live|version|edit|mix|remix|mono|stereo|demo|acoustic|instrumental)\b`)
+	recordingTag = regexp.MustCompile(`(?i)\b(live|remix|acoustic|instrumental|demo)\b`)
+	artistSplits = regexp.MustCompile(`(?i)\s*(,|&|;|/|\bfeat\.?|\bft\.?|\bfeaturing\b|\bwith\b|\bx\b)\s*`)
+)
+
+// Match scores candidates against q and classifies the best one
+func Match(q Query, candidates []models.TrackMatchCandidate) Result {
+	scored := make([]models.TrackMatchCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		c.Score = Score(q, c)
+		scored = append(scored, c)
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	if len(scored) == 0 || scored[0].Score < AmbiguousThreshold {
+		return Result{Status: models.MatchStatusMissing}
+	}
+
+	best := scored[0]
+	if best.Score >= MatchThreshold && (len(scored) == 1 || best.Score-scored[1].Score >= AmbiguityMargin) {
+		return Result{Status: models.MatchStatusMatched, Best: &best}
+	}
+
+	result := Result{Status: models.MatchStatusAmbiguous, Best: &best}
+	for _, c := range scored {
+		if c.Score < AmbiguousThreshold || len(result.Candidates) == MaxCandidates {
+			break
+		}
+		result.Candidates = append(result.Candidates, c)
+	}
+	return result
+}
+
+// Score rates how well c matches q, from 0 to 1
+func Score(q Query, c models.TrackMatchCandidate) float64 {
+	total := titleWeight * titleSimilarity(q.Title, c.Title)
+	weights := titleWeight
+
+	if strings.TrimSpace(q.Artist) != "" {
+		total += artistWeight * artistSimilarity(q.Artist, c.ArtistName)
+		weights += artistWeight
+	}
+	if q.Duration > 0 && c.Duration > 0 {
+		total += durationWeight * durationSimilarity(q.Duration, c.Duration)
+		weights += durationWeight
+	}
+	return round(total / weights)
+}
+
+// CleanTitle strips decorations that differ between services, such as "(Remastered 2011)",
+// "[Live]", "feat. X" and " - Radio Edit", leaving the bare song title.
+func CleanTitle(title string) string {
+	cleaned := bracketed.ReplaceAllString(title, "")
+	cleaned = featuring.ReplaceAllString(cleaned, "")
+	if head, tail, ok := strings.Cut(cleaned, " - "); ok && versionTag.MatchString(tail) {
+		cleaned = head
+	}
+	cleaned = strings.TrimSpace(cleaned)
+	if cleaned == "" {
+		return strings.TrimSpace(title)
+	}
+	return cleaned
+}
+
+// Normalize lowercases s, strips accents and punctuation and collapses whitespace
+func Normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(strings.ReplaceAll(s, "&", " and ")) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// titleSimilarity compares titles both as given and with decorations stripped. The stripped
+// comparison is penalised when the titles name different recordings, so that "Song (Live)"
+// does not tie with "Song".
+func titleSimilarity(a, b string) float64 {
+	const differentRecording = 0.15
+	cleaned := similarity(Normalize(CleanTitle(a)), Normalize(CleanTitle(b)))
+	if recordingTags(a) != recordingTags(b) {
+		cleaned -= differentRecording
+	}
+	return max(similarity(Normalize(a), Normalize(b)), cleaned)
+}
+
+// recordingTags lists the tags in a title that mark a distinct recording, such as "live" or "remix"
+func recordingTags(title string) string {
+	tags := recordingTag.FindAllString(strings.ToLower(title), -1)
+	sort.Strings(tags)
+	return strings.Join(slices.Compact(tags), " ")
+}
+
+// artistSimilarity compares each credited artist of a ("A feat. B", "A & B") to b and keeps the best
+func artistSimilarity(a, b string) float64 {
+	target := Normalize(b)
+	best := similarity(Normalize(a), target)
+	for _, part := range artistSplits.Split(a, -1) {
+		if part = Normalize(part); part != "" {
+			best = max(best, similarity(part, target))
+		}
+	}
+	return best
+}
+
+// durationSimilarity is 1 within a few seconds and falls to 0 at half a minute apart
+func durationSimilarity(a, b int) float64 {
+	const exact, none = 3, 30
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	switch {
+	case diff <= exact:
+		return 1
+	case diff >= none:
+		return 0
+	}
+	return 1 - float64(diff-exact)/float64(none-exact)
+}
+
+// similarity is the edit distance ratio of two normalized strings, also compared with
+// their words sorted so that reordered words still match.
+func similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	return max(levenshteinRatio(a, b), levenshteinRatio(sortWords(a), sortWords(b)))
+}
+
+func sortWords(s string) string {
+	words := strings.Fields(s)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func round(score float64) float64 {
+	return float64(int(score*1000+0.5)) / 1000
+}
@@ -42,9 +42,48 @@ func (m *AuthMiddleware) Authenticated(next http.Handler) http.Handler {
 	})
 }
 
-// RequireRole returns a middleware that checks if the user has the required role
-// by querying the database using the user ID from JWT
-func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
+// OptionalAuthenticated attaches the user to the context when a bearer token is
+// present, so public routes can personalize their responses. Requests without
+// a token pass through anonymously; only malformed or invalid tokens are rejected.
+func (m *AuthMiddleware) OptionalAuthenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if h == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !strings.HasPrefix(h, "Bearer ") {
+			utils.JSONError(w, api_errors.ErrInvalidToken, "Invalid authorization header", http.StatusUnauthorized)
+			return
+		}
+		tokenString := strings.TrimPrefix(h, "Bearer ")
+		claims, err := m.JWTManager.ParseAccessToken(tokenString)
+		if err != nil {
+			utils.JSONError(w, api_errors.ErrInvalidToken, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		ctx := WithUserID(r.Context(), claims.UserID)
+		ctx = WithUserRole(ctx, claims.Role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AuthenticatedStream is Authenticated for streaming endpoints consumed by the
+// browser EventSource API, which cannot set headers. The access token may be
+// passed as the access_token query parameter instead of the Authorization header.
+func (m *AuthMiddleware) AuthenticatedStream(next http.Handler) http.Handler {
+	authenticated := m.Authenticated(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission returns a middleware that checks if any of the user's roles
+// grants the required permission by querying role_permissions
+func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID, ok := GetUserID(r.Context())
@@ -53,16 +92,15 @@ func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handle
 				return
 			}
 
-			// Query database to get actual user role
 			repo := repository.NewRepository(m.Db)
-			userRole, err := repo.GetUserRoleByID(userID)
+			allowed, err := repo.UserHasPermission(userID, permission)
 			if err != nil {
-				slog.Error("Failed to get user role from database", "userID", userID, "error", err)
-				utils.JSONError(w, api_errors.ErrInternalServer, "Failed to verify user role", http.StatusInternalServerError)
+				slog.Error("Failed to check user permission", "userID", userID, "permission", permission, "error", err)
+				utils.JSONError(w, api_errors.ErrInternalServer, "Failed to verify user permissions", http.StatusInternalServerError)
 				return
 			}
 
-			if userRole != role {
+			if !allowed {
 				utils.JSONError(w, api_errors.ErrForbidden, "Insufficient permissions", http.StatusForbidden)
 				return
 			}
@@ -71,15 +109,3 @@ func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handle
 		})
 	}
 }
-
-// RequireAdmin is a convenience middleware that requires the admin role
-// It queries the database to verify the user's role using user ID from JWT
-func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
-	return m.RequireRole("admin")(next)
-}
-
-// RequireUser is a convenience middleware that requires the user role
-// It queries the database to verify the user's role using user ID from JWT
-func (m *AuthMiddleware) RequireUser(next http.Handler) http.Handler {
-	return m.RequireRole("user")(next)
-}
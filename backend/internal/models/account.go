@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Data export job statuses
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusCompleted  = "completed"
+	ExportStatusFailed     = "failed"
+	ExportStatusExpired    = "expired"
+)
+
+// DataExportJob tracks an asynchronous personal data export
+type DataExportJob struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	ObjectName  *string    `json:"-"`
+	Error       *string    `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccountDeletion is a pending account deletion inside its cooling-off period
+type AccountDeletion struct {
+	UserID       int       `json:"user_id"`
+	RequestedAt  time.Time `json:"requested_at"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// UserDataExport is everything stored about a user, written to the export ZIP
+type UserDataExport struct {
+	Profile   ExportProfile    `json:"profile"`
+	Roles     []UserRole       `json:"roles"`
+	Playlists []ExportPlaylist `json:"playlists"`
+	Likes     []ExportLike     `json:"likes"`
+	Listens   []ExportListen   `json:"listens"`
+	Comments  []ExportComment  `json:"comments"`
+	Uploads   []ExportUpload   `json:"uploads"`
+
+	RecentSearches []RecentSearch `json:"recent_searches"`
+}
+
+type ExportProfile struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	AvatarURL *string   `json:"avatar_url,omitempty"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportPlaylist struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Privacy   string    `json:"privacy"`
+	CoverURL  *string   `json:"cover_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	TrackIDs  []int64   `json:"track_ids"`
+}
+
+type ExportLike struct {
+	TrackID   int       `json:"track_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportListen struct {
+	TrackID        int       `json:"track_id"`
+	Title          string    `json:"title"`
+	Device         string    `json:"device,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	ListenDuration int       `json:"listen_duration,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+type ExportComment struct {
+	ID        int       `json:"id"`
+	TrackID   int       `json:"track_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportUpload struct {
+	TrackID       int       `json:"track_id"`
+	Title         string    `json:"title"`
+	FileURL       string    `json:"file_url"`
+	CoverImageURL *string   `json:"cover_image_url,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
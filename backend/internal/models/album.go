@@ -15,4 +15,7 @@ type AlbumWithTracks struct {
 	Album
 	ArtistName string            `json:"artist_name"`
 	Tracks     []TrackWithArtist `json:"tracks"`
+
+	// Search results only: matched fields as HTML with the matches wrapped in <mark>
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
@@ -16,9 +16,12 @@ type Artist struct {
 // ArtistDetails includes full artist information with stats
 type ArtistDetails struct {
 	Artist
-	TotalTracks  int `json:"total_tracks"`
-	TotalAlbums  int `json:"total_albums"`
-	TotalListens int `json:"total_listens"`
+	TotalTracks    int  `json:"total_tracks"`
+	TotalAlbums    int  `json:"total_albums"`
+	TotalListens   int  `json:"total_listens"`
+	FollowerCount  int  `json:"follower_count"`
+	FollowingCount int  `json:"following_count"`
+	IsFollowing    bool `json:"is_following,omitempty"`
 }
 
 // ArtistWithStats represents an artist with basic statistics
@@ -30,4 +33,7 @@ type ArtistWithStats struct {
 	TotalTracks  int     `json:"total_tracks"`
 	TotalAlbums  int     `json:"total_albums"`
 	TotalListens int     `json:"total_listens"`
+
+	// Search results only: the username as HTML with the matches wrapped in <mark>
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Comment is a comment on a track. Replies have a ParentID pointing at a top-level comment,
+// and top-level comments may be pinned to a position in the track with PositionMs.
+type Comment struct {
+	ID         int        `json:"id"`
+	TrackID    int        `json:"track_id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	AvatarURL  *string    `json:"avatar_url,omitempty"`
+	ParentID   *int       `json:"parent_id,omitempty"`
+	Content    string     `json:"content"`
+	PositionMs *int       `json:"position_ms,omitempty"`
+	Resolved   bool       `json:"resolved"`
+	ReplyCount int        `json:"reply_count"`
+	Replies    []Comment  `json:"replies,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// CommentsResponse is a page of top-level comments with their replies
+type CommentsResponse struct {
+	Comments []Comment `json:"comments"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
+type CreateCommentRequest struct {
+	Content    string `json:"content"`
+	ParentID   *int   `json:"parent_id,omitempty"`
+	PositionMs *int   `json:"position_ms,omitempty"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
+
+// CommentTimelineBucket groups the timestamped comments in one slice of a track
+type CommentTimelineBucket struct {
+	StartMs  int       `json:"start_ms"`
+	EndMs    int       `json:"end_ms"`
+	Count    int       `json:"count"`
+	Comments []Comment `json:"comments"`
+}
+
+// CommentTimelineResponse is the timestamped comments of a track bucketed for waveform rendering
+type CommentTimelineResponse struct {
+	TrackID      int                     `json:"track_id"`
+	DurationMs   int                     `json:"duration_ms"`
+	BucketSizeMs int                     `json:"bucket_size_ms"`
+	Buckets      []CommentTimelineBucket `json:"buckets"`
+}
+
+// TrackFeedback is a top-level comment on one of the artist's own tracks
+type TrackFeedback struct {
+	Comment
+	TrackTitle string `json:"track_title"`
+}
+
+// TrackFeedbackResponse is a page of feedback on the artist's own tracks
+type TrackFeedbackResponse struct {
+	Comments []TrackFeedback `json:"comments"`
+	Total    int             `json:"total"`
+	Limit    int             `json:"limit"`
+	Offset   int             `json:"offset"`
+}
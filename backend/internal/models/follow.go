@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// FollowUser is a user in a follower or following list
+type FollowUser struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	AvatarURL  *string   `json:"avatar_url,omitempty"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowListResponse is a page of followers or followed users
+type FollowListResponse struct {
+	Users  []FollowUser `json:"users"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// Release is a new track or album from a followed artist
+type Release struct {
+	Type       string    `json:"type"` // "track" or "album"
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	ArtistID   int       `json:"artist_id"`
+	ArtistName string    `json:"artist_name"`
+	CoverURL   *string   `json:"cover_url,omitempty"`
+	ReleasedAt time.Time `json:"released_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Genre is a node of the genre taxonomy. Track counts are of published tracks: TrackCount
+// those in the genre itself and TotalTrackCount those in it or any of its sub-genres.
+type Genre struct {
+	ID              int      `json:"id"`
+	Name            string   `json:"name"`
+	Slug            string   `json:"slug"`
+	ParentID        *int     `json:"parent_id,omitempty"`
+	Aliases         []string `json:"aliases"`
+	TrackCount      int      `json:"track_count"`
+	TotalTrackCount int      `json:"total_track_count"`
+	Children        []Genre  `json:"children"`
+}
+
+// GenreRef names a genre
+type GenreRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// GenreDetail is a genre with its sub-genres and its ancestors, root first
+type GenreDetail struct {
+	Genre
+	Ancestors []GenreRef `json:"ancestors"`
+}
+
+// GenreRequest creates a genre or changes its name or parent. A nil ParentID makes it a
+// top-level genre.
+type GenreRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+type GenreAliasRequest struct {
+	Alias string `json:"alias"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Import job statuses
+const (
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// ImportJob tracks an asynchronous import of another service's account data
+type ImportJob struct {
+	ID          int                `json:"id"`
+	UserID      int                `json:"user_id"`
+	Source      string             `json:"source"` // "spotify" or "lastfm"
+	Filename    *string            `json:"filename,omitempty"`
+	ObjectName  *string            `json:"-"`
+	Status      string             `json:"status"`
+	Report      *ImportReport      `json:"report,omitempty"`
+	Playlists   []ImportedPlaylist `json:"-"` // playlists created so far, so a retry does not create them again
+	Error       *string            `json:"error,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// ImportReport summarises what an import job brought over
+type ImportReport struct {
+	ListensTotal    int                `json:"listens_total"`    // plays found in the upload
+	ListensMatched  int                `json:"listens_matched"`  // plays of tracks found in our catalog
+	ListensImported int                `json:"listens_imported"` // matched plays not already imported before
+	TracksMatched   int                `json:"tracks_matched"`   // distinct tracks matched
+	TracksMissing   int                `json:"tracks_missing"`   // distinct tracks not found or ambiguous
+	Playlists       []ImportedPlaylist `json:"playlists"`
+	Unmatched       []UnmatchedTrack   `json:"unmatched,omitempty"` // most played tracks that could not be matched
+}
+
+// ImportedPlaylist is a playlist created by an import job
+type ImportedPlaylist struct {
+	PlaylistID int    `json:"playlist_id"`
+	Title      string `json:"title"`
+	Total      int    `json:"total"`
+	Matched    int    `json:"matched"`
+	Ambiguous  int    `json:"ambiguous"`
+	Missing    int    `json:"missing"`
+}
+
+type UnmatchedTrack struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist,omitempty"`
+	Plays  int    `json:"plays"`
+}
+
+// ImportedListen is a play from another service to be backfilled into listens
+type ImportedListen struct {
+	TrackID        int
+	PlayedAt       time.Time
+	ListenDuration int
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Library item types
+const (
+	LibraryItemFolder   = "folder"
+	LibraryItemPlaylist = "playlist"
+	LibraryItemAlbum    = "album"
+	LibraryItemArtist   = "artist"
+)
+
+// Library sort orders
+const (
+	LibrarySortCustom = "custom" // folder and playlist positions set by the user
+	LibrarySortName   = "name"
+	LibrarySortRecent = "recent" // most recently added first
+)
+
+// LibraryItem is a folder, playlist, saved album or followed artist in a user's library.
+// Folders hold their subfolders and playlists in Children.
+type LibraryItem struct {
+	Type     string     `json:"type"`
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	Subtitle string     `json:"subtitle,omitempty"` // playlist owner or album artist
+	ImageURL *string    `json:"image_url,omitempty"`
+	FolderID *int       `json:"folder_id,omitempty"`
+	Position *int       `json:"position,omitempty"`
+	Pinned   bool       `json:"pinned"`
+	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+	AddedAt  time.Time  `json:"added_at"`
+
+	Playlist *PlaylistResponse `json:"playlist,omitempty"`
+	Children []LibraryItem     `json:"children,omitempty"`
+}
+
+// LibraryResponse is a user's library as a tree of folders and items
+type LibraryResponse struct {
+	Items []LibraryItem `json:"items"`
+	Sort  string        `json:"sort"`
+	Type  string        `json:"type,omitempty"`
+	Query string        `json:"q,omitempty"`
+}
+
+type CreateLibraryFolderRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id,omitempty"` // top level when omitted
+}
+
+type UpdateLibraryFolderRequest struct {
+	Name string `json:"name"`
+}
+
+// MoveLibraryItemRequest places a folder or playlist in a folder, or at the top level when
+// FolderID is null, at Position among the folder's items or at the end when Position is null
+type MoveLibraryItemRequest struct {
+	Type     string `json:"type"`
+	ID       int    `json:"id"`
+	FolderID *int   `json:"folder_id"`
+	Position *int   `json:"position"`
+}
+
+// PinLibraryItemRequest pins an item to the top of its folder, or unpins it
+type PinLibraryItemRequest struct {
+	Type   string `json:"type"`
+	ID     int    `json:"id"`
+	Pinned bool   `json:"pinned"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationNewRelease      = "new_release"
+	NotificationComment         = "comment"
+	NotificationCommentReply    = "comment_reply"
+	NotificationNewFollower     = "new_follower"
+	NotificationUploadProcessed = "upload_processed"
+	NotificationDataExportReady = "data_export_ready"
+	NotificationPlaylistInvite  = "playlist_invite"
+	NotificationImportCompleted = "import_completed"
+)
+
+// Notification is an in-app notification for a user
+type Notification struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Type       string     `json:"type"`
+	ActorID    *int       `json:"actor_id,omitempty"`
+	ActorName  *string    `json:"actor_name,omitempty"`
+	EntityType *string    `json:"entity_type,omitempty"`
+	EntityID   *int       `json:"entity_id,omitempty"`
+	Message    string     `json:"message"`
+	Read       bool       `json:"read"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewNotification describes a notification to deliver. EntityType and EntityID
+// point at the thing the notification is about (e.g. "track", 42).
+type NewNotification struct {
+	UserID     int
+	Type       string
+	ActorID    *int
+	EntityType string
+	EntityID   *int
+	Message    string
+}
+
+// NotificationsResponse is a page of notifications with the user's unread count
+type NotificationsResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	Total         int            `json:"total"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+}
@@ -2,36 +2,113 @@ package models
 
 import "time"
 
+// Playlist privacy settings. Unlisted playlists are hidden like private ones but can be
+// read by anyone holding one of their share links.
+const (
+	PlaylistPrivacyPublic   = "public"
+	PlaylistPrivacyUnlisted = "unlisted"
+	PlaylistPrivacyPrivate  = "private"
+)
+
+// Playlist roles, from least to most access. Collaborators are viewers or editors;
+// the creator is always the owner.
+const (
+	PlaylistRoleViewer = "viewer"
+	PlaylistRoleEditor = "editor"
+	PlaylistRoleOwner  = "owner"
+)
+
 type Playlist struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
 	CreatorID int       `json:"creator_id"`
 	CoverURL  *string   `json:"cover_url,omitempty"`
-	Privacy   string    `json:"privacy"` // "public" or "private"
+	Privacy   string    `json:"privacy"` // "public", "unlisted" or "private"
+	Role      string    `json:"role,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// Rules of a smart playlist, nil for playlists with a fixed track list
+	Rules   *SmartPlaylistRules `json:"rules,omitempty"`
+	IsSmart bool                `json:"is_smart,omitempty"`
+
+	// Share status, only filled in when listing the user's own playlists
+	ShareLinkCount    int `json:"share_link_count,omitempty"`
+	CollaboratorCount int `json:"collaborator_count,omitempty"`
+
+	FollowerCount int `json:"follower_count"`
 }
 
 type PlaylistWithTracks struct {
-	ID        int               `json:"id"`
-	Title     string            `json:"title"`
-	CreatorID int               `json:"creator_id"`
-	CoverURL  *string           `json:"cover_url,omitempty"`
-	Privacy   string            `json:"privacy"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at,omitempty"`
-	Tracks    []TrackWithArtist `json:"tracks"`
+	ID        int                  `json:"id"`
+	Title     string               `json:"title"`
+	CreatorID int                  `json:"creator_id"`
+	CoverURL  *string              `json:"cover_url,omitempty"`
+	Privacy   string               `json:"privacy"`
+	Role      string               `json:"role,omitempty"` // the caller's role, if any
+	Version   int                  `json:"version"`        // incremented whenever the track list changes
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at,omitempty"`
+	Tracks    []PlaylistTrackEntry `json:"tracks"`
+
+	// Smart playlists list the tracks matching their rules as of the last refresh
+	Rules            *SmartPlaylistRules `json:"rules,omitempty"`
+	RulesRefreshedAt *time.Time          `json:"rules_refreshed_at,omitempty"`
+
+	FollowerCount int             `json:"follower_count"`
+	Following     bool            `json:"following"`             // whether the caller follows the playlist
+	ForkedFrom    *PlaylistSource `json:"forked_from,omitempty"` // the playlist this one was copied from, while it can be seen
+}
+
+// PlaylistSource attributes a forked playlist to the playlist it was copied from
+type PlaylistSource struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	CreatorID   int    `json:"creator_id"`
+	CreatorName string `json:"creator_name"`
+}
+
+// PlaylistTrackEntry is a track in a playlist together with who added it. A track may
+// appear more than once, so entries are identified by EntryID rather than the track ID.
+type PlaylistTrackEntry struct {
+	TrackWithArtist
+	EntryID     int        `json:"entry_id"`
+	Position    int        `json:"position"`
+	AddedBy     *int       `json:"added_by,omitempty"`
+	AddedByName *string    `json:"added_by_name,omitempty"`
+	AddedAt     *time.Time `json:"added_at,omitempty"`
 }
 
 type PlaylistTrack struct {
-	ID         int       `json:"id"`
-	PlaylistID int       `json:"playlist_id"`
-	TrackID    int       `json:"track_id"`
-	AddedAt    time.Time `json:"added_at"`
+	ID              int       `json:"id"`
+	PlaylistID      int       `json:"playlist_id"`
+	TrackID         int       `json:"track_id"`
+	Position        int       `json:"position"`
+	AddedBy         *int      `json:"added_by,omitempty"`
+	AddedAt         time.Time `json:"added_at"`
+	PlaylistVersion int       `json:"playlist_version"`
 }
 
 type CreatePlaylistRequest struct {
-	Title   string `json:"title" binding:"required"`
+	Title   string              `json:"title" binding:"required"`
+	Privacy string              `json:"privacy"`
+	Rules   *SmartPlaylistRules `json:"rules,omitempty"` // creates a smart playlist
+}
+
+// PlaylistCoverState is what the cover generator needs to know about a playlist
+type PlaylistCoverState struct {
+	PlaylistID int
+	Version    int
+	CoverURL   *string
+	Generated  bool     // CoverURL is a generated mosaic rather than an upload
+	Sources    []string // covers the generated cover was composed from
+	Leading    []string // covers of the playlist's first distinct albums or tracks, in order
+}
+
+// ForkPlaylistRequest names and sets the privacy of a playlist's copy. The source's title
+// and the default privacy are used when omitted.
+type ForkPlaylistRequest struct {
+	Title   string `json:"title"`
 	Privacy string `json:"privacy"`
 }
 
@@ -41,7 +118,18 @@ type UpdatePlaylistRequest struct {
 }
 
 type AddTrackToPlaylistRequest struct {
-	TrackID int `json:"track_id" binding:"required"`
+	TrackID  int  `json:"track_id" binding:"required"`
+	Position *int `json:"position,omitempty"` // zero-based index to insert at; appended when omitted
+	Version  *int `json:"version,omitempty"`  // rejects the insert if the playlist changed since this version
+}
+
+// ReorderPlaylistRequest either moves one entry to a new position or replaces the
+// whole order. Version must match the playlist's current version.
+type ReorderPlaylistRequest struct {
+	Version    *int  `json:"version" binding:"required"`
+	EntryID    *int  `json:"entry_id,omitempty"`
+	ToPosition *int  `json:"to_position,omitempty"`
+	Order      []int `json:"order,omitempty"` // every entry ID in the new order
 }
 
 type PlaylistResponse struct {
@@ -50,6 +138,152 @@ type PlaylistResponse struct {
 	CreatorID int       `json:"creator_id"`
 	CoverURL  *string   `json:"cover_url,omitempty"`
 	Privacy   string    `json:"privacy"`
+	Role      string    `json:"role,omitempty"`
+	IsSmart   bool      `json:"is_smart"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	FollowerCount int  `json:"follower_count"`
+	Following     bool `json:"following,omitempty"` // in the caller's library because they follow it
+}
+
+// PlaylistWithShareStatus is one of the user's playlists with how widely it is shared
+type PlaylistWithShareStatus struct {
+	PlaylistResponse
+	ShareLinkCount    int  `json:"share_link_count"`
+	CollaboratorCount int  `json:"collaborator_count"`
+	IsShared          bool `json:"is_shared"`
+}
+
+// PlaylistShareLink is a revocable secret link granting read access to a playlist
+type PlaylistShareLink struct {
+	ID         int       `json:"id"`
+	PlaylistID int       `json:"playlist_id"`
+	Token      string    `json:"token"`
+	Path       string    `json:"path"`
+	CreatedBy  *int      `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PlaylistCollaborator is a user invited to a playlist. The invite is pending until accepted.
+type PlaylistCollaborator struct {
+	PlaylistID int        `json:"playlist_id"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	AvatarURL  *string    `json:"avatar_url,omitempty"`
+	Role       string     `json:"role"`
+	InvitedBy  *int       `json:"invited_by,omitempty"`
+	Accepted   bool       `json:"accepted"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// PlaylistInvite is a pending invitation for the current user to collaborate on a playlist
+type PlaylistInvite struct {
+	PlaylistID    int       `json:"playlist_id"`
+	PlaylistTitle string    `json:"playlist_title"`
+	Role          string    `json:"role"`
+	InvitedBy     *int      `json:"invited_by,omitempty"`
+	InvitedByName *string   `json:"invited_by_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type InviteCollaboratorRequest struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"` // "viewer" or "editor"
+}
+
+type UpdateCollaboratorRequest struct {
+	Role string `json:"role"`
+}
+
+// Duplicate handling for bulk adds
+const (
+	DedupeNone     = "none"     // add every track, even if already in the playlist
+	DedupeExisting = "existing" // skip tracks already in the playlist
+	DedupeAll      = "all"      // also skip tracks repeated within the request
+)
+
+// Per-item outcomes of bulk playlist operations
+const (
+	BulkStatusAdded     = "added"
+	BulkStatusRemoved   = "removed"
+	BulkStatusDuplicate = "duplicate"
+	BulkStatusNotFound  = "not_found"
+)
+
+// BulkAddTracksRequest adds many tracks in one go. Exactly one of TrackIDs, AlbumID
+// and SourcePlaylistID selects the tracks to add.
+type BulkAddTracksRequest struct {
+	TrackIDs         []int  `json:"track_ids,omitempty"`
+	AlbumID          *int   `json:"album_id,omitempty"`
+	SourcePlaylistID *int   `json:"source_playlist_id,omitempty"`
+	Position         *int   `json:"position,omitempty"` // zero-based index to insert at; appended when omitted
+	Version          *int   `json:"version,omitempty"`
+	Dedupe           string `json:"dedupe,omitempty"` // "none", "existing" (default) or "all"
+}
+
+// BulkRemoveTracksRequest removes single entries by entry ID and/or every entry of the given tracks
+type BulkRemoveTracksRequest struct {
+	EntryIDs []int `json:"entry_ids,omitempty"`
+	TrackIDs []int `json:"track_ids,omitempty"`
+	Version  *int  `json:"version,omitempty"`
+}
+
+// BulkTrackResult is the outcome for one requested item
+type BulkTrackResult struct {
+	TrackID  int    `json:"track_id,omitempty"`
+	EntryID  *int   `json:"entry_id,omitempty"`
+	Position *int   `json:"position,omitempty"`
+	Status   string `json:"status"`
+}
+
+type BulkTracksResponse struct {
+	PlaylistID int               `json:"playlist_id"`
+	Version    int               `json:"version"`
+	Succeeded  int               `json:"succeeded"`
+	Skipped    int               `json:"skipped"`
+	Results    []BulkTrackResult `json:"results"`
+}
+
+// Outcomes of matching an imported playlist entry against the catalog
+const (
+	MatchStatusMatched   = "matched"
+	MatchStatusAmbiguous = "ambiguous"
+	MatchStatusMissing   = "missing"
+)
+
+// TrackMatchCandidate is a catalog track that may correspond to an imported entry
+type TrackMatchCandidate struct {
+	TrackID    int     `json:"track_id"`
+	Title      string  `json:"title"`
+	ArtistName string  `json:"artist_name"`
+	Duration   int     `json:"duration,omitempty"`
+	Score      float64 `json:"score"`
+}
+
+// PlaylistImportItem reports how one entry of an imported playlist was matched
+type PlaylistImportItem struct {
+	Index      int                   `json:"index"`
+	Title      string                `json:"title"`
+	Artist     string                `json:"artist,omitempty"`
+	Album      string                `json:"album,omitempty"`
+	Duration   int                   `json:"duration,omitempty"`
+	Location   string                `json:"location,omitempty"`
+	Status     string                `json:"status"` // "matched", "ambiguous" or "missing"
+	TrackID    *int                  `json:"track_id,omitempty"`
+	Score      float64               `json:"score,omitempty"`
+	Candidates []TrackMatchCandidate `json:"candidates,omitempty"` // best guesses for ambiguous entries
+}
+
+// PlaylistImportResponse is the playlist created from an import and the per-entry report.
+// Only matched entries are added to the playlist.
+type PlaylistImportResponse struct {
+	Playlist  PlaylistResponse     `json:"playlist"`
+	Format    string               `json:"format"`
+	Total     int                  `json:"total"`
+	Matched   int                  `json:"matched"`
+	Ambiguous int                  `json:"ambiguous"`
+	Missing   int                  `json:"missing"`
+	Items     []PlaylistImportItem `json:"items"`
 }
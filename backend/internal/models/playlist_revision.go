@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Playlist revision actions
+const (
+	RevisionCreated       = "created"
+	RevisionUpdated       = "updated"
+	RevisionCoverChanged  = "cover_changed"
+	RevisionTracksAdded   = "tracks_added"
+	RevisionTracksRemoved = "tracks_removed"
+	RevisionReordered     = "reordered"
+	RevisionRulesChanged  = "rules_changed"
+	RevisionRulesRemoved  = "rules_removed"
+	RevisionRefreshed     = "refreshed"
+	RevisionRestored      = "restored"
+	RevisionDeleted       = "deleted"
+	RevisionRecovered     = "recovered"
+)
+
+// PlaylistRevision records one change to a playlist and the playlist's state right after it
+type PlaylistRevision struct {
+	ID         int               `json:"id"`
+	PlaylistID int               `json:"playlist_id"`
+	Version    int               `json:"version"` // the playlist's track list version after the change
+	Action     string            `json:"action"`
+	Summary    string            `json:"summary"`
+	ActorID    *int              `json:"actor_id,omitempty"` // empty for changes made by the system, such as smart playlist refreshes
+	ActorName  *string           `json:"actor_name,omitempty"`
+	TrackCount int               `json:"track_count"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Snapshot   *PlaylistSnapshot `json:"snapshot,omitempty"`
+}
+
+// PlaylistSnapshot is the state of a playlist as of a revision
+type PlaylistSnapshot struct {
+	Title          string                  `json:"title"`
+	Privacy        string                  `json:"privacy"`
+	CoverURL       *string                 `json:"cover_url,omitempty"`
+	CoverGenerated bool                    `json:"cover_generated,omitempty"` // a mosaic of the tracks' covers rather than an upload
+	Rules          *SmartPlaylistRules     `json:"rules,omitempty"`
+	Entries        []PlaylistSnapshotEntry `json:"entries"`
+}
+
+type PlaylistSnapshotEntry struct {
+	TrackID int       `json:"track_id"`
+	AddedBy *int      `json:"added_by,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// PlaylistHistoryResponse is a page of a playlist's revisions, newest first
+type PlaylistHistoryResponse struct {
+	Revisions []PlaylistRevision `json:"revisions"`
+	Total     int                `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+// DeletedPlaylist is a playlist in the trash, recoverable until PurgeAt
+type DeletedPlaylist struct {
+	PlaylistResponse
+	TrackCount int       `json:"track_count"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
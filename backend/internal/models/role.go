@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Built-in role names
+const (
+	RoleListener     = "listener"
+	RoleArtist       = "artist"
+	RoleModerator    = "moderator"
+	RoleLabelManager = "label_manager"
+	RoleAdmin        = "admin"
+)
+
+// Built-in permission names
+const (
+	PermTracksUpload     = "tracks.upload"
+	PermTracksPublish    = "tracks.publish"
+	PermTracksManageAll  = "tracks.manage_all"
+	PermTracksTakedown   = "tracks.takedown"
+	PermAlbumsManage     = "albums.manage"
+	PermTagsManage       = "tags.manage"
+	PermGenresManage     = "genres.manage"
+	PermCommentsModerate = "comments.moderate"
+	PermRolesManage      = "roles.manage"
+	PermAdminDashboard   = "admin.dashboard"
+)
+
+type Permission struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+// Role is a named set of permissions that can be assigned to users
+type Role struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserRole is a role assignment for a user
+type UserRole struct {
+	Role      string    `json:"role"`
+	GrantedBy *int      `json:"granted_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserRolesResponse lists a user's roles and the permissions they grant
+type UserRolesResponse struct {
+	UserID      int        `json:"user_id"`
+	Roles       []UserRole `json:"roles"`
+	Permissions []string   `json:"permissions"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type GrantPermissionRequest struct {
+	Permission string `json:"permission"`
+}
+
+// TakedownTrackRequest is the request body for taking down a track
+type TakedownTrackRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,130 @@
+package models
+
+import "time"
+
+// Entity types of the unified search
+const (
+	SearchTypeTrack    = "track"
+	SearchTypeAlbum    = "album"
+	SearchTypeArtist   = "artist"
+	SearchTypePlaylist = "playlist"
+	SearchTypeUser     = "user"
+)
+
+// SearchTypes lists every searchable type, in the order results are presented
+var SearchTypes = []string{SearchTypeTrack, SearchTypeArtist, SearchTypeAlbum, SearchTypePlaylist, SearchTypeUser}
+
+// SearchFilter narrows a search. Genre only applies to tracks and Year to tracks (by upload
+// year) and albums (by release year); setting either leaves out the types without it.
+type SearchFilter struct {
+	Query string
+	Genre string
+	Year  int
+}
+
+// SearchAllResponse holds the results of every requested type, paged alike, with the single
+// best match across types and facet counts for narrowing the search
+type SearchAllResponse struct {
+	Query     string                 `json:"q"`
+	Types     []string               `json:"types"`
+	Limit     int                    `json:"limit"`
+	Offset    int                    `json:"offset"`
+	BestMatch *SearchBestMatch       `json:"best_match,omitempty"` // only on the first page
+	Tracks    *TrackSearchResults    `json:"tracks,omitempty"`
+	Artists   *ArtistSearchResults   `json:"artists,omitempty"`
+	Albums    *AlbumSearchResults    `json:"albums,omitempty"`
+	Playlists *PlaylistSearchResults `json:"playlists,omitempty"`
+	Users     *UserSearchResults     `json:"users,omitempty"`
+	Facets    SearchFacets           `json:"facets"`
+}
+
+// SearchBestMatch is the highest ranked result of any type. Item has the same shape as the
+// results of its type.
+type SearchBestMatch struct {
+	Type string      `json:"type"`
+	Item interface{} `json:"item"`
+}
+
+type TrackSearchResults struct {
+	Items []TrackWithArtist `json:"items"`
+	Total int               `json:"total"`
+}
+
+type ArtistSearchResults struct {
+	Items []ArtistWithStats `json:"items"`
+	Total int               `json:"total"`
+}
+
+type AlbumSearchResults struct {
+	Items []AlbumWithTracks `json:"items"`
+	Total int               `json:"total"`
+}
+
+type PlaylistSearchResults struct {
+	Items []PlaylistSearchResult `json:"items"`
+	Total int                    `json:"total"`
+}
+
+type UserSearchResults struct {
+	Items []UserSearchResult `json:"items"`
+	Total int                `json:"total"`
+}
+
+// PlaylistSearchResult is a public playlist found by search
+type PlaylistSearchResult struct {
+	PlaylistResponse
+	CreatorName string            `json:"creator_name"`
+	TrackCount  int               `json:"track_count"`
+	Highlights  map[string]string `json:"highlights,omitempty"`
+}
+
+// UserSearchResult is a listener profile found by search. Users who publish music are
+// found as artists instead.
+type UserSearchResult struct {
+	ID            int               `json:"id"`
+	Username      string            `json:"username"`
+	AvatarURL     *string           `json:"avatar_url,omitempty"`
+	FollowerCount int               `json:"follower_count"`
+	Highlights    map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchFacets count the matches per type, genre and year. Each facet is counted with the
+// other filters applied but not its own, so the counts show what selecting a value yields.
+type SearchFacets struct {
+	Types  []SearchFacet `json:"types"`
+	Genres []SearchFacet `json:"genres"`
+	Years  []SearchFacet `json:"years"`
+}
+
+type SearchFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchSuggestion is a track, artist or album offered while the user types
+type SearchSuggestion struct {
+	Type     string  `json:"type"` // SearchTypeTrack, SearchTypeArtist or SearchTypeAlbum
+	ID       int     `json:"id"`
+	Text     string  `json:"text"`
+	Subtitle string  `json:"subtitle,omitempty"` // the artist of a track or album
+	ImageURL *string `json:"image_url,omitempty"`
+	Listens  int     `json:"-"` // popularity, for ranking
+}
+
+// SearchSuggestResponse holds the user's matching recent searches and catalog suggestions
+type SearchSuggestResponse struct {
+	Query       string             `json:"q"`
+	Recent      []RecentSearch     `json:"recent"`
+	Suggestions []SearchSuggestion `json:"suggestions"`
+}
+
+// RecentSearch is a search the user ran, kept so it can be offered again
+type RecentSearch struct {
+	ID         int       `json:"id"`
+	Query      string    `json:"query"`
+	SearchedAt time.Time `json:"searched_at"`
+}
+
+type AddRecentSearchRequest struct {
+	Query string `json:"query"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "encoding/json"
+
+// Smart playlist rule matching modes
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// SmartPlaylistRules define a playlist by a query instead of a fixed track list, e.g.
+//
+//	{"match": "all",
+//	 "conditions": [{"field": "genre", "op": "eq", "value": "Rock"},
+//	                {"field": "liked", "op": "eq", "value": true},
+//	                {"field": "last_played", "op": "in_last_days", "value": 30}],
+//	 "sort": {"field": "play_count", "direction": "desc"},
+//	 "limit": 50}
+//
+// Liked and played conditions refer to the playlist owner.
+type SmartPlaylistRules struct {
+	Match      string          `json:"match,omitempty"` // "all" (default) or "any"
+	Conditions []RuleCondition `json:"conditions"`
+	Sort       *RuleSort       `json:"sort,omitempty"`
+	Limit      int             `json:"limit,omitempty"`
+}
+
+// RuleCondition compares a track field with a value, or groups nested conditions when
+// Conditions is set
+type RuleCondition struct {
+	Field string          `json:"field,omitempty"`
+	Op    string          `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+
+	Match      string          `json:"match,omitempty"`
+	Conditions []RuleCondition `json:"conditions,omitempty"`
+}
+
+type RuleSort struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction,omitempty"` // "asc" or "desc" (default)
+}
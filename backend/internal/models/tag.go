@@ -0,0 +1,24 @@
+package models
+
+// Tag is a label from the curated tag taxonomy. TrackCount counts published tracks.
+type Tag struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	TrackCount int    `json:"track_count"`
+}
+
+// TagRequest creates or renames a tag
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+// SetTrackTagsRequest replaces the tags of a track
+type SetTrackTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TrackTagsResponse lists the tags of a track
+type TrackTagsResponse struct {
+	TrackID int      `json:"track_id"`
+	Tags    []string `json:"tags"`
+}
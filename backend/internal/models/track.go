@@ -10,6 +10,7 @@ type Track struct {
 	Duration       int       `json:"duration,omitempty"`
 	CoverImageURL  *string   `json:"cover_image_url,omitempty"`
 	Genre          *string   `json:"genre,omitempty"`
+	GenreID        *int      `json:"genre_id,omitempty"`
 	Lyrics         *string   `json:"lyrics,omitempty"`
 	QualityBitrate *int      `json:"quality_bitrate,omitempty"`
 	Status         string    `json:"status"`
@@ -34,6 +35,11 @@ type TrackWithArtist struct {
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	IsFavorited    bool      `json:"is_favorited,omitempty"`
+	CommentCount   int       `json:"comment_count"`
+	Tags           []string  `json:"tags,omitempty"`
+
+	// Search results only: matched fields as HTML with the matches wrapped in <mark>
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
 
 type CreateTrackRequest struct {
@@ -43,3 +49,25 @@ type CreateTrackRequest struct {
 	CoverImageURL string `json:"cover_image_url,omitempty"`
 	Genre         string `json:"genre,omitempty"`
 }
+
+// TrackLyrics are a track's lyrics. Synced lyrics have a time for every line; plain lyrics
+// only have their text.
+type TrackLyrics struct {
+	TrackID   int         `json:"track_id"`
+	Synced    bool        `json:"synced"`
+	Text      string      `json:"text"`
+	Lines     []LyricLine `json:"lines,omitempty"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// LyricLine is a line of synced lyrics and when it is sung, in milliseconds from the start
+// of the track. Lines without text mark instrumental breaks.
+type LyricLine struct {
+	TimeMs int    `json:"time_ms"`
+	Text   string `json:"text"`
+}
+
+type SetLyricsRequest struct {
+	Lyrics string `json:"lyrics"`
+	Format string `json:"format,omitempty"` // plain or lrc, detected when empty
+}
@@ -22,7 +22,6 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
-	Role     string `json:"role,omitempty"`
 }
 
 type LoginResponse struct {
@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// subscriberBuffer is how many notifications a slow stream may fall behind before
+// new ones are dropped for it. Dropped notifications are still listed by the API.
+const subscriberBuffer = 16
+
+// Hub delivers new notifications to the streams connected to this server instance.
+// Notifications are announced through Postgres LISTEN/NOTIFY, so a notification
+// created by any instance reaches subscribers on every instance.
+type Hub struct {
+	Db          *sql.DB
+	databaseURL string
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan models.Notification]struct{}
+}
+
+func NewHub(db *sql.DB, databaseURL string) *Hub {
+	return &Hub{
+		Db:          db,
+		databaseURL: databaseURL,
+		subscribers: make(map[int]map[chan models.Notification]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving a user's new notifications and a function
+// that must be called to stop receiving them
+func (h *Hub) Subscribe(userID int) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan models.Notification]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[userID][ch]; !ok {
+			return
+		}
+		delete(h.subscribers[userID], ch)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+// Run listens for notification events until ctx is cancelled. The listener
+// reconnects on its own if the database connection drops.
+func (h *Hub) Run(ctx context.Context) error {
+	listener := pq.NewListener(h.databaseURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("Notification listener error", "event", event, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(repository.NotificationChannel); err != nil {
+		return err
+	}
+	slog.Info("Listening for notifications", "channel", repository.NotificationChannel)
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established;
+			// clients catch up on missed events with Last-Event-ID
+			if n != nil {
+				h.dispatch(n.Extra)
+			}
+		case <-ping.C:
+			go func() {
+				if err := listener.Ping(); err != nil {
+					slog.Warn("Notification listener ping failed", "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// dispatch delivers a batch of notification events to the subscribers of this instance,
+// loading only the notifications that someone here is waiting for
+func (h *Hub) dispatch(payload string) {
+	var events []repository.NotificationEvent
+	if err := json.Unmarshal([]byte(payload), &events); err != nil {
+		slog.Warn("Invalid notification event", "payload", payload, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	wanted := []repository.NotificationEvent{}
+	for _, event := range events {
+		if _, subscribed := h.subscribers[event.UserID]; subscribed {
+			wanted = append(wanted, event)
+		}
+	}
+	h.mu.Unlock()
+
+	repo := repository.NewRepository(h.Db)
+	for _, event := range wanted {
+		notification, err := repo.GetNotificationByID(event.ID)
+		if err != nil {
+			slog.Error("Failed to load notification", "error", err, "notification_id", event.ID)
+			continue
+		}
+
+		h.mu.Lock()
+		for ch := range h.subscribers[event.UserID] {
+			select {
+			case ch <- *notification:
+			default:
+				slog.Warn("Dropping notification for slow subscriber", "user_id", event.UserID, "notification_id", event.ID)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
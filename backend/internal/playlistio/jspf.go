@@ -0,0 +1,74 @@
+package playlistio
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type jspfDocument struct {
+	Playlist jspfPlaylist `json:"playlist"`
+}
+
+type jspfPlaylist struct {
+	Title   string      `json:"title,omitempty"`
+	Creator string      `json:"creator,omitempty"`
+	Tracks  []jspfTrack `json:"track"`
+}
+
+type jspfTrack struct {
+	Locations   []string `json:"location,omitempty"`
+	Identifiers []string `json:"identifier,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Creator     string   `json:"creator,omitempty"`
+	Album       string   `json:"album,omitempty"`
+	Duration    int      `json:"duration,omitempty"` // milliseconds
+	Image       string   `json:"image,omitempty"`
+}
+
+func writeJSPF(w io.Writer, p *Playlist) error {
+	doc := jspfDocument{Playlist: jspfPlaylist{Title: p.Title, Creator: p.Creator, Tracks: []jspfTrack{}}}
+	for _, t := range p.Tracks {
+		track := jspfTrack{Title: t.Title, Creator: t.Artist, Album: t.Album, Duration: t.Duration * 1000, Image: t.Image}
+		if t.Location != "" {
+			track.Locations = []string{t.Location}
+		}
+		if t.Identifier != "" {
+			track.Identifiers = []string{t.Identifier}
+		}
+		doc.Playlist.Tracks = append(doc.Playlist.Tracks, track)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func parseJSPF(r io.Reader) (*Playlist, error) {
+	var doc jspfDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	p := &Playlist{Title: strings.TrimSpace(doc.Playlist.Title), Creator: strings.TrimSpace(doc.Playlist.Creator)}
+	for _, t := range doc.Playlist.Tracks {
+		track := Track{
+			Title:    strings.TrimSpace(t.Title),
+			Artist:   strings.TrimSpace(t.Creator),
+			Album:    strings.TrimSpace(t.Album),
+			Duration: (t.Duration + 500) / 1000,
+			Image:    strings.TrimSpace(t.Image),
+		}
+		if len(t.Locations) > 0 {
+			track.Location = strings.TrimSpace(t.Locations[0])
+		}
+		if len(t.Identifiers) > 0 {
+			track.Identifier = strings.TrimSpace(t.Identifiers[0])
+		}
+		if track.Title == "" && track.Location != "" {
+			track.Artist, track.Title = titleFromLocation(track.Location)
+		}
+		p.Tracks = append(p.Tracks, track)
+	}
+	return p, nil
+}
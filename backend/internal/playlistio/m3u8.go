@@ -0,0 +1,102 @@
+package playlistio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+func writeM3U8(w io.Writer, p *Playlist) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	if p.Title != "" {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", oneLine(p.Title))
+	}
+	for _, t := range p.Tracks {
+		duration := t.Duration
+		if duration <= 0 {
+			duration = -1
+		}
+		info := oneLine(t.Title)
+		if t.Artist != "" {
+			info = oneLine(t.Artist) + " - " + info
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", duration, info)
+		if t.Album != "" {
+			fmt.Fprintf(bw, "#EXTALB:%s\n", oneLine(t.Album))
+		}
+		if t.Image != "" {
+			fmt.Fprintf(bw, "#EXTIMG:%s\n", oneLine(t.Image))
+		}
+		fmt.Fprintln(bw, oneLine(t.Location))
+	}
+	return bw.Flush()
+}
+
+func parseM3U8(r io.Reader) (*Playlist, error) {
+	p := &Playlist{}
+	var pending Track
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		switch {
+		case line == "" || line == "#EXTM3U":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			p.Title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<seconds>[ attributes],<artist> - <title>
+			spec, info, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if fields := strings.Fields(spec); len(fields) > 0 {
+				if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil && seconds > 0 {
+					pending.Duration = int(seconds + 0.5)
+				}
+			}
+			pending.Artist, pending.Title = splitArtistTitle(info)
+		case strings.HasPrefix(line, "#EXTALB:"):
+			pending.Album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+		case strings.HasPrefix(line, "#EXTART:"):
+			pending.Artist = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+		case strings.HasPrefix(line, "#EXTIMG:"):
+			pending.Image = strings.TrimSpace(strings.TrimPrefix(line, "#EXTIMG:"))
+		case strings.HasPrefix(line, "#"):
+			// Unknown directive or comment
+		default:
+			pending.Location = line
+			if pending.Title == "" {
+				pending.Artist, pending.Title = titleFromLocation(line)
+			}
+			p.Tracks = append(p.Tracks, pending)
+			pending = Track{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// titleFromLocation derives artist and title from a file name such as "Artist - Title.mp3"
+func titleFromLocation(location string) (string, string) {
+	name := location
+	if u, err := url.Parse(location); err == nil && u.Path != "" {
+		name = u.Path
+	}
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return splitArtistTitle(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
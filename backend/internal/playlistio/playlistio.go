@@ -0,0 +1,110 @@
+// Package playlistio reads and writes playlists in the common interchange formats:
+// M3U8 (extended M3U), XSPF (XML Shareable Playlist Format) and JSPF (its JSON form).
+package playlistio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// Supported formats
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSPF = "jspf"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported playlist format")
+
+// Track is one playlist entry. Fields that a format does not carry are left empty.
+type Track struct {
+	Location   string
+	Identifier string
+	Title      string
+	Artist     string
+	Album      string
+	Duration   int // seconds, 0 if unknown
+	Image      string
+}
+
+type Playlist struct {
+	Title   string
+	Creator string
+	Tracks  []Track
+}
+
+// ContentType returns the MIME type used when serving a playlist in format
+func ContentType(format string) string {
+	switch format {
+	case FormatM3U8:
+		return "audio/x-mpegurl; charset=utf-8"
+	case FormatXSPF:
+		return "application/xspf+xml; charset=utf-8"
+	case FormatJSPF:
+		return "application/jspf+json; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Write encodes a playlist in format
+func Write(w io.Writer, format string, p *Playlist) error {
+	switch format {
+	case FormatM3U8:
+		return writeM3U8(w, p)
+	case FormatXSPF:
+		return writeXSPF(w, p)
+	case FormatJSPF:
+		return writeJSPF(w, p)
+	}
+	return ErrUnsupportedFormat
+}
+
+// Parse decodes a playlist in format
+func Parse(r io.Reader, format string) (*Playlist, error) {
+	switch format {
+	case FormatM3U8:
+		return parseM3U8(r)
+	case FormatXSPF:
+		return parseXSPF(r)
+	case FormatJSPF:
+		return parseJSPF(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// DetectFormat guesses the format of an uploaded playlist from its file extension,
+// falling back to sniffing the content. It returns "" if the format is unknown.
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".m3u", ".m3u8":
+		return FormatM3U8
+	case ".xspf":
+		return FormatXSPF
+	case ".jspf":
+		return FormatJSPF
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("#EXTM3U")):
+		return FormatM3U8
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("xspf.org")):
+		return FormatXSPF
+	case bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte(`"playlist"`)):
+		return FormatJSPF
+	}
+	return ""
+}
+
+// splitArtistTitle splits the common "Artist - Title" form. Without a separator the
+// whole string is taken as the title.
+func splitArtistTitle(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if artist, title, ok := strings.Cut(s, " - "); ok {
+		return strings.TrimSpace(artist), strings.TrimSpace(title)
+	}
+	return "", s
+}
@@ -0,0 +1,82 @@
+package playlistio
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Version   string      `xml:"version,attr"`
+	Namespace string      `xml:"xmlns,attr,omitempty"`
+	Title     string      `xml:"title,omitempty"`
+	Creator   string      `xml:"creator,omitempty"`
+	Tracks    []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Locations   []string `xml:"location"`
+	Identifiers []string `xml:"identifier,omitempty"`
+	Title       string   `xml:"title,omitempty"`
+	Creator     string   `xml:"creator,omitempty"`
+	Album       string   `xml:"album,omitempty"`
+	Duration    int      `xml:"duration,omitempty"` // milliseconds
+	Image       string   `xml:"image,omitempty"`
+}
+
+func writeXSPF(w io.Writer, p *Playlist) error {
+	doc := xspfPlaylist{Version: "1", Namespace: xspfNamespace, Title: p.Title, Creator: p.Creator}
+	for _, t := range p.Tracks {
+		track := xspfTrack{Title: t.Title, Creator: t.Artist, Album: t.Album, Duration: t.Duration * 1000, Image: t.Image}
+		if t.Location != "" {
+			track.Locations = []string{t.Location}
+		}
+		if t.Identifier != "" {
+			track.Identifiers = []string{t.Identifier}
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func parseXSPF(r io.Reader) (*Playlist, error) {
+	var doc xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	p := &Playlist{Title: strings.TrimSpace(doc.Title), Creator: strings.TrimSpace(doc.Creator)}
+	for _, t := range doc.Tracks {
+		track := Track{
+			Title:    strings.TrimSpace(t.Title),
+			Artist:   strings.TrimSpace(t.Creator),
+			Album:    strings.TrimSpace(t.Album),
+			Duration: (t.Duration + 500) / 1000,
+			Image:    strings.TrimSpace(t.Image),
+		}
+		if len(t.Locations) > 0 {
+			track.Location = strings.TrimSpace(t.Locations[0])
+		}
+		if len(t.Identifiers) > 0 {
+			track.Identifier = strings.TrimSpace(t.Identifiers[0])
+		}
+		if track.Title == "" && track.Location != "" {
+			track.Artist, track.Title = titleFromLocation(track.Location)
+		}
+		p.Tracks = append(p.Tracks, track)
+	}
+	return p, nil
+}
@@ -0,0 +1,468 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const dataExportJobColumns = `id, user_id, status, object_name, error, created_at, completed_at, expires_at`
+
+func scanDataExportJob(row interface{ Scan(...interface{}) error }) (*models.DataExportJob, error) {
+	job := &models.DataExportJob{}
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Status, &job.ObjectName, &job.Error,
+		&job.CreatedAt, &job.CompletedAt, &job.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// CreateDataExportJob queues a new personal data export for a user. A user has at most one
+// export queued or running; if there is one already it is returned instead, and created is
+// false.
+func (r *Repository) CreateDataExportJob(userID int) (job *models.DataExportJob, created bool, err error) {
+	job, err = scanDataExportJob(r.Db.QueryRow(`
+		INSERT INTO data_export_jobs (user_id, status)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) WHERE status IN ('pending', 'processing') DO NOTHING
+		RETURNING `+dataExportJobColumns,
+		userID, models.ExportStatusPending,
+	))
+	if err == nil {
+		return job, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	job, err = r.GetLatestDataExportJob(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if job == nil || (job.Status != models.ExportStatusPending && job.Status != models.ExportStatusProcessing) {
+		// The active export finished in the meantime
+		return r.CreateDataExportJob(userID)
+	}
+	return job, false, nil
+}
+
+// GetLatestDataExportJob returns the most recent export job of a user, or nil if there is none
+func (r *Repository) GetLatestDataExportJob(userID int) (*models.DataExportJob, error) {
+	job, err := scanDataExportJob(r.Db.QueryRow(`
+		SELECT `+dataExportJobColumns+`
+		FROM data_export_jobs
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimDataExportJob marks the oldest pending export job as processing and returns it, or
+// nil if none is pending. Jobs being claimed by another instance are skipped.
+func (r *Repository) ClaimDataExportJob() (*models.DataExportJob, error) {
+	job, err := scanDataExportJob(r.Db.QueryRow(`
+		UPDATE data_export_jobs
+		SET status = $1, started_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM data_export_jobs
+			WHERE status = $2
+			ORDER BY created_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+dataExportJobColumns,
+		models.ExportStatusProcessing, models.ExportStatusPending,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// RequeueStaleDataExportJobs puts export jobs that have been processing for longer than
+// timeout, whose worker most likely stopped, back in the queue, and fails those already
+// tried maxAttempts times. It returns how many were requeued and failed.
+func (r *Repository) RequeueStaleDataExportJobs(timeout time.Duration, maxAttempts int) (int, int, error) {
+	result, err := r.Db.Exec(`
+		UPDATE data_export_jobs
+		SET status = $1, error = $2, completed_at = NOW()
+		WHERE status = $3 AND started_at < NOW() - make_interval(secs => $4) AND attempts >= $5
+	`, models.ExportStatusFailed, "the export did not finish", models.ExportStatusProcessing, timeout.Seconds(), maxAttempts)
+	if err != nil {
+		return 0, 0, err
+	}
+	failed, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err = r.Db.Exec(`
+		UPDATE data_export_jobs SET status = $1
+		WHERE status = $2 AND started_at < NOW() - make_interval(secs => $3)
+	`, models.ExportStatusPending, models.ExportStatusProcessing, timeout.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(requeued), int(failed), nil
+}
+
+// CompleteDataExportJob marks an export job as completed with the stored archive
+func (r *Repository) CompleteDataExportJob(jobID int, objectName string, expiresAt time.Time) error {
+	query := `
+		UPDATE data_export_jobs
+		SET status = $1, object_name = $2, completed_at = NOW(), expires_at = $3
+		WHERE id = $4
+	`
+	_, err := r.Db.Exec(query, models.ExportStatusCompleted, objectName, expiresAt, jobID)
+	return err
+}
+
+// FailDataExportJob marks an export job as failed
+func (r *Repository) FailDataExportJob(jobID int, message string) error {
+	query := `UPDATE data_export_jobs SET status = $1, error = $2, completed_at = NOW() WHERE id = $3`
+	_, err := r.Db.Exec(query, models.ExportStatusFailed, message, jobID)
+	return err
+}
+
+// ExpireDataExports marks completed exports past their expiry as expired and
+// returns the archive object names that should be removed from storage
+func (r *Repository) ExpireDataExports() ([]string, error) {
+	query := `
+		WITH expired AS (
+			SELECT id, object_name
+			FROM data_export_jobs
+			WHERE status = $2 AND expires_at < NOW()
+			FOR UPDATE
+		)
+		UPDATE data_export_jobs d
+		SET status = $1, object_name = NULL
+		FROM expired e
+		WHERE d.id = e.id
+		RETURNING e.object_name
+	`
+	rows, err := r.Db.Query(query, models.ExportStatusExpired, models.ExportStatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectNames := []string{}
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if name.Valid {
+			objectNames = append(objectNames, name.String)
+		}
+	}
+	return objectNames, rows.Err()
+}
+
+// GetUserDataExport collects all personal data stored about a user
+func (r *Repository) GetUserDataExport(userID int) (*models.UserDataExport, error) {
+	export := &models.UserDataExport{}
+
+	err := r.Db.QueryRow(
+		`SELECT id, email, username, avatar_url, COALESCE(role, 'user'), created_at, updated_at FROM users WHERE id = $1`,
+		userID,
+	).Scan(
+		&export.Profile.ID, &export.Profile.Email, &export.Profile.Username, &export.Profile.AvatarURL,
+		&export.Profile.Role, &export.Profile.CreatedAt, &export.Profile.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	if export.Roles, err = r.GetUserRoles(userID); err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	// Playlists with their track IDs
+	rows, err := r.Db.Query(`
+		SELECT p.id, p.title, COALESCE(p.privacy, 'public'), p.cover_url, p.created_at,
+		       COALESCE(ARRAY_AGG(pt.track_id ORDER BY pt.position, pt.id) FILTER (WHERE pt.track_id IS NOT NULL), '{}')
+		FROM playlists p
+		LEFT JOIN playlist_tracks pt ON p.id = pt.playlist_id
+		WHERE p.creator_id = $1
+		GROUP BY p.id
+		ORDER BY p.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlists: %w", err)
+	}
+	export.Playlists = []models.ExportPlaylist{}
+	for rows.Next() {
+		var p models.ExportPlaylist
+		var trackIDs pq.Int64Array
+		if err := rows.Scan(&p.ID, &p.Title, &p.Privacy, &p.CoverURL, &p.CreatedAt, &trackIDs); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan playlist: %w", err)
+		}
+		p.TrackIDs = trackIDs
+		export.Playlists = append(export.Playlists, p)
+	}
+	rows.Close()
+
+	// Likes
+	rows, err = r.Db.Query(`
+		SELECT l.track_id, t.title, l.created_at
+		FROM likes l
+		JOIN tracks t ON l.track_id = t.id
+		WHERE l.user_id = $1
+		ORDER BY l.created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get likes: %w", err)
+	}
+	export.Likes = []models.ExportLike{}
+	for rows.Next() {
+		var l models.ExportLike
+		if err := rows.Scan(&l.TrackID, &l.Title, &l.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan like: %w", err)
+		}
+		export.Likes = append(export.Likes, l)
+	}
+	rows.Close()
+
+	// Listens, including the device and IP address we recorded
+	rows, err = r.Db.Query(`
+		SELECT l.track_id, t.title, COALESCE(l.device, ''), COALESCE(HOST(l.ip), ''),
+		       COALESCE(l.listen_duration, 0), l.timestamp
+		FROM listens l
+		JOIN tracks t ON l.track_id = t.id
+		WHERE l.user_id = $1
+		ORDER BY l.timestamp ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listens: %w", err)
+	}
+	export.Listens = []models.ExportListen{}
+	for rows.Next() {
+		var l models.ExportListen
+		if err := rows.Scan(&l.TrackID, &l.Title, &l.Device, &l.IP, &l.ListenDuration, &l.Timestamp); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan listen: %w", err)
+		}
+		export.Listens = append(export.Listens, l)
+	}
+	rows.Close()
+
+	// Comments
+	rows, err = r.Db.Query(`
+		SELECT id, track_id, content, created_at
+		FROM comments
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	export.Comments = []models.ExportComment{}
+	for rows.Next() {
+		var c models.ExportComment
+		if err := rows.Scan(&c.ID, &c.TrackID, &c.Content, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		export.Comments = append(export.Comments, c)
+	}
+	rows.Close()
+
+	// Recent searches
+	if export.RecentSearches, err = r.GetRecentSearches(userID, "", recentSearchLimit); err != nil {
+		return nil, fmt.Errorf("failed to get recent searches: %w", err)
+	}
+
+	// Uploaded tracks
+	rows, err = r.Db.Query(`
+		SELECT id, title, file_url, cover_image_url, created_at
+		FROM tracks
+		WHERE artist_id = $1
+		ORDER BY created_at ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uploads: %w", err)
+	}
+	defer rows.Close()
+	export.Uploads = []models.ExportUpload{}
+	for rows.Next() {
+		var u models.ExportUpload
+		if err := rows.Scan(&u.TrackID, &u.Title, &u.FileURL, &u.CoverImageURL, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		export.Uploads = append(export.Uploads, u)
+	}
+
+	return export, rows.Err()
+}
+
+// ScheduleAccountDeletion schedules a user's account for deletion after the cooling-off period.
+// Requesting deletion again keeps the original schedule.
+func (r *Repository) ScheduleAccountDeletion(userID int, scheduledFor time.Time) (*models.AccountDeletion, error) {
+	query := `
+		INSERT INTO account_deletions (user_id, requested_at, scheduled_for)
+		VALUES ($1, NOW(), $2)
+		ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING user_id, requested_at, scheduled_for
+	`
+	deletion := &models.AccountDeletion{}
+	err := r.Db.QueryRow(query, userID, scheduledFor).Scan(&deletion.UserID, &deletion.RequestedAt, &deletion.ScheduledFor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+	return deletion, nil
+}
+
+// GetAccountDeletion returns the pending deletion of a user, or nil if there is none
+func (r *Repository) GetAccountDeletion(userID int) (*models.AccountDeletion, error) {
+	deletion := &models.AccountDeletion{}
+	err := r.Db.QueryRow(
+		`SELECT user_id, requested_at, scheduled_for FROM account_deletions WHERE user_id = $1`,
+		userID,
+	).Scan(&deletion.UserID, &deletion.RequestedAt, &deletion.ScheduledFor)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// CancelAccountDeletion cancels a pending account deletion
+func (r *Repository) CancelAccountDeletion(userID int) error {
+	result, err := r.Db.Exec(`DELETE FROM account_deletions WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDueAccountDeletions returns the IDs of users whose cooling-off period has ended
+func (r *Repository) GetDueAccountDeletions() ([]int, error) {
+	rows, err := r.Db.Query(`SELECT user_id FROM account_deletions WHERE scheduled_for <= NOW() ORDER BY scheduled_for ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// PurgeUser permanently deletes a user. Listens are kept for statistics but
+// anonymized, and uploaded tracks are deleted or transferred to transferToUserID
+// when it is non-zero. It returns the storage objects (URLs or object names)
+// that are no longer referenced and should be removed.
+func (r *Repository) PurgeUser(userID int, transferToUserID int) ([]string, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	urls := []string{}
+	collect := func(query string, args ...interface{}) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var url sql.NullString
+			if err := rows.Scan(&url); err != nil {
+				return err
+			}
+			if url.Valid && url.String != "" {
+				urls = append(urls, url.String)
+			}
+		}
+		return rows.Err()
+	}
+
+	if err := collect(`SELECT avatar_url FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to collect avatar: %w", err)
+	}
+	if err := collect(`SELECT cover_url FROM playlists WHERE creator_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to collect playlist covers: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE listens SET user_id = NULL, ip = NULL, device = NULL WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to anonymize listens: %w", err)
+	}
+
+	if transferToUserID != 0 {
+		if _, err := tx.Exec(`UPDATE tracks SET artist_id = $1, updated_at = NOW() WHERE artist_id = $2`, transferToUserID, userID); err != nil {
+			return nil, fmt.Errorf("failed to transfer tracks: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE albums SET artist_id = $1 WHERE artist_id = $2`, transferToUserID, userID); err != nil {
+			return nil, fmt.Errorf("failed to transfer albums: %w", err)
+		}
+	} else {
+		// Track covers may be borrowed from another artist's album, which must survive
+		err := collect(`
+			SELECT file_url FROM tracks WHERE artist_id = $1
+			UNION ALL
+			SELECT t.cover_image_url FROM tracks t
+			WHERE t.artist_id = $1
+			AND NOT EXISTS (SELECT 1 FROM albums a WHERE a.cover_url = t.cover_image_url AND a.artist_id <> $1)
+		`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect track files: %w", err)
+		}
+		if err := collect(`SELECT cover_url FROM albums WHERE artist_id = $1`, userID); err != nil {
+			return nil, fmt.Errorf("failed to collect album covers: %w", err)
+		}
+	}
+
+	if err := collect(`SELECT object_name FROM data_export_jobs WHERE user_id = $1 AND object_name IS NOT NULL`, userID); err != nil {
+		return nil, fmt.Errorf("failed to collect exports: %w", err)
+	}
+
+	// Everything else (playlists, likes, comments, roles, remaining tracks and albums) cascades
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
 )
 
 func (r *Repository) CreateAlbum(album *models.Album, trackIDs []int) error {
@@ -33,13 +36,13 @@ func (r *Repository) CreateAlbum(album *models.Album, trackIDs []int) error {
 	}
 
 	if len(trackIDs) > 0 {
-		stmt, err := tx.Prepare("INSERT INTO album_tracks (album_id, track_id) VALUES ($1, $2)")
+		stmt, err := tx.Prepare("INSERT INTO album_tracks (album_id, track_id, position) VALUES ($1, $2, $3)")
 		if err != nil {
 			return err
 		}
 
-		for _, trackID := range trackIDs {
-			if _, err := stmt.Exec(album.ID, trackID); err != nil {
+		for position, trackID := range trackIDs {
+			if _, err := stmt.Exec(album.ID, trackID, position); err != nil {
 				stmt.Close()
 				return err
 			}
@@ -80,12 +83,14 @@ func (r *Repository) GetAlbumByID(id int) (*models.AlbumWithTracks, error) {
 	tracksQuery := `
 		SELECT t.id, t.title, t.artist_id, u.username, t.file_url, t.duration, 
 		       t.cover_image_url, t.genre, t.lyrics, t.quality_bitrate, t.status, 
-		       t.created_at, t.updated_at
+		       t.created_at, t.updated_at,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		INNER JOIN album_tracks at ON t.id = at.track_id
 		LEFT JOIN users u ON t.artist_id = u.id
 		WHERE at.album_id = $1
-		ORDER BY at.id ASC
+		ORDER BY at.position ASC, at.id ASC
 	`
 
 	rows, err := r.Db.Query(tracksQuery, id)
@@ -102,6 +107,8 @@ func (r *Repository) GetAlbumByID(id int) (*models.AlbumWithTracks, error) {
 			&track.FileURL, &track.Duration, &track.CoverImageURL,
 			&track.Genre, &track.Lyrics, &track.QualityBitrate,
 			&track.Status, &track.CreatedAt, &track.UpdatedAt,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		); err != nil {
 			slog.Error("Failed to scan track", "error", err)
 			continue
@@ -144,13 +151,15 @@ func (r *Repository) GetAlbumByIDWithFavorites(id int, userID int) (*models.Albu
 		SELECT t.id, t.title, t.artist_id, u.username, t.file_url, t.duration, 
 		       t.cover_image_url, t.genre, t.lyrics, t.quality_bitrate, t.status, 
 		       t.created_at, t.updated_at,
-		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited
+		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		INNER JOIN album_tracks at ON t.id = at.track_id
 		LEFT JOIN users u ON t.artist_id = u.id
 		LEFT JOIN likes l ON t.id = l.track_id AND l.user_id = $2
 		WHERE at.album_id = $1
-		ORDER BY at.id ASC
+		ORDER BY at.position ASC, at.id ASC
 	`
 
 	rows, err := r.Db.Query(tracksQuery, id, userID)
@@ -168,6 +177,8 @@ func (r *Repository) GetAlbumByIDWithFavorites(id int, userID int) (*models.Albu
 			&track.Genre, &track.Lyrics, &track.QualityBitrate,
 			&track.Status, &track.CreatedAt, &track.UpdatedAt,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		); err != nil {
 			slog.Error("Failed to scan track", "error", err)
 			continue
@@ -179,15 +190,18 @@ func (r *Repository) GetAlbumByIDWithFavorites(id int, userID int) (*models.Albu
 	return album, nil
 }
 
-func (r *Repository) GetAllAlbums() ([]models.AlbumWithTracks, error) {
+// GetAllAlbums retrieves albums, filtered and sorted by opts and newest first otherwise
+func (r *Repository) GetAllAlbums(opts *listing.Options) ([]models.AlbumWithTracks, error) {
+	where, orderBy, args := opts.Compile(nil, "a.created_at DESC, a.id DESC")
 	query := `
 		SELECT a.id, a.title, a.artist_id, u.username, a.cover_url, a.release_date, a.created_at
 		FROM albums a
 		JOIN users u ON a.artist_id = u.id
-		ORDER BY a.created_at DESC
+		WHERE ` + where + `
+		ORDER BY ` + orderBy + `
 	`
 
-	rows, err := r.Db.Query(query)
+	rows, err := r.Db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get albums: %w", err)
 	}
@@ -208,38 +222,64 @@ func (r *Repository) GetAllAlbums() ([]models.AlbumWithTracks, error) {
 	return albums, nil
 }
 
+// SearchAlbums searches albums by title and artist name, best matches first, ignoring case
+// and accents and tolerating small typos
 func (r *Repository) SearchAlbums(query string) ([]models.AlbumWithTracks, error) {
+	albums, _, err := r.searchAlbums(models.SearchFilter{Query: query}, 0, 0)
+	return albums, err
+}
+
+// searchAlbums returns a page of matching albums with highlighted fragments, along with the
+// rank of the first one. A limit of 0 returns every match.
+func (r *Repository) searchAlbums(filter models.SearchFilter, limit, offset int) ([]models.AlbumWithTracks, float64, error) {
 	sqlQuery := `
-		SELECT a.id, a.title, a.artist_id, u.username, a.cover_url, a.release_date, a.created_at
+		SELECT a.id, a.title, a.artist_id, u.username, a.cover_url, a.release_date, a.created_at,
+		       ts_headline('search_simple', a.title, search_query($1), $6),
+		       ts_headline('search_simple', u.username, search_query($1), $6),
+		       ts_rank(a.search_vector, search_query($1))
+		         + 0.5 * word_similarity(search_normalize($1), search_normalize(a.title))
+		         + 0.3 * word_similarity(search_normalize($1), search_normalize(u.username)) AS rank
 		FROM albums a
 		JOIN users u ON a.artist_id = u.id
-		WHERE a.title ILIKE '%' || $1 || '%' OR u.username ILIKE '%' || $1 || '%'
-		ORDER BY a.created_at DESC
+		WHERE ` + albumSearchMatch + `
+		ORDER BY rank DESC, a.created_at DESC, a.id DESC
+		LIMIT NULLIF($4, 0) OFFSET $5
 	`
 
-	rows, err := r.Db.Query(sqlQuery, query)
+	rows, err := r.Db.Query(sqlQuery, filter.Query, filter.Genre, filter.Year, limit, offset, headlineOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search albums: %w", err)
+		return nil, 0, fmt.Errorf("failed to search albums: %w", err)
 	}
 	defer rows.Close()
 
 	albums := []models.AlbumWithTracks{}
+	var bestRank float64
 	for rows.Next() {
 		var album models.AlbumWithTracks
+		var title, artist *string
+		var rank float64
 		if err := rows.Scan(
 			&album.ID, &album.Title, &album.ArtistID, &album.ArtistName,
-			&album.CoverURL, &album.ReleaseDate, &album.CreatedAt,
+			&album.CoverURL, &album.ReleaseDate, &album.CreatedAt, &title, &artist, &rank,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan album: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan album: %w", err)
+		}
+		album.Highlights = searchHighlights(map[string]*string{"title": title, "artist_name": artist})
+		if len(albums) == 0 {
+			bestRank = rank
 		}
 		albums = append(albums, album)
 	}
 
-	return albums, nil
+	return albums, bestRank, rows.Err()
 }
 
+// AddTrackToAlbum appends a track to the end of an album
 func (r *Repository) AddTrackToAlbum(albumID, trackID int) error {
-	query := "INSERT INTO album_tracks (album_id, track_id) VALUES ($1, $2)"
+	query := `
+		INSERT INTO album_tracks (album_id, track_id, position)
+		SELECT $1, $2, COALESCE(MAX(position) + 1, 0) FROM album_tracks WHERE album_id = $1
+	`
 	_, err := r.Db.Exec(query, albumID, trackID)
 	return err
 }
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"database/sql"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
 )
 
 // GetArtistByID retrieves an artist by their user ID
@@ -23,6 +26,14 @@ func (r *Repository) GetArtistByID(artistID int) (*models.Artist, error) {
 	return &artist, nil
 }
 
+// IsArtist reports whether a user is an artist, that is has published tracks or albums.
+// It returns sql.ErrNoRows when the user does not exist.
+func (r *Repository) IsArtist(userID int) (bool, error) {
+	var isArtist bool
+	err := r.Db.QueryRow(`SELECT `+userPublishes+` FROM users u WHERE u.id = $1`, userID).Scan(&isArtist)
+	return isArtist, err
+}
+
 // GetArtistDetails retrieves detailed artist information including statistics
 func (r *Repository) GetArtistDetails(artistID int) (*models.ArtistDetails, error) {
 	query := `
@@ -35,7 +46,9 @@ func (r *Repository) GetArtistDetails(artistID int) (*models.ArtistDetails, erro
 			u.created_at,
 			COALESCE(track_count.total, 0) as total_tracks,
 			COALESCE(album_count.total, 0) as total_albums,
-			COALESCE(listen_count.total, 0) as total_listens
+			COALESCE(listen_count.total, 0) as total_listens,
+			(SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) as follower_count,
+			(SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id) as following_count
 		FROM users u
 		LEFT JOIN (
 			SELECT artist_id, COUNT(*) as total 
@@ -69,6 +82,8 @@ func (r *Repository) GetArtistDetails(artistID int) (*models.ArtistDetails, erro
 		&artist.TotalTracks,
 		&artist.TotalAlbums,
 		&artist.TotalListens,
+		&artist.FollowerCount,
+		&artist.FollowingCount,
 	)
 	if err != nil {
 		return nil, err
@@ -98,7 +113,9 @@ func (r *Repository) GetArtistTopTracks(artistID int, limit int, userID *int) ([
 			CASE 
 				WHEN $3::int IS NOT NULL AND likes.user_id IS NOT NULL THEN true 
 				ELSE false 
-			END as is_favorited
+			END as is_favorited,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+			ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		JOIN users u ON t.artist_id = u.id
 		LEFT JOIN (
@@ -146,6 +163,8 @@ func (r *Repository) GetArtistTopTracks(artistID int, limit int, userID *int) ([
 			&track.UpdatedAt,
 			&playCount,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		)
 		if err != nil {
 			return nil, err
@@ -160,15 +179,17 @@ func (r *Repository) GetArtistTopTracks(artistID int, limit int, userID *int) ([
 	return tracks, nil
 }
 
-// GetArtistAlbums retrieves all albums for an artist
-func (r *Repository) GetArtistAlbums(artistID int) ([]models.Album, error) {
+// GetArtistAlbums retrieves all albums for an artist, filtered and sorted by opts and
+// latest release first otherwise
+func (r *Repository) GetArtistAlbums(artistID int, opts *listing.Options) ([]models.Album, error) {
+	where, orderBy, args := opts.Compile([]interface{}{artistID}, "a.release_date DESC, a.created_at DESC")
 	query := `
-		SELECT id, title, artist_id, cover_url, release_date, created_at
-		FROM albums
-		WHERE artist_id = $1
-		ORDER BY release_date DESC, created_at DESC
+		SELECT a.id, a.title, a.artist_id, a.cover_url, a.release_date, a.created_at
+		FROM albums a
+		WHERE a.artist_id = $1 AND ` + where + `
+		ORDER BY ` + orderBy + `
 	`
-	rows, err := r.Db.Query(query, artistID)
+	rows, err := r.Db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -198,8 +219,10 @@ func (r *Repository) GetArtistAlbums(artistID int) ([]models.Album, error) {
 	return albums, nil
 }
 
-// GetArtistTracks retrieves all tracks for an artist
-func (r *Repository) GetArtistTracks(artistID int, userID *int) ([]models.TrackWithArtist, error) {
+// GetArtistTracks retrieves all tracks for an artist, filtered and sorted by opts and newest
+// first otherwise
+func (r *Repository) GetArtistTracks(artistID int, userID *int, opts *listing.Options) ([]models.TrackWithArtist, error) {
+	where, orderBy, args := opts.Compile([]interface{}{artistID, userID}, "t.created_at DESC, t.id DESC")
 	query := `
 		SELECT 
 			t.id,
@@ -218,23 +241,17 @@ func (r *Repository) GetArtistTracks(artistID int, userID *int) ([]models.TrackW
 			CASE 
 				WHEN $2::int IS NOT NULL AND likes.user_id IS NOT NULL THEN true 
 				ELSE false 
-			END as is_favorited
+			END as is_favorited,
+			(SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+			ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		JOIN users u ON t.artist_id = u.id
 		LEFT JOIN likes ON t.id = likes.track_id AND likes.user_id = $2
-		WHERE t.artist_id = $1 AND t.status = 'published'
-		ORDER BY t.created_at DESC
+		WHERE t.artist_id = $1 AND t.status = 'published' AND ` + where + `
+		ORDER BY ` + orderBy + `
 	`
 
-	var rows *sql.Rows
-	var err error
-
-	if userID != nil {
-		rows, err = r.Db.Query(query, artistID, *userID)
-	} else {
-		rows, err = r.Db.Query(query, artistID, nil)
-	}
-
+	rows, err := r.Db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +275,8 @@ func (r *Repository) GetArtistTracks(artistID int, userID *int) ([]models.TrackW
 			&track.CreatedAt,
 			&track.UpdatedAt,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		)
 		if err != nil {
 			return nil, err
@@ -272,47 +291,34 @@ func (r *Repository) GetArtistTracks(artistID int, userID *int) ([]models.TrackW
 	return tracks, nil
 }
 
-// SearchArtists searches for artists by username
-func (r *Repository) SearchArtists(query string, limit int) ([]models.ArtistWithStats, error) {
-	searchQuery := `
+// ListArtists retrieves a page of artists, users with published tracks or albums, with their
+// stats, filtered and sorted by opts and most listened first otherwise
+func (r *Repository) ListArtists(opts *listing.Options, limit, offset int) ([]models.ArtistWithStats, error) {
+	where, orderBy, args := opts.Compile([]interface{}{limit, offset}, "total_listens DESC, u.id")
+	query := `
 		SELECT 
 			u.id,
 			u.username,
 			u.avatar_url,
-			COALESCE(track_count.total, 0) as total_tracks,
-			COALESCE(album_count.total, 0) as total_albums,
-			COALESCE(listen_count.total, 0) as total_listens
+			(SELECT COUNT(*) FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published') as total_tracks,
+			(SELECT COUNT(*) FROM albums a WHERE a.artist_id = u.id) as total_albums,
+			(SELECT COUNT(*) FROM listens l JOIN tracks t ON l.track_id = t.id WHERE t.artist_id = u.id) as total_listens
 		FROM users u
-		LEFT JOIN (
-			SELECT artist_id, COUNT(*) as total 
-			FROM tracks 
-			WHERE status = 'published'
-			GROUP BY artist_id
-		) track_count ON u.id = track_count.artist_id
-		LEFT JOIN (
-			SELECT artist_id, COUNT(*) as total 
-			FROM albums 
-			GROUP BY artist_id
-		) album_count ON u.id = album_count.artist_id
-		LEFT JOIN (
-			SELECT t.artist_id, COUNT(*) as total 
-			FROM listens l
-			JOIN tracks t ON l.track_id = t.id
-			GROUP BY t.artist_id
-		) listen_count ON u.id = listen_count.artist_id
-		WHERE LOWER(u.username) LIKE LOWER($1)
-		AND (track_count.total > 0 OR album_count.total > 0)
-		ORDER BY total_listens DESC, total_tracks DESC
-		LIMIT $2
+		WHERE (
+			EXISTS (SELECT 1 FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published') OR
+			EXISTS (SELECT 1 FROM albums a WHERE a.artist_id = u.id)
+		) AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.Db.Query(searchQuery, "%"+query+"%", limit)
+	rows, err := r.Db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var artists []models.ArtistWithStats
+	artists := []models.ArtistWithStats{}
 	for rows.Next() {
 		var artist models.ArtistWithStats
 		err := rows.Scan(
@@ -329,9 +335,77 @@ func (r *Repository) SearchArtists(query string, limit int) ([]models.ArtistWith
 		artists = append(artists, artist)
 	}
 
+	return artists, rows.Err()
+}
+
+// SearchArtists searches artists by username, best matches first, ignoring case and accents
+// and tolerating small typos. Ties go to the most listened artists.
+func (r *Repository) SearchArtists(query string, limit int) ([]models.ArtistWithStats, error) {
+	artists, _, err := r.searchArtists(models.SearchFilter{Query: query}, limit, 0)
+	return artists, err
+}
+
+// searchArtists returns a page of matching artists with their stats and highlighted
+// username, along with the rank of the first one
+func (r *Repository) searchArtists(filter models.SearchFilter, limit, offset int) ([]models.ArtistWithStats, float64, error) {
+	searchQuery := `
+		WITH ranked AS (
+			SELECT u.id,
+			       ts_rank(to_tsvector('search_simple', u.username), search_query($1))
+			         + word_similarity(search_normalize($1), search_normalize(u.username)) AS rank
+			FROM users u
+			WHERE ` + artistSearchMatch + `
+		)
+		SELECT 
+			u.id,
+			u.username,
+			u.avatar_url,
+			(SELECT COUNT(*) FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published') as total_tracks,
+			(SELECT COUNT(*) FROM albums a WHERE a.artist_id = u.id) as total_albums,
+			(SELECT COUNT(*) FROM listens l JOIN tracks t ON l.track_id = t.id WHERE t.artist_id = u.id) as total_listens,
+			ts_headline('search_simple', u.username, search_query($1), $6),
+			ranked.rank
+		FROM ranked
+		JOIN users u ON u.id = ranked.id
+		ORDER BY ranked.rank DESC, total_listens DESC, total_tracks DESC, u.id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.Db.Query(searchQuery, filter.Query, filter.Genre, filter.Year, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var artists []models.ArtistWithStats
+	var bestRank float64
+	for rows.Next() {
+		var artist models.ArtistWithStats
+		var username *string
+		var rank float64
+		err := rows.Scan(
+			&artist.ID,
+			&artist.Username,
+			&artist.AvatarURL,
+			&artist.TotalTracks,
+			&artist.TotalAlbums,
+			&artist.TotalListens,
+			&username,
+			&rank,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		artist.Highlights = searchHighlights(map[string]*string{"username": username})
+		if artists == nil {
+			bestRank = rank
+		}
+		artists = append(artists, artist)
+	}
+
 	if artists == nil {
 		artists = []models.ArtistWithStats{}
 	}
 
-	return artists, nil
+	return artists, bestRank, rows.Err()
 }
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+const commentColumns = `
+	c.id, c.track_id, c.user_id, u.username, u.avatar_url, c.parent_id, c.content,
+	c.position_ms, c.resolved,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id),
+	c.created_at, c.updated_at
+`
+
+func scanComment(row interface{ Scan(...interface{}) error }) (models.Comment, error) {
+	var c models.Comment
+	err := row.Scan(
+		&c.ID, &c.TrackID, &c.UserID, &c.Username, &c.AvatarURL, &c.ParentID, &c.Content,
+		&c.PositionMs, &c.Resolved, &c.ReplyCount, &c.CreatedAt, &c.UpdatedAt,
+	)
+	return c, err
+}
+
+// GetTrackComments returns a page of top-level comments on a track, newest first,
+// each with its replies in chronological order, and the total number of top-level comments
+func (r *Repository) GetTrackComments(trackID, limit, offset int) ([]models.Comment, int, error) {
+	var total int
+	err := r.Db.QueryRow(`SELECT COUNT(*) FROM comments WHERE track_id = $1 AND parent_id IS NULL`, trackID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Db.Query(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.track_id = $1 AND c.parent_id IS NULL
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $2 OFFSET $3
+	`, trackID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	index := map[int]int{}
+	ids := []int64{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		index[c.ID] = len(comments)
+		ids = append(ids, int64(c.ID))
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return comments, total, nil
+	}
+
+	replyRows, err := r.Db.Query(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.parent_id = ANY($1)
+		ORDER BY c.created_at ASC, c.id ASC
+	`, pq.Int64Array(ids))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer replyRows.Close()
+
+	for replyRows.Next() {
+		reply, err := scanComment(replyRows)
+		if err != nil {
+			return nil, 0, err
+		}
+		parent := &comments[index[*reply.ParentID]]
+		parent.Replies = append(parent.Replies, reply)
+	}
+
+	return comments, total, replyRows.Err()
+}
+
+// GetCommentByID returns a single comment
+func (r *Repository) GetCommentByID(commentID int) (*models.Comment, error) {
+	row := r.Db.QueryRow(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = $1
+	`, commentID)
+	c, err := scanComment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateComment adds a comment to a track, optionally pinned to positionMs. Replies
+// to a reply are attached to its top-level comment so threads stay one level deep.
+// ErrCommentNotFound is returned if the parent does not exist on the same track.
+func (r *Repository) CreateComment(trackID, userID int, parentID *int, positionMs *int, content string) (*models.Comment, error) {
+	if parentID != nil {
+		var rootID int
+		err := r.Db.QueryRow(
+			`SELECT COALESCE(parent_id, id) FROM comments WHERE id = $1 AND track_id = $2`,
+			*parentID, trackID,
+		).Scan(&rootID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrCommentNotFound
+			}
+			return nil, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		parentID = &rootID
+	}
+
+	var id int
+	err := r.Db.QueryRow(`
+		INSERT INTO comments (track_id, user_id, parent_id, position_ms, content)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, trackID, userID, parentID, positionMs, content).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return r.GetCommentByID(id)
+}
+
+// UpdateComment replaces the content of a comment and marks it as edited
+func (r *Repository) UpdateComment(commentID int, content string) (*models.Comment, error) {
+	result, err := r.Db.Exec(`UPDATE comments SET content = $1, updated_at = NOW() WHERE id = $2`, content, commentID)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrCommentNotFound
+	}
+	return r.GetCommentByID(commentID)
+}
+
+// DeleteComment deletes a comment together with its replies
+func (r *Repository) DeleteComment(commentID int) error {
+	result, err := r.Db.Exec(`DELETE FROM comments WHERE id = $1`, commentID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// GetTimedComments returns all top-level comments on a track that are pinned to a
+// position, in timeline order
+func (r *Repository) GetTimedComments(trackID int) ([]models.Comment, error) {
+	rows, err := r.Db.Query(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.track_id = $1 AND c.parent_id IS NULL AND c.position_ms IS NOT NULL
+		ORDER BY c.position_ms ASC, c.created_at ASC
+	`, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// SetCommentResolved marks a top-level comment as resolved or unresolved
+func (r *Repository) SetCommentResolved(commentID int, resolved bool) (*models.Comment, error) {
+	result, err := r.Db.Exec(`
+		UPDATE comments
+		SET resolved = $1, resolved_at = CASE WHEN $1 THEN NOW() ELSE NULL END
+		WHERE id = $2 AND parent_id IS NULL
+	`, resolved, commentID)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrCommentNotFound
+	}
+	return r.GetCommentByID(commentID)
+}
+
+// GetArtistFeedback returns top-level comments left by other users on an artist's
+// tracks, newest first. trackID, resolved and timedOnly narrow the results.
+func (r *Repository) GetArtistFeedback(artistID int, trackID *int, resolved *bool, timedOnly bool, limit, offset int) ([]models.TrackFeedback, int, error) {
+	where := `t.artist_id = $1 AND c.user_id <> $1 AND c.parent_id IS NULL
+		AND ($2::int IS NULL OR c.track_id = $2)
+		AND ($3::boolean IS NULL OR c.resolved = $3)
+		AND (NOT $4 OR c.position_ms IS NOT NULL)`
+	args := []interface{}{artistID, trackID, resolved, timedOnly}
+
+	var total int
+	err := r.Db.QueryRow(`
+		SELECT COUNT(*)
+		FROM comments c
+		JOIN tracks t ON c.track_id = t.id
+		WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Db.Query(`
+		SELECT `+commentColumns+`, t.title
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		JOIN tracks t ON c.track_id = t.id
+		WHERE `+where+`
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $5 OFFSET $6
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	feedback := []models.TrackFeedback{}
+	for rows.Next() {
+		var f models.TrackFeedback
+		c := &f.Comment
+		if err := rows.Scan(
+			&c.ID, &c.TrackID, &c.UserID, &c.Username, &c.AvatarURL, &c.ParentID, &c.Content,
+			&c.PositionMs, &c.Resolved, &c.ReplyCount, &c.CreatedAt, &c.UpdatedAt,
+			&f.TrackTitle,
+		); err != nil {
+			return nil, 0, err
+		}
+		feedback = append(feedback, f)
+	}
+	return feedback, total, rows.Err()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"music-app/backend/internal/models"
+)
+
+// FollowUser makes followerID follow followeeID and reports whether the follow is new.
+// Following twice is a no-op.
+func (r *Repository) FollowUser(followerID, followeeID int) (bool, error) {
+	query := `
+		INSERT INTO follows (follower_id, followee_id)
+		VALUES ($1, $2)
+		ON CONFLICT (follower_id, followee_id) DO NOTHING
+	`
+	result, err := r.Db.Exec(query, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// UnfollowUser removes a follow. Unfollowing a user that is not followed is a no-op.
+func (r *Repository) UnfollowUser(followerID, followeeID int) error {
+	_, err := r.Db.Exec(`DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`, followerID, followeeID)
+	return err
+}
+
+// IsFollowing checks if followerID follows followeeID
+func (r *Repository) IsFollowing(followerID, followeeID int) (bool, error) {
+	var following bool
+	err := r.Db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)`,
+		followerID, followeeID,
+	).Scan(&following)
+	return following, err
+}
+
+// GetFollowers returns the users following userID, most recent first
+func (r *Repository) GetFollowers(userID, limit, offset int) ([]models.FollowUser, int, error) {
+	return r.getFollowList(`f.followee_id = $1`, `f.follower_id`, userID, limit, offset)
+}
+
+// GetFollowing returns the users followed by userID, most recent first
+func (r *Repository) GetFollowing(userID, limit, offset int) ([]models.FollowUser, int, error) {
+	return r.getFollowList(`f.follower_id = $1`, `f.followee_id`, userID, limit, offset)
+}
+
+func (r *Repository) getFollowList(where, joinColumn string, userID, limit, offset int) ([]models.FollowUser, int, error) {
+	var total int
+	if err := r.Db.QueryRow(`SELECT COUNT(*) FROM follows f WHERE `+where, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Db.Query(`
+		SELECT u.id, u.username, u.avatar_url, f.created_at
+		FROM follows f
+		JOIN users u ON u.id = `+joinColumn+`
+		WHERE `+where+`
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.FollowUser{}
+	for rows.Next() {
+		var u models.FollowUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.AvatarURL, &u.FollowedAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetReleaseFeed returns new albums and tracks from the artists a user follows,
+// newest first. Tracks that belong to an album are represented by the album.
+func (r *Repository) GetReleaseFeed(userID, limit, offset int) ([]models.Release, error) {
+	query := `
+		SELECT type, id, title, artist_id, artist_name, cover_url, released_at
+		FROM (
+			SELECT 'track' as type, t.id, t.title, t.artist_id, u.username as artist_name,
+			       t.cover_image_url as cover_url, t.created_at as released_at
+			FROM tracks t
+			JOIN follows f ON f.followee_id = t.artist_id AND f.follower_id = $1
+			JOIN users u ON t.artist_id = u.id
+			WHERE t.status = 'published'
+			AND NOT EXISTS (SELECT 1 FROM album_tracks at WHERE at.track_id = t.id)
+
+			UNION ALL
+
+			SELECT 'album' as type, a.id, a.title, a.artist_id, u.username as artist_name,
+			       a.cover_url, a.created_at as released_at
+			FROM albums a
+			JOIN follows f ON f.followee_id = a.artist_id AND f.follower_id = $1
+			JOIN users u ON a.artist_id = u.id
+		) releases
+		ORDER BY released_at DESC, type ASC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.Db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	releases := []models.Release{}
+	for rows.Next() {
+		var rel models.Release
+		if err := rows.Scan(&rel.Type, &rel.ID, &rel.Title, &rel.ArtistID, &rel.ArtistName, &rel.CoverURL, &rel.ReleasedAt); err != nil {
+			return nil, err
+		}
+		releases = append(releases, rel)
+	}
+	return releases, rows.Err()
+}
@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrGenreNotFound       = errors.New("genre not found")
+	ErrGenreParentNotFound = errors.New("parent genre not found")
+	ErrGenreExists         = errors.New("a genre or alias with this name already exists")
+	ErrGenreCycle          = errors.New("a genre cannot be placed under itself or one of its sub-genres")
+	ErrInvalidGenreName    = errors.New("genre names must contain letters or digits")
+	ErrGenreAliasNotFound  = errors.New("alias not found")
+	ErrGenreAliasIsName    = errors.New("a genre's own name cannot be removed from its aliases")
+)
+
+// genreColumns selects a genre aliased g with the aliases other than its own name and the
+// number of published tracks in it
+const genreColumns = `
+	g.id, g.name, g.slug, g.parent_id,
+	COALESCE((SELECT ARRAY_AGG(ga.alias ORDER BY ga.alias) FROM genre_aliases ga
+	          WHERE ga.genre_id = g.id AND ga.alias <> genre_key(g.name)), '{}'),
+	(SELECT COUNT(*) FROM tracks t WHERE t.genre_id = g.id AND t.status = 'published')
+`
+
+// genreSubtree lists the IDs of the genre $1 and all of its sub-genres
+const genreSubtree = `
+	WITH RECURSIVE subtree(id) AS (
+		SELECT $1::int
+		UNION
+		SELECT g.id FROM genres g JOIN subtree s ON g.parent_id = s.id
+	)
+	SELECT id FROM subtree
+`
+
+func scanGenre(row interface{ Scan(...interface{}) error }) (models.Genre, error) {
+	var genre models.Genre
+	var aliases pq.StringArray
+	err := row.Scan(&genre.ID, &genre.Name, &genre.Slug, &genre.ParentID, &aliases, &genre.TrackCount)
+	genre.Aliases = aliases
+	genre.TotalTrackCount = genre.TrackCount
+	genre.Children = []models.Genre{}
+	return genre, err
+}
+
+// GetGenreTree returns the top-level genres with their sub-genres nested, each level in
+// alphabetical order
+func (r *Repository) GetGenreTree() ([]models.Genre, error) {
+	rows, err := r.Db.Query(`SELECT ` + genreColumns + ` FROM genres g ORDER BY LOWER(g.name) ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buildGenreTree(genres), nil
+}
+
+// buildGenreTree nests genres under their parents and adds up their track counts
+func buildGenreTree(genres []models.Genre) []models.Genre {
+	known := make(map[int]bool, len(genres))
+	for _, genre := range genres {
+		known[genre.ID] = true
+	}
+
+	children := map[int][]int{}
+	roots := []int{}
+	for i, genre := range genres {
+		if genre.ParentID != nil && known[*genre.ParentID] {
+			children[*genre.ParentID] = append(children[*genre.ParentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var build func(i int) models.Genre
+	build = func(i int) models.Genre {
+		genre := genres[i]
+		for _, c := range children[genre.ID] {
+			child := build(c)
+			genre.TotalTrackCount += child.TotalTrackCount
+			genre.Children = append(genre.Children, child)
+		}
+		return genre
+	}
+
+	tree := make([]models.Genre, len(roots))
+	for i, root := range roots {
+		tree[i] = build(root)
+	}
+	return tree
+}
+
+// GetGenreBySlug returns a genre with its sub-genres and ancestors, or nil if there is none
+func (r *Repository) GetGenreBySlug(slug string) (*models.GenreDetail, error) {
+	tree, err := r.GetGenreTree()
+	if err != nil {
+		return nil, err
+	}
+
+	var find func(genres []models.Genre, ancestors []models.GenreRef) *models.GenreDetail
+	find = func(genres []models.Genre, ancestors []models.GenreRef) *models.GenreDetail {
+		for _, genre := range genres {
+			if genre.Slug == slug {
+				return &models.GenreDetail{Genre: genre, Ancestors: ancestors}
+			}
+			path := append(ancestors[:len(ancestors):len(ancestors)], models.GenreRef{ID: genre.ID, Name: genre.Name, Slug: genre.Slug})
+			if detail := find(genre.Children, path); detail != nil {
+				return detail
+			}
+		}
+		return nil
+	}
+	return find(tree, []models.GenreRef{}), nil
+}
+
+// getGenre returns a genre without its sub-genres, or nil if there is none
+func getGenre(q execQueryRower, genreID int) (*models.Genre, error) {
+	genre, err := scanGenre(q.QueryRow(`SELECT `+genreColumns+` FROM genres g WHERE g.id = $1`, genreID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &genre, nil
+}
+
+// findGenre returns the genre a name or alias stands for, or nil if there is none
+func findGenre(q execQueryRower, name string) (*models.Genre, error) {
+	genre, err := scanGenre(q.QueryRow(`
+		SELECT `+genreColumns+`
+		FROM genres g
+		WHERE g.id = (SELECT genre_id FROM genre_aliases WHERE alias = genre_key($1))
+		   OR g.slug = replace(genre_key($1), ' ', '-')
+		-- An alias wins over a slug left behind by a rename
+		ORDER BY g.slug = replace(genre_key($1), ' ', '-') ASC
+		LIMIT 1
+	`, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &genre, nil
+}
+
+// ResolveGenre returns the genre a name or alias stands for, ignoring case, accents,
+// punctuation and spacing, or nil if the taxonomy has none
+func (r *Repository) ResolveGenre(name string) (*models.Genre, error) {
+	return findGenre(r.Db, name)
+}
+
+// linkGenreTracks points tracks whose genre matched no genre when they were saved at
+// genreID, if their spelling is now one of its aliases
+func linkGenreTracks(q execQueryRower, genreID int) error {
+	_, err := q.Exec(`
+		UPDATE tracks t
+		SET genre_id = g.id, genre = g.name
+		FROM genre_aliases ga
+		JOIN genres g ON g.id = ga.genre_id
+		WHERE ga.genre_id = $1 AND t.genre_id IS NULL AND ga.alias = genre_key(t.genre)
+	`, genreID)
+	return err
+}
+
+// CreateGenre adds a genre to the taxonomy, under parentID when it is not nil. Tracks
+// already saved with its spelling are moved into it.
+func (r *Repository) CreateGenre(name string, parentID *int) (*models.Genre, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkGenreName(tx, name, 0); err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if parent, err := getGenre(tx, *parentID); err != nil {
+			return nil, err
+		} else if parent == nil {
+			return nil, ErrGenreParentNotFound
+		}
+	}
+
+	var genreID int
+	err = tx.QueryRow(`
+		INSERT INTO genres (name, slug, parent_id) VALUES ($1, replace(genre_key($1), ' ', '-'), $2)
+		RETURNING id
+	`, name, parentID).Scan(&genreID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT INTO genre_aliases (alias, genre_id) VALUES (genre_key($1), $2)`, name, genreID); err != nil {
+		return nil, err
+	}
+	if err := linkGenreTracks(tx, genreID); err != nil {
+		return nil, err
+	}
+
+	genre, err := getGenre(tx, genreID)
+	if err != nil {
+		return nil, err
+	}
+	return genre, tx.Commit()
+}
+
+// UpdateGenre renames a genre and moves it under parentID, or to the top level when
+// parentID is nil. Its tracks take the new name; the old name stays an alias.
+func (r *Repository) UpdateGenre(genreID int, name string, parentID *int) (*models.Genre, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if genre, err := getGenre(tx, genreID); err != nil {
+		return nil, err
+	} else if genre == nil {
+		return nil, ErrGenreNotFound
+	}
+	if err := checkGenreName(tx, name, genreID); err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if err := checkGenreParent(tx, genreID, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE genres SET name = $1, slug = replace(genre_key($1), ' ', '-'), parent_id = $2 WHERE id = $3
+	`, name, parentID, genreID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT INTO genre_aliases (alias, genre_id) VALUES (genre_key($1), $2) ON CONFLICT (alias) DO NOTHING`, name, genreID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE tracks SET genre = $1 WHERE genre_id = $2 AND genre IS DISTINCT FROM $1`, name, genreID); err != nil {
+		return nil, err
+	}
+	if err := linkGenreTracks(tx, genreID); err != nil {
+		return nil, err
+	}
+
+	genre, err := getGenre(tx, genreID)
+	if err != nil {
+		return nil, err
+	}
+	return genre, tx.Commit()
+}
+
+// MergeGenre folds a genre into another: its tracks, aliases and sub-genres move to the
+// target, and the genre is deleted
+func (r *Repository) MergeGenre(genreID, targetID int) (*models.Genre, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if genre, err := getGenre(tx, genreID); err != nil {
+		return nil, err
+	} else if genre == nil {
+		return nil, ErrGenreNotFound
+	}
+	target, err := getGenre(tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrGenreParentNotFound
+	}
+	if err := checkGenreParent(tx, genreID, targetID); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		`UPDATE tracks SET genre_id = $2, genre = (SELECT name FROM genres WHERE id = $2) WHERE genre_id = $1`,
+		`UPDATE genre_aliases SET genre_id = $2 WHERE genre_id = $1`,
+		`UPDATE genres SET parent_id = $2 WHERE parent_id = $1`,
+		`DELETE FROM genres WHERE id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, genreID, targetID); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err = getGenre(tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return target, tx.Commit()
+}
+
+// AddGenreAlias makes another spelling resolve to a genre, including on tracks already
+// saved with that spelling
+func (r *Repository) AddGenreAlias(genreID int, alias string) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if genre, err := getGenre(tx, genreID); err != nil {
+		return err
+	} else if genre == nil {
+		return ErrGenreNotFound
+	}
+	if err := checkGenreName(tx, alias, 0); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO genre_aliases (alias, genre_id) VALUES (genre_key($1), $2)`, alias, genreID); err != nil {
+		return err
+	}
+	if err := linkGenreTracks(tx, genreID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteGenreAlias stops a spelling from resolving to a genre
+func (r *Repository) DeleteGenreAlias(genreID int, alias string) error {
+	var isName bool
+	err := r.Db.QueryRow(`SELECT genre_key(name) = genre_key($2) FROM genres WHERE id = $1`, genreID, alias).Scan(&isName)
+	if err == sql.ErrNoRows {
+		return ErrGenreNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if isName {
+		return ErrGenreAliasIsName
+	}
+
+	result, err := r.Db.Exec(`DELETE FROM genre_aliases WHERE genre_id = $1 AND alias = genre_key($2)`, genreID, alias)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrGenreAliasNotFound
+	}
+	return nil
+}
+
+// checkGenreName checks that name can name the genre genreID (0 for a new genre) or be
+// added as an alias: it must normalize to something, and not to another genre's alias
+func checkGenreName(q execQueryRower, name string, genreID int) error {
+	var key string
+	var takenBy sql.NullInt64
+	err := q.QueryRow(`
+		SELECT genre_key($1), (SELECT genre_id FROM genre_aliases WHERE alias = genre_key($1))
+	`, name).Scan(&key, &takenBy)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return ErrInvalidGenreName
+	}
+	if takenBy.Valid && int(takenBy.Int64) != genreID {
+		return ErrGenreExists
+	}
+	return nil
+}
+
+// checkGenreParent checks that parentID exists and is not genreID or one of its sub-genres
+func checkGenreParent(q execQueryRower, genreID, parentID int) error {
+	if parent, err := getGenre(q, parentID); err != nil {
+		return err
+	} else if parent == nil {
+		return ErrGenreParentNotFound
+	}
+
+	var cycle bool
+	err := q.QueryRow(`SELECT $2 IN (`+genreSubtree+`)`, genreID, parentID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrGenreCycle
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// importListenBatchSize is how many listens are inserted per statement
+const importListenBatchSize = 1000
+
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// CreateImportJob queues an import of an uploaded data export
+func (r *Repository) CreateImportJob(userID int, source, filename, objectName string) (*models.ImportJob, error) {
+	job := &models.ImportJob{UserID: userID, Source: source, Filename: &filename, ObjectName: &objectName, Status: models.ImportStatusPending}
+	query := `
+		INSERT INTO import_jobs (user_id, source, filename, object_name, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	if err := r.Db.QueryRow(query, userID, source, filename, objectName, job.Status).Scan(&job.ID, &job.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+	return job, nil
+}
+
+const importJobColumns = `id, user_id, source, filename, object_name, status, report, playlists, error, created_at, completed_at`
+
+func scanImportJob(row interface{ Scan(...interface{}) error }) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	var report, playlists []byte
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Source, &job.Filename, &job.ObjectName, &job.Status,
+		&report, &playlists, &job.Error, &job.CreatedAt, &job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(playlists, &job.Playlists); err != nil {
+		return nil, fmt.Errorf("failed to decode imported playlists: %w", err)
+	}
+	if report != nil {
+		job.Report = &models.ImportReport{}
+		if err := json.Unmarshal(report, job.Report); err != nil {
+			return nil, fmt.Errorf("failed to decode import report: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// GetImportJob returns one of a user's import jobs
+func (r *Repository) GetImportJob(jobID, userID int) (*models.ImportJob, error) {
+	row := r.Db.QueryRow(`SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1 AND user_id = $2`, jobID, userID)
+	job, err := scanImportJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrImportJobNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetImportJobs returns a user's import jobs, newest first
+func (r *Repository) GetImportJobs(userID int) ([]models.ImportJob, error) {
+	rows, err := r.Db.Query(`SELECT `+importJobColumns+` FROM import_jobs WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimImportJob marks the oldest pending import job as processing and returns it, or nil
+// if none is pending. Jobs being claimed by another instance are skipped.
+func (r *Repository) ClaimImportJob() (*models.ImportJob, error) {
+	row := r.Db.QueryRow(`
+		UPDATE import_jobs
+		SET status = $1, started_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE status = $2
+			ORDER BY created_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+importJobColumns,
+		models.ImportStatusProcessing, models.ImportStatusPending,
+	)
+	job, err := scanImportJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// RequeueStaleImportJobs puts import jobs that have been processing for longer than timeout,
+// whose worker most likely stopped, back in the queue. Jobs already tried maxAttempts times
+// are failed instead and returned, so that their uploads can be deleted.
+func (r *Repository) RequeueStaleImportJobs(timeout time.Duration, maxAttempts int) (int, []models.ImportJob, error) {
+	rows, err := r.Db.Query(`
+		UPDATE import_jobs
+		SET status = $1, error = $2, completed_at = NOW()
+		WHERE status = $3 AND started_at < NOW() - make_interval(secs => $4) AND attempts >= $5
+		RETURNING `+importJobColumns,
+		models.ImportStatusFailed, "the import did not finish", models.ImportStatusProcessing, timeout.Seconds(), maxAttempts,
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	failed := []models.ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+		failed = append(failed, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	result, err := r.Db.Exec(`
+		UPDATE import_jobs SET status = $1
+		WHERE status = $2 AND started_at < NOW() - make_interval(secs => $3)
+	`, models.ImportStatusPending, models.ImportStatusProcessing, timeout.Seconds())
+	if err != nil {
+		return 0, nil, err
+	}
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+	return int(requeued), failed, nil
+}
+
+// AddImportJobPlaylist records a playlist an import job has created
+func (r *Repository) AddImportJobPlaylist(jobID int, playlist models.ImportedPlaylist) error {
+	data, err := json.Marshal(playlist)
+	if err != nil {
+		return err
+	}
+	_, err = r.Db.Exec(`UPDATE import_jobs SET playlists = playlists || jsonb_build_array($1::jsonb) WHERE id = $2`, data, jobID)
+	return err
+}
+
+// CompleteImportJob marks an import job as completed with its report. The uploaded file
+// has been deleted by then, so the object name is cleared.
+func (r *Repository) CompleteImportJob(jobID int, report *models.ImportReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE import_jobs
+		SET status = $1, report = $2, object_name = NULL, completed_at = NOW()
+		WHERE id = $3
+	`
+	_, err = r.Db.Exec(query, models.ImportStatusCompleted, data, jobID)
+	return err
+}
+
+// FailImportJob marks an import job as failed
+func (r *Repository) FailImportJob(jobID int, message string) error {
+	query := `UPDATE import_jobs SET status = $1, error = $2, completed_at = NOW() WHERE id = $3`
+	_, err := r.Db.Exec(query, models.ImportStatusFailed, message, jobID)
+	return err
+}
+
+// InsertImportedListens backfills listens from another service, keeping their original
+// timestamps and flagging them as imported. Plays that were already imported are skipped.
+// It returns how many listens were added.
+func (r *Repository) InsertImportedListens(userID, jobID int, listens []models.ImportedListen) (int, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO listens (track_id, user_id, listen_duration, timestamp, imported, import_job_id)
+		SELECT l.track_id, $1, NULLIF(l.duration, 0), l.played_at, TRUE, $2
+		FROM UNNEST($3::int[], $4::int[], $5::timestamptz[]) AS l(track_id, duration, played_at)
+		ON CONFLICT (user_id, track_id, timestamp) WHERE imported DO NOTHING
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for start := 0; start < len(listens); start += importListenBatchSize {
+		batch := listens[start:min(start+importListenBatchSize, len(listens))]
+		trackIDs := make(pq.Int64Array, len(batch))
+		durations := make(pq.Int64Array, len(batch))
+		playedAt := make(pq.StringArray, len(batch))
+		for i, l := range batch {
+			trackIDs[i] = int64(l.TrackID)
+			durations[i] = int64(l.ListenDuration)
+			playedAt[i] = l.PlayedAt.UTC().Format(time.RFC3339)
+		}
+
+		res, err := stmt.Exec(userID, jobID, trackIDs, durations, playedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert imported listens: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		inserted += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
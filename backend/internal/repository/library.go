@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+var (
+	ErrLibraryFolderNotFound = errors.New("folder not found")
+	ErrLibraryItemNotFound   = errors.New("item is not in your library")
+	ErrInvalidLibraryMove    = errors.New("a folder cannot be moved into itself or one of its subfolders")
+)
+
+// GetLibraryItems returns every folder, playlist, saved album and followed artist in a
+// user's library as a flat list. Folders and playlists carry the folder they are in.
+func (r *Repository) GetLibraryItems(userID int) ([]models.LibraryItem, error) {
+	items := []models.LibraryItem{}
+
+	folders, err := r.Db.Query(`
+		SELECT id, parent_id, name, position, pinned_at, created_at
+		FROM library_folders
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library folders: %w", err)
+	}
+	defer folders.Close()
+	for folders.Next() {
+		item := models.LibraryItem{Type: models.LibraryItemFolder, Position: new(int)}
+		if err := folders.Scan(&item.ID, &item.FolderID, &item.Name, item.Position, &item.PinnedAt, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := folders.Err(); err != nil {
+		return nil, err
+	}
+
+	// Playlists the user owns, collaborates on or follows, where the user placed them.
+	// Followed playlists drop out while they are not public.
+	playlists, err := r.Db.Query(`
+		SELECT p.id, p.title, u.username, p.creator_id, p.cover_url, p.privacy, p.created_at, p.rules IS NOT NULL,
+		       CASE WHEN p.creator_id = $1 THEN 'owner' ELSE COALESCE(pc.role, '') END,
+		       pf.user_id IS NOT NULL AND p.creator_id <> $1 AND pc.user_id IS NULL,
+		       (SELECT COUNT(*) FROM playlist_follows f WHERE f.playlist_id = p.id),
+		       COALESCE(pc.accepted_at, pf.created_at, p.created_at),
+		       lp.folder_id, lp.position, lp.pinned_at
+		FROM playlists p
+		JOIN users u ON u.id = p.creator_id
+		LEFT JOIN playlist_collaborators pc
+			ON pc.playlist_id = p.id AND pc.user_id = $1 AND pc.accepted_at IS NOT NULL
+		LEFT JOIN playlist_follows pf ON pf.playlist_id = p.id AND pf.user_id = $1
+		LEFT JOIN library_playlists lp ON lp.playlist_id = p.id AND lp.user_id = $1
+		WHERE (p.creator_id = $1 OR pc.user_id IS NOT NULL OR (pf.user_id IS NOT NULL AND p.privacy = 'public'))
+		  AND p.deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library playlists: %w", err)
+	}
+	defer playlists.Close()
+	for playlists.Next() {
+		p := &models.PlaylistResponse{}
+		item := models.LibraryItem{Type: models.LibraryItemPlaylist}
+		err := playlists.Scan(
+			&p.ID, &p.Title, &item.Subtitle, &p.CreatorID, &p.CoverURL, &p.Privacy, &p.CreatedAt, &p.IsSmart,
+			&p.Role, &p.Following, &p.FollowerCount, &item.AddedAt, &item.FolderID, &item.Position, &item.PinnedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.ID, item.Name, item.ImageURL, item.Playlist = p.ID, p.Title, p.CoverURL, p
+		items = append(items, item)
+	}
+	if err := playlists.Err(); err != nil {
+		return nil, err
+	}
+
+	albums, err := r.Db.Query(`
+		SELECT a.id, a.title, u.username, a.cover_url, s.pinned_at, s.created_at
+		FROM saved_albums s
+		JOIN albums a ON a.id = s.album_id
+		JOIN users u ON u.id = a.artist_id
+		WHERE s.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved albums: %w", err)
+	}
+	defer albums.Close()
+	for albums.Next() {
+		item := models.LibraryItem{Type: models.LibraryItemAlbum}
+		if err := albums.Scan(&item.ID, &item.Name, &item.Subtitle, &item.ImageURL, &item.PinnedAt, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := albums.Err(); err != nil {
+		return nil, err
+	}
+
+	// Followed users count as artists once they have published something
+	artists, err := r.Db.Query(`
+		SELECT u.id, u.username, u.avatar_url, f.pinned_at, f.created_at
+		FROM follows f
+		JOIN users u ON u.id = f.followee_id
+		WHERE f.follower_id = $1
+		  AND (EXISTS (SELECT 1 FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published')
+		       OR EXISTS (SELECT 1 FROM albums a WHERE a.artist_id = u.id))
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followed artists: %w", err)
+	}
+	defer artists.Close()
+	for artists.Next() {
+		item := models.LibraryItem{Type: models.LibraryItemArtist}
+		if err := artists.Scan(&item.ID, &item.Name, &item.ImageURL, &item.PinnedAt, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := artists.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Pinned = items[i].PinnedAt != nil
+	}
+	return items, nil
+}
+
+// CreateLibraryFolder adds a folder at the end of parentID, or of the top level when parentID is nil
+func (r *Repository) CreateLibraryFolder(userID int, name string, parentID *int) (*models.LibraryItem, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if parentID != nil {
+		if err := checkLibraryFolder(tx, userID, *parentID); err != nil {
+			return nil, err
+		}
+	}
+	position, err := nextLibraryPosition(tx, userID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := &models.LibraryItem{Type: models.LibraryItemFolder, Name: name, FolderID: parentID, Position: &position}
+	err = tx.QueryRow(
+		`INSERT INTO library_folders (user_id, parent_id, name, position) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		userID, parentID, name, position,
+	).Scan(&folder.ID, &folder.AddedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+	return folder, tx.Commit()
+}
+
+// RenameLibraryFolder renames one of a user's folders
+func (r *Repository) RenameLibraryFolder(userID, folderID int, name string) error {
+	result, err := r.Db.Exec(`UPDATE library_folders SET name = $1 WHERE id = $2 AND user_id = $3`, name, folderID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLibraryFolderNotFound
+	}
+	return nil
+}
+
+// DeleteLibraryFolder deletes a folder. Its subfolders and playlists move to the folder's
+// parent, after the items already there and in their current order.
+func (r *Repository) DeleteLibraryFolder(userID, folderID int) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID *int
+	err = tx.QueryRow(`SELECT parent_id FROM library_folders WHERE id = $1 AND user_id = $2 FOR UPDATE`, folderID, userID).Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrLibraryFolderNotFound
+		}
+		return err
+	}
+
+	offset, err := nextLibraryPosition(tx, userID, parentID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`UPDATE library_folders SET parent_id = $1, position = position + $2 WHERE parent_id = $3`,
+		parentID, offset, folderID,
+	); err != nil {
+		return fmt.Errorf("failed to move subfolders: %w", err)
+	}
+	if _, err := tx.Exec(
+		`UPDATE library_playlists SET folder_id = $1, position = position + $2 WHERE folder_id = $3`,
+		parentID, offset, folderID,
+	); err != nil {
+		return fmt.Errorf("failed to move playlists: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM library_folders WHERE id = $1`, folderID); err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return tx.Commit()
+}
+
+// MoveLibraryItem places a folder or playlist in folderID (nil for the top level) at
+// position, shifting the items at and after it, or at the end when position is nil
+func (r *Repository) MoveLibraryItem(userID int, itemType string, itemID int, folderID *int, position *int) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	switch itemType {
+	case models.LibraryItemFolder:
+		if err := checkLibraryFolder(tx, userID, itemID); err != nil {
+			return err
+		}
+	case models.LibraryItemPlaylist:
+		if err := checkLibraryPlaylist(tx, userID, itemID); err != nil {
+			return err
+		}
+	default:
+		return ErrLibraryItemNotFound
+	}
+
+	if folderID != nil {
+		if err := checkLibraryFolder(tx, userID, *folderID); err != nil {
+			return err
+		}
+		if itemType == models.LibraryItemFolder {
+			// The target must not be the folder itself or below it
+			var inside bool
+			err := tx.QueryRow(`
+				WITH RECURSIVE subtree AS (
+					SELECT id FROM library_folders WHERE id = $1
+					UNION ALL
+					SELECT f.id FROM library_folders f JOIN subtree s ON f.parent_id = s.id
+				)
+				SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+			`, itemID, *folderID).Scan(&inside)
+			if err != nil {
+				return err
+			}
+			if inside {
+				return ErrInvalidLibraryMove
+			}
+		}
+	}
+
+	var to int
+	if position == nil {
+		if to, err = nextLibraryPosition(tx, userID, folderID); err != nil {
+			return err
+		}
+	} else {
+		to = *position
+		if _, err := tx.Exec(
+			`UPDATE library_folders SET position = position + 1
+			 WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2::int AND position >= $3`,
+			userID, folderID, to,
+		); err != nil {
+			return fmt.Errorf("failed to make room in folder: %w", err)
+		}
+		if _, err := tx.Exec(
+			`UPDATE library_playlists SET position = position + 1
+			 WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2::int AND position >= $3`,
+			userID, folderID, to,
+		); err != nil {
+			return fmt.Errorf("failed to make room in folder: %w", err)
+		}
+	}
+
+	if itemType == models.LibraryItemFolder {
+		_, err = tx.Exec(`UPDATE library_folders SET parent_id = $1, position = $2 WHERE id = $3`, folderID, to, itemID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO library_playlists (user_id, playlist_id, folder_id, position)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, playlist_id) DO UPDATE SET folder_id = EXCLUDED.folder_id, position = EXCLUDED.position
+		`, userID, itemID, folderID, to)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move library item: %w", err)
+	}
+	return tx.Commit()
+}
+
+// PinLibraryItem pins or unpins a folder, playlist, saved album or followed artist
+func (r *Repository) PinLibraryItem(userID int, itemType string, itemID int, pinned bool) error {
+	var query string
+	switch itemType {
+	case models.LibraryItemFolder:
+		query = `UPDATE library_folders SET pinned_at = CASE WHEN $3 THEN COALESCE(pinned_at, NOW()) END WHERE user_id = $1 AND id = $2`
+	case models.LibraryItemAlbum:
+		query = `UPDATE saved_albums SET pinned_at = CASE WHEN $3 THEN COALESCE(pinned_at, NOW()) END WHERE user_id = $1 AND album_id = $2`
+	case models.LibraryItemArtist:
+		query = `UPDATE follows SET pinned_at = CASE WHEN $3 THEN COALESCE(pinned_at, NOW()) END WHERE follower_id = $1 AND followee_id = $2`
+	case models.LibraryItemPlaylist:
+		if err := checkLibraryPlaylist(r.Db, userID, itemID); err != nil {
+			return err
+		}
+		query = `
+			INSERT INTO library_playlists (user_id, playlist_id, pinned_at)
+			VALUES ($1, $2, CASE WHEN $3 THEN NOW() END)
+			ON CONFLICT (user_id, playlist_id) DO UPDATE
+			SET pinned_at = CASE WHEN $3 THEN COALESCE(library_playlists.pinned_at, NOW()) END
+		`
+	default:
+		return ErrLibraryItemNotFound
+	}
+
+	result, err := r.Db.Exec(query, userID, itemID, pinned)
+	if err != nil {
+		return fmt.Errorf("failed to pin library item: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLibraryItemNotFound
+	}
+	return nil
+}
+
+// SaveAlbum adds an album to a user's library. Saving an album twice is a no-op.
+func (r *Repository) SaveAlbum(userID, albumID int) error {
+	var exists bool
+	if err := r.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM albums WHERE id = $1)`, albumID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("album not found")
+	}
+
+	_, err := r.Db.Exec(
+		`INSERT INTO saved_albums (user_id, album_id) VALUES ($1, $2) ON CONFLICT (user_id, album_id) DO NOTHING`,
+		userID, albumID,
+	)
+	return err
+}
+
+// UnsaveAlbum removes an album from a user's library. Removing an album that is not saved is a no-op.
+func (r *Repository) UnsaveAlbum(userID, albumID int) error {
+	_, err := r.Db.Exec(`DELETE FROM saved_albums WHERE user_id = $1 AND album_id = $2`, userID, albumID)
+	return err
+}
+
+// checkLibraryFolder makes sure a folder exists and belongs to the user
+func checkLibraryFolder(tx *sql.Tx, userID, folderID int) error {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM library_folders WHERE id = $1 AND user_id = $2)`, folderID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrLibraryFolderNotFound
+	}
+	return nil
+}
+
+// checkLibraryPlaylist makes sure a playlist is in the user's library, i.e. the user owns,
+// collaborates on or follows it
+func checkLibraryPlaylist(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, userID, playlistID int) error {
+	var exists bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM playlists p
+			LEFT JOIN playlist_collaborators pc
+				ON pc.playlist_id = p.id AND pc.user_id = $2 AND pc.accepted_at IS NOT NULL
+			LEFT JOIN playlist_follows pf ON pf.playlist_id = p.id AND pf.user_id = $2
+			WHERE p.id = $1 AND p.deleted_at IS NULL
+			  AND (p.creator_id = $2 OR pc.user_id IS NOT NULL OR (pf.user_id IS NOT NULL AND p.privacy = 'public'))
+		)
+	`, playlistID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrLibraryItemNotFound
+	}
+	return nil
+}
+
+// nextLibraryPosition returns the position after the last folder or playlist in folderID,
+// or in the top level when folderID is nil
+func nextLibraryPosition(tx *sql.Tx, userID int, folderID *int) (int, error) {
+	var position int
+	err := tx.QueryRow(`
+		SELECT COALESCE(MAX(position) + 1, 0) FROM (
+			SELECT position FROM library_folders WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2::int
+			UNION ALL
+			SELECT position FROM library_playlists WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2::int
+		) positions
+	`, userID, folderID).Scan(&position)
+	return position, err
+}
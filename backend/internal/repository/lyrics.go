@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"music-app/backend/internal/models"
+)
+
+// GetTrackLyrics returns a track's lyrics, or nil when the track has none. Callers check
+// that the track exists and may be seen.
+func (r *Repository) GetTrackLyrics(trackID int) (*models.TrackLyrics, error) {
+	var text sql.NullString
+	var lines []byte
+	lyrics := &models.TrackLyrics{TrackID: trackID}
+	err := r.Db.QueryRow(`SELECT lyrics, lyrics_lines, updated_at FROM tracks WHERE id = $1`, trackID).
+		Scan(&text, &lines, &lyrics.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !text.Valid || text.String == "" {
+		return nil, nil
+	}
+
+	lyrics.Text = text.String
+	if lines != nil {
+		if err := json.Unmarshal(lines, &lyrics.Lines); err != nil {
+			return nil, err
+		}
+		lyrics.Synced = len(lyrics.Lines) > 0
+	}
+	return lyrics, nil
+}
+
+// SetTrackLyrics replaces a track's lyrics. lines are the synced lines, nil for plain
+// lyrics; text is what the search index sees.
+func (r *Repository) SetTrackLyrics(trackID int, text string, lines []models.LyricLine) error {
+	var linesJSON []byte
+	if lines != nil {
+		var err error
+		if linesJSON, err = json.Marshal(lines); err != nil {
+			return err
+		}
+	}
+
+	result, err := r.Db.Exec(`
+		UPDATE tracks
+		SET lyrics = $1, lyrics_lines = $2, updated_at = NOW()
+		WHERE id = $3
+	`, text, linesJSON, trackID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteTrackLyrics removes a track's lyrics
+func (r *Repository) DeleteTrackLyrics(trackID int) error {
+	result, err := r.Db.Exec(`
+		UPDATE tracks
+		SET lyrics = NULL, lyrics_lines = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, trackID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// NotificationChannel is the Postgres LISTEN/NOTIFY channel announcing new notifications
+const NotificationChannel = "notifications"
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// notificationEventBatch is how many events one message on NotificationChannel carries at
+// most. An event takes up to 40 bytes with 10-digit IDs, so a batch stays around 6000 bytes,
+// under the 8000 byte limit of NOTIFY.
+const notificationEventBatch = 150
+
+// NotificationEvent announces a notification. Messages on NotificationChannel carry a
+// JSON array of them.
+type NotificationEvent struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+}
+
+const notificationColumns = `
+	n.id, n.user_id, n.type, n.actor_id, u.username, n.entity_type, n.entity_id,
+	n.message, n.read_at, n.created_at
+`
+
+func scanNotification(row interface{ Scan(...interface{}) error }) (models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(
+		&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.ActorName, &n.EntityType, &n.EntityID,
+		&n.Message, &n.ReadAt, &n.CreatedAt,
+	)
+	n.Read = n.ReadAt != nil
+	return n, err
+}
+
+// CreateNotification stores a notification and announces it to listening server instances
+func (r *Repository) CreateNotification(n models.NewNotification) error {
+	return r.createNotifications(`
+		INSERT INTO notifications (user_id, type, actor_id, entity_type, entity_id, message)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		RETURNING id, user_id
+	`, n.UserID, n.Type, n.ActorID, n.EntityType, n.EntityID, n.Message)
+}
+
+// NotifyFollowers delivers a notification to every follower of a user. The UserID
+// of n is ignored.
+func (r *Repository) NotifyFollowers(followeeID int, n models.NewNotification) error {
+	return r.createNotifications(`
+		INSERT INTO notifications (user_id, type, actor_id, entity_type, entity_id, message)
+		SELECT follower_id, $2, $3, NULLIF($4, ''), $5, $6
+		FROM follows
+		WHERE followee_id = $1
+		RETURNING id, user_id
+	`, followeeID, n.Type, n.ActorID, n.EntityType, n.EntityID, n.Message)
+}
+
+// createNotifications runs an insert returning (id, user_id) and publishes the events in
+// batches, so notifying many users takes a handful of messages. Postgres delivers them when
+// the transaction commits.
+func (r *Repository) createNotifications(query string, args ...interface{}) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	events := []NotificationEvent{}
+	for rows.Next() {
+		var e NotificationEvent
+		if err := rows.Scan(&e.ID, &e.UserID); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	payloads := pq.StringArray{}
+	for start := 0; start < len(events); start += notificationEventBatch {
+		payload, err := json.Marshal(events[start:min(start+notificationEventBatch, len(events))])
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, string(payload))
+	}
+	if len(payloads) > 0 {
+		if _, err := tx.Exec(`SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload`, NotificationChannel, payloads); err != nil {
+			return fmt.Errorf("failed to publish notification: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetNotificationByID returns a single notification
+func (r *Repository) GetNotificationByID(notificationID int) (*models.Notification, error) {
+	row := r.Db.QueryRow(`
+		SELECT `+notificationColumns+`
+		FROM notifications n
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.id = $1
+	`, notificationID)
+	n, err := scanNotification(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+	return &n, nil
+}
+
+// GetNotifications returns a page of a user's notifications, newest first, along
+// with the total matching and the number of unread notifications
+func (r *Repository) GetNotifications(userID int, unreadOnly bool, limit, offset int) ([]models.Notification, int, int, error) {
+	var total, unread int
+	err := r.Db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE NOT $2 OR read_at IS NULL), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1
+	`, userID, unreadOnly).Scan(&total, &unread)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	rows, err := r.Db.Query(`
+		SELECT `+notificationColumns+`
+		FROM notifications n
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, total, unread, rows.Err()
+}
+
+// GetNotificationsAfter returns a user's notifications with an ID greater than
+// afterID in delivery order, used to replay events a stream client missed
+func (r *Repository) GetNotificationsAfter(userID, afterID, limit int) ([]models.Notification, error) {
+	rows, err := r.Db.Query(`
+		SELECT `+notificationColumns+`
+		FROM notifications n
+		LEFT JOIN users u ON n.actor_id = u.id
+		WHERE n.user_id = $1 AND n.id > $2
+		ORDER BY n.id ASC
+		LIMIT $3
+	`, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func (r *Repository) MarkNotificationRead(userID, notificationID int) error {
+	result, err := r.Db.Exec(
+		`UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2`,
+		notificationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks all of a user's notifications as read and returns how many changed
+func (r *Repository) MarkAllNotificationsRead(userID int) (int64, error) {
+	result, err := r.Db.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -2,11 +2,15 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"music-app/backend/internal/models"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type PlaylistRepository struct {
@@ -19,157 +23,115 @@ func NewPlaylistRepository(db *sql.DB) *PlaylistRepository {
 
 // CreatePlaylist creates a new playlist
 func (pr *PlaylistRepository) CreatePlaylist(playlist *models.Playlist) (*models.Playlist, error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := createPlaylist(tx, playlist); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// createPlaylist inserts a playlist and records its creation, filling in the generated fields
+func createPlaylist(tx *sql.Tx, playlist *models.Playlist) error {
 	query := `
-		INSERT INTO playlists (title, creator_id, privacy, created_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO playlists (title, creator_id, privacy, rules, created_at)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
 
-	privacy := "public"
+	privacy := models.PlaylistPrivacyPublic
 	if playlist.Privacy != "" {
 		privacy = playlist.Privacy
 	}
 
-	err := pr.db.QueryRow(
+	var rules []byte
+	if playlist.Rules != nil {
+		var err error
+		if rules, err = json.Marshal(playlist.Rules); err != nil {
+			return err
+		}
+	}
+
+	err := tx.QueryRow(
 		query,
 		playlist.Title,
 		playlist.CreatorID,
 		privacy,
+		rules,
 		time.Now(),
 	).Scan(&playlist.ID, &playlist.CreatedAt)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create playlist: %w", err)
+		return fmt.Errorf("failed to create playlist: %w", err)
+	}
+
+	if err := recordPlaylistRevision(tx, playlist.ID, &playlist.CreatorID, models.RevisionCreated, "created the playlist"); err != nil {
+		return err
 	}
 
 	playlist.Privacy = privacy
-	return playlist, nil
+	playlist.IsSmart = playlist.Rules != nil
+	return nil
 }
 
 // GetPlaylistByID retrieves a playlist by ID with its tracks
 func (pr *PlaylistRepository) GetPlaylistByID(id int) (*models.PlaylistWithTracks, error) {
 	slog.Info("Getting playlist by ID", "playlistID", id)
-
-	playlist := &models.Playlist{}
-	query := `
-		SELECT id, title, creator_id, cover_url, privacy, created_at
-		FROM playlists
-		WHERE id = $1
-	`
-
-	err := pr.db.QueryRow(query, id).Scan(
-		&playlist.ID,
-		&playlist.Title,
-		&playlist.CreatorID,
-		&playlist.CoverURL,
-		&playlist.Privacy,
-		&playlist.CreatedAt,
-	)
-
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			slog.Warn("Playlist not found", "playlistID", id)
-			return nil, fmt.Errorf("playlist not found")
-		}
-		slog.Error("Failed to get playlist", "error", err, "playlistID", id)
-		return nil, fmt.Errorf("failed to get playlist: %w", err)
-	}
-
-	slog.Info("Playlist found", "playlistID", id, "title", playlist.Title)
-
-	// Get tracks in the playlist
-	tracksQuery := `
-		SELECT t.id, t.title, t.artist_id, u.username, t.file_url, t.duration, 
-		       t.cover_image_url, t.genre, t.lyrics, t.quality_bitrate, t.status, 
-		       t.created_at, t.updated_at
-		FROM tracks t
-		INNER JOIN playlist_tracks pt ON t.id = pt.track_id
-		LEFT JOIN users u ON t.artist_id = u.id
-		WHERE pt.playlist_id = $1
-		ORDER BY pt.id DESC
-	`
-
-	rows, err := pr.db.Query(tracksQuery, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get playlist tracks: %w", err)
-	}
-	defer rows.Close()
-
-	tracks := []models.TrackWithArtist{}
-	for rows.Next() {
-		var track models.Track
-		var artistName string
-
-		err := rows.Scan(
-			&track.ID,
-			&track.Title,
-			&track.ArtistID,
-			&artistName, // u.username from LEFT JOIN
-			&track.FileURL,
-			&track.Duration,
-			&track.CoverImageURL,
-			&track.Genre,
-			&track.Lyrics,
-			&track.QualityBitrate,
-			&track.Status,
-			&track.CreatedAt,
-			&track.UpdatedAt,
-		)
-		if err != nil {
-			slog.Error("Failed to scan track", "error", err, "playlistID", id)
-			return nil, fmt.Errorf("failed to scan track: %w", err)
-		}
-
-		trackWithArtist := models.TrackWithArtist{
-			ID:             track.ID,
-			Title:          track.Title,
-			ArtistID:       track.ArtistID,
-			ArtistName:     artistName,
-			FileURL:        track.FileURL,
-			Duration:       track.Duration,
-			CoverImageURL:  track.CoverImageURL,
-			Genre:          track.Genre,
-			Lyrics:         track.Lyrics,
-			QualityBitrate: track.QualityBitrate,
-			Status:         track.Status,
-			CreatedAt:      track.CreatedAt,
-			UpdatedAt:      track.UpdatedAt,
-		}
-
-		tracks = append(tracks, trackWithArtist)
-	}
-
-	slog.Info("Tracks fetched for playlist", "playlistID", id, "trackCount", len(tracks))
-
-	return &models.PlaylistWithTracks{
-		ID:        playlist.ID,
-		Title:     playlist.Title,
-		CreatorID: playlist.CreatorID,
-		CoverURL:  playlist.CoverURL,
-		Privacy:   playlist.Privacy,
-		CreatedAt: playlist.CreatedAt,
-		Tracks:    tracks,
-	}, nil
+	return pr.getPlaylistWithTracks(id, 0)
 }
 
 // GetPlaylistByIDWithFavorites retrieves a playlist by ID with its tracks and favorite status for a user
 func (pr *PlaylistRepository) GetPlaylistByIDWithFavorites(id int, userID int) (*models.PlaylistWithTracks, error) {
 	slog.Info("Getting playlist by ID with favorites", "playlistID", id, "userID", userID)
+	return pr.getPlaylistWithTracks(id, userID)
+}
 
+// getPlaylistWithTracks loads a playlist with its tracks and who added them.
+// Favorite status is filled in for userID; pass 0 for anonymous requests.
+func (pr *PlaylistRepository) getPlaylistWithTracks(id int, userID int) (*models.PlaylistWithTracks, error) {
 	playlist := &models.Playlist{}
+	var version int
+	var rules []byte
+	var rulesRefreshedAt *time.Time
+	var following bool
+	var sourceID, sourceCreatorID *int
+	var sourceTitle, sourceCreatorName *string
+	// The source of a fork is shown while it exists and the user can see it
 	query := `
-		SELECT id, title, creator_id, cover_url, privacy, created_at
-		FROM playlists
-		WHERE id = $1
+		SELECT p.id, p.title, p.creator_id, p.cover_url, p.privacy, p.version, p.rules, p.rules_refreshed_at, p.created_at,
+		       (SELECT COUNT(*) FROM playlist_follows f WHERE f.playlist_id = p.id),
+		       EXISTS (SELECT 1 FROM playlist_follows f WHERE f.playlist_id = p.id AND f.user_id = $2),
+		       src.id, src.title, src.creator_id, su.username
+		FROM playlists p
+		LEFT JOIN playlists src ON src.id = p.forked_from_id AND src.deleted_at IS NULL
+			AND (src.privacy = 'public' OR src.creator_id = $2)
+		LEFT JOIN users su ON su.id = src.creator_id
+		WHERE p.id = $1 AND p.deleted_at IS NULL
 	`
 
-	err := pr.db.QueryRow(query, id).Scan(
+	err := pr.db.QueryRow(query, id, userID).Scan(
 		&playlist.ID,
 		&playlist.Title,
 		&playlist.CreatorID,
 		&playlist.CoverURL,
 		&playlist.Privacy,
+		&version,
+		&rules,
+		&rulesRefreshedAt,
 		&playlist.CreatedAt,
+		&playlist.FollowerCount,
+		&following,
+		&sourceID,
+		&sourceTitle,
+		&sourceCreatorID,
+		&sourceCreatorName,
 	)
 
 	if err != nil {
@@ -183,18 +145,22 @@ func (pr *PlaylistRepository) GetPlaylistByIDWithFavorites(id int, userID int) (
 
 	slog.Info("Playlist found", "playlistID", id, "title", playlist.Title)
 
-	// Get tracks in the playlist with favorite status
+	// Get tracks in the playlist with favorite status and contributor
 	tracksQuery := `
 		SELECT t.id, t.title, t.artist_id, u.username, t.file_url, t.duration, 
 		       t.cover_image_url, t.genre, t.lyrics, t.quality_bitrate, t.status, 
 		       t.created_at, t.updated_at,
-		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited
+		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags,
+		       pt.id, pt.position, pt.added_by, ab.username, pt.added_at
 		FROM tracks t
 		INNER JOIN playlist_tracks pt ON t.id = pt.track_id
 		LEFT JOIN users u ON t.artist_id = u.id
+		LEFT JOIN users ab ON pt.added_by = ab.id
 		LEFT JOIN likes l ON t.id = l.track_id AND l.user_id = $2
 		WHERE pt.playlist_id = $1
-		ORDER BY pt.id DESC
+		ORDER BY pt.position ASC, pt.id ASC
 	`
 
 	rows, err := pr.db.Query(tracksQuery, id, userID)
@@ -203,17 +169,16 @@ func (pr *PlaylistRepository) GetPlaylistByIDWithFavorites(id int, userID int) (
 	}
 	defer rows.Close()
 
-	tracks := []models.TrackWithArtist{}
+	tracks := []models.PlaylistTrackEntry{}
 	for rows.Next() {
-		var track models.Track
-		var artistName string
-		var isFavorited bool
+		var entry models.PlaylistTrackEntry
+		track := &entry.TrackWithArtist
 
 		err := rows.Scan(
 			&track.ID,
 			&track.Title,
 			&track.ArtistID,
-			&artistName,
+			&track.ArtistName,
 			&track.FileURL,
 			&track.Duration,
 			&track.CoverImageURL,
@@ -223,53 +188,68 @@ func (pr *PlaylistRepository) GetPlaylistByIDWithFavorites(id int, userID int) (
 			&track.Status,
 			&track.CreatedAt,
 			&track.UpdatedAt,
-			&isFavorited,
+			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
+			&entry.EntryID,
+			&entry.Position,
+			&entry.AddedBy,
+			&entry.AddedByName,
+			&entry.AddedAt,
 		)
 		if err != nil {
 			slog.Error("Failed to scan track", "error", err, "playlistID", id)
 			return nil, fmt.Errorf("failed to scan track: %w", err)
 		}
 
-		trackWithArtist := models.TrackWithArtist{
-			ID:             track.ID,
-			Title:          track.Title,
-			ArtistID:       track.ArtistID,
-			ArtistName:     artistName,
-			FileURL:        track.FileURL,
-			Duration:       track.Duration,
-			CoverImageURL:  track.CoverImageURL,
-			Genre:          track.Genre,
-			Lyrics:         track.Lyrics,
-			QualityBitrate: track.QualityBitrate,
-			Status:         track.Status,
-			CreatedAt:      track.CreatedAt,
-			UpdatedAt:      track.UpdatedAt,
-			IsFavorited:    isFavorited,
-		}
-
-		tracks = append(tracks, trackWithArtist)
+		tracks = append(tracks, entry)
 	}
 
 	slog.Info("Tracks fetched for playlist", "playlistID", id, "trackCount", len(tracks))
 
-	return &models.PlaylistWithTracks{
-		ID:        playlist.ID,
-		Title:     playlist.Title,
-		CreatorID: playlist.CreatorID,
-		CoverURL:  playlist.CoverURL,
-		Privacy:   playlist.Privacy,
-		CreatedAt: playlist.CreatedAt,
-		Tracks:    tracks,
-	}, nil
+	result := &models.PlaylistWithTracks{
+		ID:               playlist.ID,
+		Title:            playlist.Title,
+		CreatorID:        playlist.CreatorID,
+		CoverURL:         playlist.CoverURL,
+		Privacy:          playlist.Privacy,
+		Version:          version,
+		CreatedAt:        playlist.CreatedAt,
+		Tracks:           tracks,
+		RulesRefreshedAt: rulesRefreshedAt,
+		FollowerCount:    playlist.FollowerCount,
+		Following:        following,
+	}
+	if sourceID != nil {
+		result.ForkedFrom = &models.PlaylistSource{
+			ID:          *sourceID,
+			Title:       *sourceTitle,
+			CreatorID:   *sourceCreatorID,
+			CreatorName: *sourceCreatorName,
+		}
+	}
+	if rules != nil {
+		result.Rules = &models.SmartPlaylistRules{}
+		if err := json.Unmarshal(rules, result.Rules); err != nil {
+			return nil, fmt.Errorf("failed to decode playlist rules: %w", err)
+		}
+	}
+	return result, nil
 }
 
-// GetUserPlaylists retrieves all playlists for a user
+// GetUserPlaylists retrieves all playlists a user owns or collaborates on, with the user's role and share status
 func (pr *PlaylistRepository) GetUserPlaylists(userID int) ([]models.Playlist, error) {
 	query := `
-		SELECT id, title, creator_id, cover_url, privacy, created_at
-		FROM playlists
-		WHERE creator_id = $1
-		ORDER BY created_at DESC
+		SELECT p.id, p.title, p.creator_id, p.cover_url, p.privacy, p.created_at, p.rules IS NOT NULL,
+		       CASE WHEN p.creator_id = $1 THEN 'owner' ELSE pc.role END,
+		       (SELECT COUNT(*) FROM playlist_share_links sl WHERE sl.playlist_id = p.id AND sl.revoked_at IS NULL),
+		       (SELECT COUNT(*) FROM playlist_collaborators c WHERE c.playlist_id = p.id AND c.accepted_at IS NOT NULL),
+		       (SELECT COUNT(*) FROM playlist_follows f WHERE f.playlist_id = p.id)
+		FROM playlists p
+		LEFT JOIN playlist_collaborators pc
+			ON pc.playlist_id = p.id AND pc.user_id = $1 AND pc.accepted_at IS NOT NULL
+		WHERE (p.creator_id = $1 OR pc.user_id IS NOT NULL) AND p.deleted_at IS NULL
+		ORDER BY p.created_at DESC
 	`
 
 	rows, err := pr.db.Query(query, userID)
@@ -281,7 +261,7 @@ func (pr *PlaylistRepository) GetUserPlaylists(userID int) ([]models.Playlist, e
 	playlists := []models.Playlist{}
 	for rows.Next() {
 		var p models.Playlist
-		err := rows.Scan(&p.ID, &p.Title, &p.CreatorID, &p.CoverURL, &p.Privacy, &p.CreatedAt)
+		err := rows.Scan(&p.ID, &p.Title, &p.CreatorID, &p.CoverURL, &p.Privacy, &p.CreatedAt, &p.IsSmart, &p.Role, &p.ShareLinkCount, &p.CollaboratorCount, &p.FollowerCount)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan playlist: %w", err)
 		}
@@ -291,187 +271,231 @@ func (pr *PlaylistRepository) GetUserPlaylists(userID int) ([]models.Playlist, e
 	return playlists, nil
 }
 
-// UpdatePlaylist updates a playlist
-func (pr *PlaylistRepository) UpdatePlaylist(id int, creatorID int, update *models.UpdatePlaylistRequest) (*models.Playlist, error) {
-	// Check if playlist belongs to the user
-	var actualCreatorID int
-	err := pr.db.QueryRow("SELECT creator_id FROM playlists WHERE id = $1", id).Scan(&actualCreatorID)
+// UpdatePlaylist updates a playlist. Editors may rename it; only the owner may change its privacy.
+func (pr *PlaylistRepository) UpdatePlaylist(id int, userID int, update *models.UpdatePlaylistRequest) (*models.Playlist, error) {
+	role, err := pr.checkPlaylistRole(id, userID, models.PlaylistRoleEditor, "you don't have permission to update this playlist")
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := pr.db.Begin()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("playlist not found")
-		}
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	if actualCreatorID != creatorID {
+	var title, privacy string
+	if err := tx.QueryRow("SELECT title, privacy FROM playlists WHERE id = $1 FOR UPDATE", id).Scan(&title, &privacy); err != nil {
+		return nil, err
+	}
+	if update.Privacy != "" && update.Privacy != privacy && role != models.PlaylistRoleOwner {
 		return nil, fmt.Errorf("you don't have permission to update this playlist")
 	}
 
 	query := `
 		UPDATE playlists
-		SET title = COALESCE($1, title),
-		    privacy = COALESCE($2, privacy)
+		SET title = COALESCE(NULLIF($1, ''), title),
+		    privacy = COALESCE(NULLIF($2, ''), privacy)
 		WHERE id = $3
-		RETURNING id, title, creator_id, cover_url, privacy, created_at
+		RETURNING id, title, creator_id, cover_url, privacy, created_at,
+		          (SELECT COUNT(*) FROM playlist_follows f WHERE f.playlist_id = playlists.id)
 	`
 
 	playlist := &models.Playlist{}
-	err = pr.db.QueryRow(query, update.Title, update.Privacy, id).Scan(
+	err = tx.QueryRow(query, update.Title, update.Privacy, id).Scan(
 		&playlist.ID,
 		&playlist.Title,
 		&playlist.CreatorID,
 		&playlist.CoverURL,
 		&playlist.Privacy,
 		&playlist.CreatedAt,
+		&playlist.FollowerCount,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update playlist: %w", err)
 	}
 
+	changes := []string{}
+	if playlist.Title != title {
+		changes = append(changes, fmt.Sprintf("renamed the playlist to %q", playlist.Title))
+	}
+	if playlist.Privacy != privacy {
+		changes = append(changes, "made the playlist "+playlist.Privacy)
+	}
+	if len(changes) > 0 {
+		if err := recordPlaylistRevision(tx, id, &userID, models.RevisionUpdated, strings.Join(changes, " and ")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return playlist, nil
 }
 
-// UpdatePlaylistCover updates a playlist's cover image URL
-func (pr *PlaylistRepository) UpdatePlaylistCover(id int, creatorID int, coverURL string) (*models.Playlist, error) {
-	// Check if playlist belongs to the user
-	var actualCreatorID int
-	err := pr.db.QueryRow("SELECT creator_id FROM playlists WHERE id = $1", id).Scan(&actualCreatorID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("playlist not found")
-		}
+// UpdatePlaylistCover updates a playlist's cover image URL. An uploaded cover replaces the
+// generated one until it is removed. Editors and the owner may change the cover.
+func (pr *PlaylistRepository) UpdatePlaylistCover(id int, userID int, coverURL string) (*models.Playlist, error) {
+	if _, err := pr.checkPlaylistRole(id, userID, models.PlaylistRoleEditor, "you don't have permission to update this playlist"); err != nil {
 		return nil, err
 	}
 
-	if actualCreatorID != creatorID {
-		return nil, fmt.Errorf("you don't have permission to update this playlist")
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
 	query := `
 		UPDATE playlists
-		SET cover_url = $1
+		SET cover_url = $1, cover_generated = false, cover_version = NULL, cover_sources = NULL
 		WHERE id = $2
-		RETURNING id, title, creator_id, cover_url, privacy, created_at
+		RETURNING id, title, creator_id, cover_url, privacy, created_at,
+		          (SELECT COUNT(*) FROM playlist_follows f WHERE f.playlist_id = playlists.id)
 	`
 
 	playlist := &models.Playlist{}
-	err = pr.db.QueryRow(query, coverURL, id).Scan(
+	err = tx.QueryRow(query, coverURL, id).Scan(
 		&playlist.ID,
 		&playlist.Title,
 		&playlist.CreatorID,
 		&playlist.CoverURL,
 		&playlist.Privacy,
 		&playlist.CreatedAt,
+		&playlist.FollowerCount,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update playlist cover: %w", err)
 	}
 
+	if err := recordPlaylistRevision(tx, id, &userID, models.RevisionCoverChanged, "changed the cover"); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return playlist, nil
 }
 
-// DeletePlaylist deletes a playlist and its tracks
-func (pr *PlaylistRepository) DeletePlaylist(id int, creatorID int) error {
-	// Check if playlist belongs to the user
-	var actualCreatorID int
-	err := pr.db.QueryRow("SELECT creator_id FROM playlists WHERE id = $1", id).Scan(&actualCreatorID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("playlist not found")
-		}
+// DeletePlaylist moves a playlist to the trash, from which the owner can recover it until
+// it is purged. Only the owner may delete a playlist.
+func (pr *PlaylistRepository) DeletePlaylist(id int, userID int) error {
+	if _, err := pr.checkPlaylistRole(id, userID, models.PlaylistRoleOwner, "you don't have permission to delete this playlist"); err != nil {
 		return err
 	}
 
-	if actualCreatorID != creatorID {
-		return fmt.Errorf("you don't have permission to delete this playlist")
-	}
-
-	// Delete playlist tracks first (due to foreign key)
-	_, err = pr.db.Exec("DELETE FROM playlist_tracks WHERE playlist_id = $1", id)
+	tx, err := pr.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete playlist tracks: %w", err)
+		return err
 	}
+	defer tx.Rollback()
 
-	// Delete playlist
-	_, err = pr.db.Exec("DELETE FROM playlists WHERE id = $1", id)
-	if err != nil {
+	if _, err := tx.Exec("UPDATE playlists SET deleted_at = NOW() WHERE id = $1", id); err != nil {
 		return fmt.Errorf("failed to delete playlist: %w", err)
 	}
-
-	return nil
-}
-
-// AddTrackToPlaylist adds a track to a playlist
-func (pr *PlaylistRepository) AddTrackToPlaylist(playlistID int, trackID int, creatorID int) error {
-	// Check if playlist belongs to the user
-	var actualCreatorID int
-	err := pr.db.QueryRow("SELECT creator_id FROM playlists WHERE id = $1", playlistID).Scan(&actualCreatorID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("playlist not found")
-		}
+	if err := recordPlaylistRevision(tx, id, &userID, models.RevisionDeleted, "deleted the playlist"); err != nil {
 		return err
 	}
 
-	if actualCreatorID != creatorID {
-		return fmt.Errorf("you don't have permission to modify this playlist")
+	return tx.Commit()
+}
+
+// AddTrackToPlaylist adds a track to a playlist on behalf of userID, who must be an editor or the owner.
+// The track is inserted at position, shifting later entries down, or appended when position is nil.
+// If expectedVersion is set and the playlist has changed since, nothing is inserted.
+func (pr *PlaylistRepository) AddTrackToPlaylist(playlistID int, trackID int, userID int, position *int, expectedVersion *int) (*models.PlaylistTrack, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist"); err != nil {
+		return nil, err
 	}
 
 	// Check if track exists
 	var trackExists bool
-	err = pr.db.QueryRow("SELECT EXISTS(SELECT 1 FROM tracks WHERE id = $1)", trackID).Scan(&trackExists)
+	err := pr.db.QueryRow("SELECT EXISTS(SELECT 1 FROM tracks WHERE id = $1)", trackID).Scan(&trackExists)
 	if err != nil || !trackExists {
-		return fmt.Errorf("track not found")
+		return nil, fmt.Errorf("track not found")
 	}
 
-	// Check if track is already in playlist
-	var alreadyExists bool
-	err = pr.db.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM playlist_tracks WHERE playlist_id = $1 AND track_id = $2)",
-		playlistID, trackID,
-	).Scan(&alreadyExists)
+	tx, err := pr.db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	if alreadyExists {
-		return fmt.Errorf("track already in playlist")
+	version, count, err := lockPlaylistTracks(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return nil, fmt.Errorf("playlist was modified, reload and try again")
 	}
 
-	// Add track to playlist
-	_, err = pr.db.Exec(
-		"INSERT INTO playlist_tracks (playlist_id, track_id) VALUES ($1, $2)",
-		playlistID, trackID,
-	)
+	insertAt := count
+	if position != nil {
+		if *position < 0 || *position > count {
+			return nil, fmt.Errorf("position out of range")
+		}
+		insertAt = *position
+		_, err = tx.Exec(
+			"UPDATE playlist_tracks SET position = position + 1 WHERE playlist_id = $1 AND position >= $2",
+			playlistID, insertAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make room for track: %w", err)
+		}
+	}
+
+	entry := &models.PlaylistTrack{PlaylistID: playlistID, TrackID: trackID, Position: insertAt, AddedBy: &userID}
+	err = tx.QueryRow(
+		"INSERT INTO playlist_tracks (playlist_id, track_id, position, added_by) VALUES ($1, $2, $3, $4) RETURNING id, added_at",
+		playlistID, trackID, insertAt, userID,
+	).Scan(&entry.ID, &entry.AddedAt)
 	if err != nil {
-		return fmt.Errorf("failed to add track to playlist: %w", err)
+		return nil, fmt.Errorf("failed to add track to playlist: %w", err)
 	}
 
-	return nil
+	if entry.PlaylistVersion, err = bumpPlaylistVersion(tx, playlistID); err != nil {
+		return nil, err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionTracksAdded, "added "+countTracks(1)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entry, nil
 }
 
-// RemoveTrackFromPlaylist removes a track from a playlist
-func (pr *PlaylistRepository) RemoveTrackFromPlaylist(playlistID int, trackID int, creatorID int) error {
-	// Check if playlist belongs to the user
-	var actualCreatorID int
-	err := pr.db.QueryRow("SELECT creator_id FROM playlists WHERE id = $1", playlistID).Scan(&actualCreatorID)
+// RemoveTrackFromPlaylist removes every entry of a track from a playlist. userID must be an editor or the owner.
+func (pr *PlaylistRepository) RemoveTrackFromPlaylist(playlistID int, trackID int, userID int) error {
+	return pr.removePlaylistEntries(playlistID, userID, "track_id = $2", trackID, "track not found in playlist")
+}
+
+// RemovePlaylistEntry removes a single entry from a playlist. userID must be an editor or the owner.
+func (pr *PlaylistRepository) RemovePlaylistEntry(playlistID int, entryID int, userID int) error {
+	return pr.removePlaylistEntries(playlistID, userID, "id = $2", entryID, "entry not found in playlist")
+}
+
+// removePlaylistEntries deletes the entries matching condition and closes the gaps they leave
+func (pr *PlaylistRepository) removePlaylistEntries(playlistID int, userID int, condition string, arg int, notFoundMessage string) error {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist"); err != nil {
+		return err
+	}
+
+	tx, err := pr.db.Begin()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("playlist not found")
-		}
 		return err
 	}
+	defer tx.Rollback()
 
-	if actualCreatorID != creatorID {
-		return fmt.Errorf("you don't have permission to modify this playlist")
+	if _, _, err := lockPlaylistTracks(tx, playlistID); err != nil {
+		return err
 	}
 
-	result, err := pr.db.Exec(
-		"DELETE FROM playlist_tracks WHERE playlist_id = $1 AND track_id = $2",
-		playlistID, trackID,
-	)
+	result, err := tx.Exec("DELETE FROM playlist_tracks WHERE playlist_id = $1 AND "+condition, playlistID, arg)
 	if err != nil {
 		return fmt.Errorf("failed to remove track from playlist: %w", err)
 	}
@@ -482,8 +506,18 @@ func (pr *PlaylistRepository) RemoveTrackFromPlaylist(playlistID int, trackID in
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("track not found in playlist")
+		return errors.New(notFoundMessage)
 	}
 
-	return nil
+	if err := renumberPlaylistTracks(tx, playlistID); err != nil {
+		return err
+	}
+	if _, err := bumpPlaylistVersion(tx, playlistID); err != nil {
+		return err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionTracksRemoved, "removed "+countTracks(int(rowsAffected))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
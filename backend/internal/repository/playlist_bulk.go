@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetAlbumTrackIDs returns the tracks of an album in album order
+func (pr *PlaylistRepository) GetAlbumTrackIDs(albumID int) ([]int, error) {
+	var exists bool
+	if err := pr.db.QueryRow("SELECT EXISTS(SELECT 1 FROM albums WHERE id = $1)", albumID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("album not found")
+	}
+	return pr.queryIDs("SELECT track_id FROM album_tracks WHERE album_id = $1 ORDER BY position, id", albumID)
+}
+
+// GetSourcePlaylistTrackIDs returns the tracks of a playlist in playlist order. The playlist
+// must be public or userID must have a role on it.
+func (pr *PlaylistRepository) GetSourcePlaylistTrackIDs(playlistID int, userID int) ([]int, error) {
+	var privacy string
+	err := pr.db.QueryRow("SELECT privacy FROM playlists WHERE id = $1 AND deleted_at IS NULL", playlistID).Scan(&privacy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("source playlist not found")
+		}
+		return nil, err
+	}
+	if privacy != models.PlaylistPrivacyPublic {
+		role, err := pr.GetPlaylistRole(playlistID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, fmt.Errorf("source playlist not found")
+		}
+	}
+	return pr.queryIDs("SELECT track_id FROM playlist_tracks WHERE playlist_id = $1 ORDER BY position, id", playlistID)
+}
+
+func (pr *PlaylistRepository) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := pr.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// BulkAddTracks inserts tracks into a playlist in one transaction, keeping their order,
+// at position or at the end. Missing tracks and duplicates (according to dedupe) are
+// skipped and reported per item.
+func (pr *PlaylistRepository) BulkAddTracks(playlistID int, userID int, trackIDs []int, position *int, expectedVersion *int, dedupe string) (*models.BulkTracksResponse, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist"); err != nil {
+		return nil, err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	response, err := addPlaylistTracks(tx, playlistID, userID, trackIDs, position, expectedVersion, dedupe)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// addPlaylistTracks does the work of BulkAddTracks in tx, once the user's role is checked
+func addPlaylistTracks(tx *sql.Tx, playlistID int, userID int, trackIDs []int, position *int, expectedVersion *int, dedupe string) (*models.BulkTracksResponse, error) {
+	version, count, err := lockPlaylistTracks(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return nil, fmt.Errorf("playlist was modified, reload and try again")
+	}
+
+	insertAt := count
+	if position != nil {
+		if *position < 0 || *position > count {
+			return nil, fmt.Errorf("position out of range")
+		}
+		insertAt = *position
+	}
+
+	ids := make([]int64, len(trackIDs))
+	for i, id := range trackIDs {
+		ids[i] = int64(id)
+	}
+	known, err := txTrackIDSet(tx, "SELECT id FROM tracks WHERE id = ANY($1)", pq.Int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	present := map[int]bool{}
+	if dedupe != models.DedupeNone {
+		if present, err = txTrackIDSet(tx, "SELECT track_id FROM playlist_tracks WHERE playlist_id = $1", playlistID); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &models.BulkTracksResponse{PlaylistID: playlistID, Version: version, Results: make([]models.BulkTrackResult, len(trackIDs))}
+	toAdd := []int{}
+	for i, trackID := range trackIDs {
+		result := &response.Results[i]
+		result.TrackID = trackID
+		switch {
+		case !known[trackID]:
+			result.Status = models.BulkStatusNotFound
+		case present[trackID]:
+			result.Status = models.BulkStatusDuplicate
+		default:
+			result.Status = models.BulkStatusAdded
+			toAdd = append(toAdd, i)
+			if dedupe == models.DedupeAll {
+				present[trackID] = true
+			}
+		}
+	}
+
+	if len(toAdd) > 0 {
+		_, err = tx.Exec(
+			"UPDATE playlist_tracks SET position = position + $3 WHERE playlist_id = $1 AND position >= $2",
+			playlistID, insertAt, len(toAdd),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make room for tracks: %w", err)
+		}
+
+		stmt, err := tx.Prepare("INSERT INTO playlist_tracks (playlist_id, track_id, position, added_by) VALUES ($1, $2, $3, $4) RETURNING id")
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+
+		for offset, i := range toAdd {
+			result := &response.Results[i]
+			entryPosition := insertAt + offset
+			var entryID int
+			if err := stmt.QueryRow(playlistID, result.TrackID, entryPosition, userID).Scan(&entryID); err != nil {
+				return nil, fmt.Errorf("failed to add track to playlist: %w", err)
+			}
+			result.EntryID = &entryID
+			result.Position = &entryPosition
+		}
+
+		if response.Version, err = bumpPlaylistVersion(tx, playlistID); err != nil {
+			return nil, err
+		}
+		if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionTracksAdded, "added "+countTracks(len(toAdd))); err != nil {
+			return nil, err
+		}
+	}
+
+	response.Succeeded = len(toAdd)
+	response.Skipped = len(trackIDs) - len(toAdd)
+	return response, nil
+}
+
+// BulkRemoveTracks removes entries by entry ID and every entry of the given tracks in one
+// transaction. Items that are not in the playlist are reported per item.
+func (pr *PlaylistRepository) BulkRemoveTracks(playlistID int, userID int, entryIDs []int, trackIDs []int, expectedVersion *int) (*models.BulkTracksResponse, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist"); err != nil {
+		return nil, err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	version, _, err := lockPlaylistTracks(tx, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return nil, fmt.Errorf("playlist was modified, reload and try again")
+	}
+
+	response := &models.BulkTracksResponse{PlaylistID: playlistID, Version: version, Results: []models.BulkTrackResult{}}
+	remove := func(condition string, arg int, result models.BulkTrackResult) error {
+		res, err := tx.Exec("DELETE FROM playlist_tracks WHERE playlist_id = $1 AND "+condition, playlistID, arg)
+		if err != nil {
+			return fmt.Errorf("failed to remove track from playlist: %w", err)
+		}
+		removed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result.Status = models.BulkStatusNotFound
+		if removed > 0 {
+			result.Status = models.BulkStatusRemoved
+			response.Succeeded++
+		} else {
+			response.Skipped++
+		}
+		response.Results = append(response.Results, result)
+		return nil
+	}
+
+	for _, entryID := range entryIDs {
+		if err := remove("id = $2", entryID, models.BulkTrackResult{EntryID: &entryID}); err != nil {
+			return nil, err
+		}
+	}
+	for _, trackID := range trackIDs {
+		if err := remove("track_id = $2", trackID, models.BulkTrackResult{TrackID: trackID}); err != nil {
+			return nil, err
+		}
+	}
+
+	if response.Succeeded > 0 {
+		if err := renumberPlaylistTracks(tx, playlistID); err != nil {
+			return nil, err
+		}
+		if response.Version, err = bumpPlaylistVersion(tx, playlistID); err != nil {
+			return nil, err
+		}
+		if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionTracksRemoved, "removed "+countTracks(response.Succeeded)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func txTrackIDSet(tx *sql.Tx, query string, args ...interface{}) (map[int]bool, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		set[id] = true
+	}
+	return set, rows.Err()
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+// playlistRoleRank orders playlist roles by the access they grant
+var playlistRoleRank = map[string]int{
+	models.PlaylistRoleViewer: 1,
+	models.PlaylistRoleEditor: 2,
+	models.PlaylistRoleOwner:  3,
+}
+
+// PlaylistRoleAtLeast reports whether role grants at least the access of minRole
+func PlaylistRoleAtLeast(role, minRole string) bool {
+	return playlistRoleRank[role] >= playlistRoleRank[minRole] && role != ""
+}
+
+// GetPlaylistRole returns the role a user has on a playlist: owner for the creator,
+// the role of an accepted collaborator, or "" for everyone else
+func (pr *PlaylistRepository) GetPlaylistRole(playlistID int, userID int) (string, error) {
+	var role string
+	err := pr.db.QueryRow(`
+		SELECT CASE WHEN p.creator_id = $2 THEN 'owner' ELSE COALESCE(pc.role, '') END
+		FROM playlists p
+		LEFT JOIN playlist_collaborators pc
+			ON pc.playlist_id = p.id AND pc.user_id = $2 AND pc.accepted_at IS NOT NULL
+		WHERE p.id = $1 AND p.deleted_at IS NULL
+	`, playlistID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("playlist not found")
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// checkPlaylistRole returns the user's role, or an error with deniedMessage if it is below minRole
+func (pr *PlaylistRepository) checkPlaylistRole(playlistID int, userID int, minRole string, deniedMessage string) (string, error) {
+	role, err := pr.GetPlaylistRole(playlistID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !PlaylistRoleAtLeast(role, minRole) {
+		return role, errors.New(deniedMessage)
+	}
+	return role, nil
+}
+
+// GetCollaborators lists a playlist's collaborators, including pending invites.
+// Any user with access to the playlist may see who else has access.
+func (pr *PlaylistRepository) GetCollaborators(playlistID int, userID int) ([]models.PlaylistCollaborator, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleViewer, "you don't have permission to view this playlist"); err != nil {
+		return nil, err
+	}
+
+	rows, err := pr.db.Query(`
+		SELECT pc.playlist_id, pc.user_id, u.username, u.avatar_url, pc.role, pc.invited_by,
+		       pc.accepted_at IS NOT NULL, pc.created_at, pc.accepted_at
+		FROM playlist_collaborators pc
+		JOIN users u ON pc.user_id = u.id
+		WHERE pc.playlist_id = $1
+		ORDER BY pc.created_at ASC
+	`, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collaborators: %w", err)
+	}
+	defer rows.Close()
+
+	collaborators := []models.PlaylistCollaborator{}
+	for rows.Next() {
+		var c models.PlaylistCollaborator
+		if err := rows.Scan(
+			&c.PlaylistID, &c.UserID, &c.Username, &c.AvatarURL, &c.Role, &c.InvitedBy,
+			&c.Accepted, &c.CreatedAt, &c.AcceptedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan collaborator: %w", err)
+		}
+		collaborators = append(collaborators, c)
+	}
+	return collaborators, rows.Err()
+}
+
+// InviteCollaborator invites a user to a playlist with the given role. Only the owner may invite.
+func (pr *PlaylistRepository) InviteCollaborator(playlistID int, ownerID int, userID int, role string) error {
+	if _, err := pr.checkPlaylistRole(playlistID, ownerID, models.PlaylistRoleOwner, "you don't have permission to manage collaborators"); err != nil {
+		return err
+	}
+	if userID == ownerID {
+		return fmt.Errorf("cannot invite the playlist owner")
+	}
+
+	var userExists bool
+	err := pr.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&userExists)
+	if err != nil || !userExists {
+		return fmt.Errorf("user not found")
+	}
+
+	result, err := pr.db.Exec(`
+		INSERT INTO playlist_collaborators (playlist_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (playlist_id, user_id) DO NOTHING
+	`, playlistID, userID, role, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to invite collaborator: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user is already a collaborator")
+	}
+
+	return nil
+}
+
+// AcceptInvite accepts the user's pending invite to a playlist
+func (pr *PlaylistRepository) AcceptInvite(playlistID int, userID int) error {
+	result, err := pr.db.Exec(`
+		UPDATE playlist_collaborators
+		SET accepted_at = NOW()
+		WHERE playlist_id = $1 AND user_id = $2 AND accepted_at IS NULL
+	`, playlistID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to accept invite: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("invite not found")
+	}
+
+	return nil
+}
+
+// UpdateCollaboratorRole changes a collaborator's role. Only the owner may change roles.
+func (pr *PlaylistRepository) UpdateCollaboratorRole(playlistID int, ownerID int, userID int, role string) error {
+	if _, err := pr.checkPlaylistRole(playlistID, ownerID, models.PlaylistRoleOwner, "you don't have permission to manage collaborators"); err != nil {
+		return err
+	}
+
+	result, err := pr.db.Exec(
+		"UPDATE playlist_collaborators SET role = $1 WHERE playlist_id = $2 AND user_id = $3",
+		role, playlistID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update collaborator: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("collaborator not found")
+	}
+
+	return nil
+}
+
+// RemoveCollaborator removes a collaborator or pending invite. The owner may remove anyone;
+// collaborators may remove themselves to leave a playlist or decline an invite.
+func (pr *PlaylistRepository) RemoveCollaborator(playlistID int, actorID int, userID int) error {
+	if actorID != userID {
+		if _, err := pr.checkPlaylistRole(playlistID, actorID, models.PlaylistRoleOwner, "you don't have permission to manage collaborators"); err != nil {
+			return err
+		}
+	}
+
+	result, err := pr.db.Exec(
+		"DELETE FROM playlist_collaborators WHERE playlist_id = $1 AND user_id = $2",
+		playlistID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove collaborator: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("collaborator not found")
+	}
+
+	return nil
+}
+
+// GetPendingInvites lists the playlists a user has been invited to but not yet joined
+func (pr *PlaylistRepository) GetPendingInvites(userID int) ([]models.PlaylistInvite, error) {
+	rows, err := pr.db.Query(`
+		SELECT p.id, p.title, pc.role, pc.invited_by, u.username, pc.created_at
+		FROM playlist_collaborators pc
+		JOIN playlists p ON pc.playlist_id = p.id
+		LEFT JOIN users u ON pc.invited_by = u.id
+		WHERE pc.user_id = $1 AND pc.accepted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY pc.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.PlaylistInvite{}
+	for rows.Next() {
+		var i models.PlaylistInvite
+		if err := rows.Scan(&i.PlaylistID, &i.PlaylistTitle, &i.Role, &i.InvitedBy, &i.InvitedByName, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
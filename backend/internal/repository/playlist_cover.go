@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetPlaylistsNeedingCovers returns up to limit playlists without an uploaded cover whose
+// generated cover does not reflect their current tracks
+func (pr *PlaylistRepository) GetPlaylistsNeedingCovers(limit int) ([]int, error) {
+	return pr.queryIDs(`
+		SELECT id FROM playlists
+		WHERE deleted_at IS NULL
+		  AND (cover_url IS NULL OR cover_generated)
+		  AND cover_version IS DISTINCT FROM version
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+}
+
+// GetPlaylistCoverState returns a playlist's cover and up to limit distinct covers of its
+// leading tracks. A track's album cover is preferred over its own cover.
+func (pr *PlaylistRepository) GetPlaylistCoverState(playlistID int, limit int) (*models.PlaylistCoverState, error) {
+	state := &models.PlaylistCoverState{PlaylistID: playlistID}
+	var sources pq.StringArray
+	err := pr.db.QueryRow(`
+		SELECT version, cover_url, cover_generated, cover_sources
+		FROM playlists
+		WHERE id = $1 AND deleted_at IS NULL
+	`, playlistID).Scan(&state.Version, &state.CoverURL, &state.Generated, &sources)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("playlist not found")
+		}
+		return nil, err
+	}
+	state.Sources = sources
+
+	rows, err := pr.db.Query(`
+		SELECT cover FROM (
+			SELECT DISTINCT ON (c.cover) c.cover, pt.position, pt.id
+			FROM playlist_tracks pt
+			JOIN tracks t ON t.id = pt.track_id AND t.status = 'published'
+			CROSS JOIN LATERAL (
+				SELECT COALESCE((
+					SELECT a.cover_url FROM album_tracks at
+					JOIN albums a ON a.id = at.album_id
+					WHERE at.track_id = t.id AND a.cover_url IS NOT NULL
+					ORDER BY at.album_id
+					LIMIT 1
+				), t.cover_image_url) AS cover
+			) c
+			WHERE pt.playlist_id = $1 AND c.cover IS NOT NULL
+			ORDER BY c.cover, pt.position, pt.id
+		) leading
+		ORDER BY position, id
+		LIMIT $2
+	`, playlistID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist covers: %w", err)
+	}
+	defer rows.Close()
+
+	state.Leading = []string{}
+	for rows.Next() {
+		var cover string
+		if err := rows.Scan(&cover); err != nil {
+			return nil, err
+		}
+		state.Leading = append(state.Leading, cover)
+	}
+	return state, rows.Err()
+}
+
+// SetGeneratedPlaylistCover stores the cover generated for a playlist as of version, or
+// clears it when coverURL is nil. It reports false, changing nothing, when a cover has been
+// uploaded in the meantime.
+func (pr *PlaylistRepository) SetGeneratedPlaylistCover(playlistID int, version int, coverURL *string, sources []string) (bool, error) {
+	result, err := pr.db.Exec(`
+		UPDATE playlists
+		SET cover_url = $2, cover_generated = $2::text IS NOT NULL, cover_version = $3, cover_sources = $4
+		WHERE id = $1 AND (cover_url IS NULL OR cover_generated)
+	`, playlistID, coverURL, version, pq.Array(sources))
+	if err != nil {
+		return false, fmt.Errorf("failed to update playlist cover: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RemovePlaylistCover removes a playlist's uploaded cover, so a generated one takes its
+// place. Editors and the owner may change the cover.
+func (pr *PlaylistRepository) RemovePlaylistCover(playlistID int, userID int) error {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to update this playlist"); err != nil {
+		return err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE playlists
+		SET cover_url = NULL, cover_generated = false, cover_version = NULL, cover_sources = NULL
+		WHERE id = $1 AND cover_url IS NOT NULL AND NOT cover_generated
+	`, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to remove playlist cover: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("playlist has no uploaded cover")
+	}
+
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionCoverChanged, "removed the cover"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
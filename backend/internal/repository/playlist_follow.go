@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+// getVisiblePlaylist returns the title and rules of a playlist the user may see: a public
+// playlist, or one the user owns or collaborates on. Others are reported as not found.
+func (pr *PlaylistRepository) getVisiblePlaylist(playlistID int, userID int) (string, []byte, error) {
+	var title, privacy string
+	var rules []byte
+	err := pr.db.QueryRow(
+		"SELECT title, privacy, rules FROM playlists WHERE id = $1 AND deleted_at IS NULL",
+		playlistID,
+	).Scan(&title, &privacy, &rules)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, fmt.Errorf("playlist not found")
+		}
+		return "", nil, err
+	}
+
+	if privacy != models.PlaylistPrivacyPublic {
+		role, err := pr.GetPlaylistRole(playlistID, userID)
+		if err != nil {
+			return "", nil, err
+		}
+		if role == "" {
+			return "", nil, fmt.Errorf("playlist not found")
+		}
+	}
+	return title, rules, nil
+}
+
+// ForkPlaylist creates a copy of a playlist owned by the user, attributed to the source.
+// The copy starts with the source's tracks, or its rules for a smart playlist, and is
+// independent of it from then on.
+func (pr *PlaylistRepository) ForkPlaylist(sourceID int, userID int, request *models.ForkPlaylistRequest) (*models.Playlist, error) {
+	sourceTitle, rules, err := pr.getVisiblePlaylist(sourceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &models.Playlist{
+		Title:     sourceTitle,
+		CreatorID: userID,
+		Privacy:   models.PlaylistPrivacyPublic,
+		IsSmart:   rules != nil,
+	}
+	if request.Title != "" {
+		playlist.Title = request.Title
+	}
+	if request.Privacy != "" {
+		playlist.Privacy = request.Privacy
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO playlists (title, creator_id, privacy, rules, forked_from_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, playlist.Title, userID, playlist.Privacy, rules, sourceID).Scan(&playlist.ID, &playlist.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fork playlist: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO playlist_tracks (playlist_id, track_id, position, added_by)
+		SELECT $1, track_id, ROW_NUMBER() OVER (ORDER BY position, id) - 1, $3
+		FROM playlist_tracks
+		WHERE playlist_id = $2
+	`, playlist.ID, sourceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy playlist tracks: %w", err)
+	}
+
+	summary := fmt.Sprintf("forked the playlist from %q", sourceTitle)
+	if err := recordPlaylistRevision(tx, playlist.ID, &userID, models.RevisionCreated, summary); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Rules that refer to "my" plays and likes now refer to the new owner's
+	if playlist.IsSmart {
+		if err := pr.RefreshSmartPlaylist(playlist.ID); err != nil {
+			return nil, err
+		}
+	}
+	return playlist, nil
+}
+
+// FollowPlaylist adds another user's public playlist to the user's library. Following is a
+// live reference: the follower sees the playlist as its owner changes it. Following a
+// playlist twice is a no-op.
+func (pr *PlaylistRepository) FollowPlaylist(playlistID int, userID int) error {
+	var privacy string
+	err := pr.db.QueryRow("SELECT privacy FROM playlists WHERE id = $1 AND deleted_at IS NULL", playlistID).Scan(&privacy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("playlist not found")
+		}
+		return err
+	}
+
+	role, err := pr.GetPlaylistRole(playlistID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "" {
+		return fmt.Errorf("playlist is already in your library")
+	}
+	if privacy != models.PlaylistPrivacyPublic {
+		return fmt.Errorf("playlist not found")
+	}
+
+	_, err = pr.db.Exec(
+		"INSERT INTO playlist_follows (user_id, playlist_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, playlistID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to follow playlist: %w", err)
+	}
+	return nil
+}
+
+// UnfollowPlaylist removes a followed playlist from the user's library, along with where
+// the user had placed it unless they also collaborate on it
+func (pr *PlaylistRepository) UnfollowPlaylist(playlistID int, userID int) error {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM playlist_follows WHERE user_id = $1 AND playlist_id = $2", userID, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow playlist: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("you don't follow this playlist")
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM library_playlists lp
+		WHERE lp.user_id = $1 AND lp.playlist_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM playlists p WHERE p.id = lp.playlist_id AND p.creator_id = $1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM playlist_collaborators pc
+			WHERE pc.playlist_id = lp.playlist_id AND pc.user_id = $1 AND pc.accepted_at IS NOT NULL
+		  )
+	`, userID, playlistID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/smartplaylist"
+
+	"github.com/lib/pq"
+)
+
+// FindTrackCandidates returns published tracks that may correspond to an imported entry:
+// tracks whose title contains title or closely matches a word sequence of it or the other
+// way round, and tracks by an artist named artist. Titles are compared like in search, so
+// the lookup uses the trigram index on track titles.
+func (pr *PlaylistRepository) FindTrackCandidates(title string, artist string, limit int) ([]models.TrackMatchCandidate, error) {
+	// Each branch of the union can use its own index: the trigram index on titles, and the
+	// ones on usernames and track artists
+	query := `
+		SELECT t.id, t.title, u.username, COALESCE(t.duration, 0)
+		FROM tracks t
+		JOIN users u ON t.artist_id = u.id
+		WHERE t.status = 'published'
+		  AND t.id IN (
+			SELECT id FROM tracks
+			WHERE $1 <> '' AND (
+				search_normalize(title) LIKE '%' || search_normalize($2) || '%' OR
+				search_normalize($1) <% search_normalize(title) OR
+				search_normalize(title) <% search_normalize($1)
+			)
+			UNION
+			SELECT id FROM tracks
+			WHERE artist_id IN (SELECT id FROM users WHERE $3 <> '' AND search_normalize(username) = search_normalize($3))
+		  )
+		ORDER BY (search_normalize(t.title) = search_normalize($1)) DESC,
+		         similarity(search_normalize($1), search_normalize(t.title)) DESC,
+		         LENGTH(t.title), t.id
+		LIMIT $4
+	`
+	rows, err := pr.db.Query(query, title, smartplaylist.EscapeLike(title), artist, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []models.TrackMatchCandidate{}
+	for rows.Next() {
+		var c models.TrackMatchCandidate
+		if err := rows.Scan(&c.TrackID, &c.Title, &c.ArtistName, &c.Duration); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// GetTrackCandidatesByID looks up published tracks referenced directly by an imported
+// entry, e.g. through one of our own stream URLs. Unknown IDs are left out of the result.
+func (pr *PlaylistRepository) GetTrackCandidatesByID(trackIDs []int) (map[int]models.TrackMatchCandidate, error) {
+	ids := make([]int64, len(trackIDs))
+	for i, id := range trackIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := pr.db.Query(`
+		SELECT t.id, t.title, u.username, COALESCE(t.duration, 0)
+		FROM tracks t
+		JOIN users u ON t.artist_id = u.id
+		WHERE t.id = ANY($1) AND t.status = 'published'
+	`, pq.Int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := map[int]models.TrackMatchCandidate{}
+	for rows.Next() {
+		var c models.TrackMatchCandidate
+		if err := rows.Scan(&c.TrackID, &c.Title, &c.ArtistName, &c.Duration); err != nil {
+			return nil, err
+		}
+		c.Score = 1
+		candidates[c.TrackID] = c
+	}
+	return candidates, rows.Err()
+}
+
+// ImportPlaylist creates an imported playlist together with its matched tracks in one
+// transaction, so a failed import leaves no empty playlist behind
+func (pr *PlaylistRepository) ImportPlaylist(playlist *models.Playlist, trackIDs []int) (*models.Playlist, error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := createPlaylist(tx, playlist); err != nil {
+		return nil, err
+	}
+	if len(trackIDs) > 0 {
+		if _, err := addPlaylistTracks(tx, playlist.ID, playlist.CreatorID, trackIDs, nil, nil, models.DedupeNone); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
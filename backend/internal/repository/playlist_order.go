@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+// lockPlaylistTracks locks a playlist for a manual track list change and returns its current
+// version and number of entries. Concurrent edits to the same playlist wait for each other.
+// Smart playlists are rejected, since their tracks come from their rules.
+func lockPlaylistTracks(tx *sql.Tx, playlistID int) (int, int, error) {
+	var version int
+	var smart bool
+	err := tx.QueryRow("SELECT version, rules IS NOT NULL FROM playlists WHERE id = $1 FOR UPDATE", playlistID).Scan(&version, &smart)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, fmt.Errorf("playlist not found")
+		}
+		return 0, 0, err
+	}
+	if smart {
+		return 0, 0, fmt.Errorf("smart playlist tracks are managed by its rules")
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM playlist_tracks WHERE playlist_id = $1", playlistID).Scan(&count); err != nil {
+		return 0, 0, err
+	}
+	return version, count, nil
+}
+
+// bumpPlaylistVersion records a change to the track list and returns the new version
+func bumpPlaylistVersion(tx *sql.Tx, playlistID int) (int, error) {
+	var version int
+	err := tx.QueryRow("UPDATE playlists SET version = version + 1 WHERE id = $1 RETURNING version", playlistID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update playlist version: %w", err)
+	}
+	return version, nil
+}
+
+// renumberPlaylistTracks closes gaps in positions so they run from 0 without changing the order
+func renumberPlaylistTracks(tx *sql.Tx, playlistID int) error {
+	_, err := tx.Exec(`
+		UPDATE playlist_tracks pt SET position = o.rn
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY position, id) - 1 AS rn
+			FROM playlist_tracks
+			WHERE playlist_id = $1
+		) o
+		WHERE pt.id = o.id AND pt.position <> o.rn
+	`, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to renumber playlist tracks: %w", err)
+	}
+	return nil
+}
+
+// ReorderPlaylistTracks moves one entry or applies a complete new order, returning the new
+// version. The request is rejected if the playlist changed since request.Version.
+func (pr *PlaylistRepository) ReorderPlaylistTracks(playlistID int, userID int, request *models.ReorderPlaylistRequest) (int, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist"); err != nil {
+		return 0, err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	version, count, err := lockPlaylistTracks(tx, playlistID)
+	if err != nil {
+		return 0, err
+	}
+	if *request.Version != version {
+		return 0, fmt.Errorf("playlist was modified, reload and try again")
+	}
+
+	summary := "reordered the tracks"
+	if request.Order != nil {
+		err = applyPlaylistOrder(tx, playlistID, request.Order)
+	} else {
+		err = movePlaylistEntry(tx, playlistID, *request.EntryID, *request.ToPosition, count)
+		summary = "moved a track"
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if version, err = bumpPlaylistVersion(tx, playlistID); err != nil {
+		return 0, err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionReordered, summary); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+func movePlaylistEntry(tx *sql.Tx, playlistID int, entryID int, to int, count int) error {
+	var from int
+	err := tx.QueryRow("SELECT position FROM playlist_tracks WHERE id = $1 AND playlist_id = $2", entryID, playlistID).Scan(&from)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("entry not found in playlist")
+		}
+		return err
+	}
+	if to < 0 || to >= count {
+		return fmt.Errorf("position out of range")
+	}
+
+	// Shift the entries between the old and new position by one to make room
+	switch {
+	case from < to:
+		_, err = tx.Exec(
+			"UPDATE playlist_tracks SET position = position - 1 WHERE playlist_id = $1 AND position > $2 AND position <= $3",
+			playlistID, from, to,
+		)
+	case from > to:
+		_, err = tx.Exec(
+			"UPDATE playlist_tracks SET position = position + 1 WHERE playlist_id = $1 AND position >= $2 AND position < $3",
+			playlistID, to, from,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move entry: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE playlist_tracks SET position = $1 WHERE id = $2", to, entryID); err != nil {
+		return fmt.Errorf("failed to move entry: %w", err)
+	}
+	return nil
+}
+
+func applyPlaylistOrder(tx *sql.Tx, playlistID int, order []int) error {
+	rows, err := tx.Query("SELECT id FROM playlist_tracks WHERE playlist_id = $1", playlistID)
+	if err != nil {
+		return err
+	}
+	entries := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		entries[id] = false
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(order) != len(entries) {
+		return fmt.Errorf("order must list every entry exactly once")
+	}
+	for _, id := range order {
+		seen, ok := entries[id]
+		if !ok || seen {
+			return fmt.Errorf("order must list every entry exactly once")
+		}
+		entries[id] = true
+	}
+
+	stmt, err := tx.Prepare("UPDATE playlist_tracks SET position = $1 WHERE id = $2")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for position, id := range order {
+		if _, err := stmt.Exec(position, id); err != nil {
+			return fmt.Errorf("failed to reorder playlist: %w", err)
+		}
+	}
+	return nil
+}
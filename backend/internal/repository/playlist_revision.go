@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxPlaylistRevisions is how many revisions are kept per playlist; older ones are pruned
+const MaxPlaylistRevisions = 500
+
+// execQueryRower is satisfied by both *sql.DB and *sql.Tx
+type execQueryRower interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// recordPlaylistRevision stores a revision with a snapshot of the playlist as it is now.
+// Call it after the change, within the same transaction. actorID is nil for system changes.
+func recordPlaylistRevision(q execQueryRower, playlistID int, actorID *int, action string, summary string) error {
+	_, err := q.Exec(`
+		INSERT INTO playlist_revisions (playlist_id, version, actor_id, action, summary, snapshot)
+		SELECT p.id, p.version, $2, $3, $4, jsonb_build_object(
+			'title', p.title,
+			'privacy', p.privacy,
+			'cover_url', p.cover_url,
+			'cover_generated', p.cover_generated,
+			'rules', p.rules,
+			'entries', COALESCE((
+				SELECT jsonb_agg(jsonb_build_object(
+					'track_id', pt.track_id,
+					'added_by', pt.added_by,
+					'added_at', pt.added_at AT TIME ZONE 'UTC'
+				) ORDER BY pt.position, pt.id)
+				FROM playlist_tracks pt
+				WHERE pt.playlist_id = p.id
+			), '[]'::jsonb)
+		)
+		FROM playlists p
+		WHERE p.id = $1
+	`, playlistID, actorID, action, summary)
+	if err != nil {
+		return fmt.Errorf("failed to record playlist revision: %w", err)
+	}
+
+	_, err = q.Exec(`
+		DELETE FROM playlist_revisions
+		WHERE playlist_id = $1 AND id <= (
+			SELECT id FROM playlist_revisions WHERE playlist_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1
+		)
+	`, playlistID, MaxPlaylistRevisions)
+	if err != nil {
+		return fmt.Errorf("failed to prune playlist revisions: %w", err)
+	}
+	return nil
+}
+
+// countTracks describes a number of tracks for revision summaries
+func countTracks(n int) string {
+	if n == 1 {
+		return "1 track"
+	}
+	return fmt.Sprintf("%d tracks", n)
+}
+
+// GetPlaylistHistory returns a page of a playlist's revisions, newest first, without their
+// snapshots. The owner and collaborators may see the history.
+func (pr *PlaylistRepository) GetPlaylistHistory(playlistID int, userID int, limit int, offset int) (*models.PlaylistHistoryResponse, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleViewer, "you don't have permission to view this playlist's history"); err != nil {
+		return nil, err
+	}
+
+	response := &models.PlaylistHistoryResponse{Revisions: []models.PlaylistRevision{}, Limit: limit, Offset: offset}
+	if err := pr.db.QueryRow("SELECT COUNT(*) FROM playlist_revisions WHERE playlist_id = $1", playlistID).Scan(&response.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := pr.db.Query(`
+		SELECT r.id, r.playlist_id, r.version, r.action, r.summary, r.actor_id, u.username,
+		       jsonb_array_length(r.snapshot->'entries'), r.created_at
+		FROM playlist_revisions r
+		LEFT JOIN users u ON r.actor_id = u.id
+		WHERE r.playlist_id = $1
+		ORDER BY r.id DESC
+		LIMIT $2 OFFSET $3
+	`, playlistID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rev models.PlaylistRevision
+		err := rows.Scan(&rev.ID, &rev.PlaylistID, &rev.Version, &rev.Action, &rev.Summary, &rev.ActorID, &rev.ActorName, &rev.TrackCount, &rev.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		response.Revisions = append(response.Revisions, rev)
+	}
+	return response, rows.Err()
+}
+
+// GetPlaylistRevision returns one revision of a playlist with its snapshot
+func (pr *PlaylistRepository) GetPlaylistRevision(playlistID int, revisionID int, userID int) (*models.PlaylistRevision, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleViewer, "you don't have permission to view this playlist's history"); err != nil {
+		return nil, err
+	}
+
+	rev := &models.PlaylistRevision{}
+	var snapshot []byte
+	err := pr.db.QueryRow(`
+		SELECT r.id, r.playlist_id, r.version, r.action, r.summary, r.actor_id, u.username,
+		       jsonb_array_length(r.snapshot->'entries'), r.created_at, r.snapshot
+		FROM playlist_revisions r
+		LEFT JOIN users u ON r.actor_id = u.id
+		WHERE r.id = $1 AND r.playlist_id = $2
+	`, revisionID, playlistID).Scan(
+		&rev.ID, &rev.PlaylistID, &rev.Version, &rev.Action, &rev.Summary, &rev.ActorID, &rev.ActorName,
+		&rev.TrackCount, &rev.CreatedAt, &snapshot,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("revision not found")
+		}
+		return nil, err
+	}
+
+	rev.Snapshot = &models.PlaylistSnapshot{}
+	if err := json.Unmarshal(snapshot, rev.Snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode playlist snapshot: %w", err)
+	}
+	return rev, nil
+}
+
+// RestorePlaylistRevision puts a playlist back into the state recorded by a revision: its
+// title, cover, rules and tracks, and its privacy when the owner restores. Tracks that have
+// since been deleted are skipped. The restore is itself recorded as a revision, so it can
+// be undone. It returns the new version.
+func (pr *PlaylistRepository) RestorePlaylistRevision(playlistID int, revisionID int, userID int) (int, error) {
+	role, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to modify this playlist")
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT 1 FROM playlists WHERE id = $1 FOR UPDATE", playlistID); err != nil {
+		return 0, err
+	}
+
+	// Only the owner may change privacy, so editors keep the current one. A manually uploaded
+	// cover is restored; otherwise the playlist keeps or regenerates its generated cover.
+	result, err := tx.Exec(`
+		UPDATE playlists p
+		SET title = r.snapshot->>'title',
+		    privacy = CASE WHEN $3 THEN COALESCE(r.snapshot->>'privacy', p.privacy) ELSE p.privacy END,
+		    cover_url = CASE
+		        WHEN r.snapshot->>'cover_url' IS NOT NULL AND NOT COALESCE((r.snapshot->>'cover_generated')::boolean, false)
+		            THEN r.snapshot->>'cover_url'
+		        WHEN p.cover_generated THEN p.cover_url
+		    END,
+		    cover_generated = p.cover_generated
+		        AND (r.snapshot->>'cover_url' IS NULL OR COALESCE((r.snapshot->>'cover_generated')::boolean, false)),
+		    rules = NULLIF(r.snapshot->'rules', 'null'::jsonb),
+		    rules_refreshed_at = CASE WHEN r.snapshot->'rules' IS NULL OR r.snapshot->'rules' = 'null'::jsonb THEN NULL ELSE NOW() END
+		FROM playlist_revisions r
+		WHERE p.id = $1 AND r.id = $2 AND r.playlist_id = $1
+	`, playlistID, revisionID, role == models.PlaylistRoleOwner)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore playlist: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, fmt.Errorf("revision not found")
+	}
+
+	if _, err := tx.Exec("DELETE FROM playlist_tracks WHERE playlist_id = $1", playlistID); err != nil {
+		return 0, fmt.Errorf("failed to clear playlist tracks: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO playlist_tracks (playlist_id, track_id, position, added_by, added_at)
+		SELECT $1, t.id, ROW_NUMBER() OVER (ORDER BY e.ord) - 1, u.id,
+		       COALESCE((e.entry->>'added_at')::timestamptz AT TIME ZONE 'UTC', NOW())
+		FROM playlist_revisions r
+		CROSS JOIN LATERAL jsonb_array_elements(r.snapshot->'entries') WITH ORDINALITY AS e(entry, ord)
+		JOIN tracks t ON t.id = (e.entry->>'track_id')::int
+		LEFT JOIN users u ON u.id = (e.entry->>'added_by')::int
+		WHERE r.id = $2
+	`, playlistID, revisionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore playlist tracks: %w", err)
+	}
+
+	version, err := bumpPlaylistVersion(tx, playlistID)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionRestored, fmt.Sprintf("restored revision %d", revisionID)); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// RecoverPlaylist takes a playlist out of the trash. Only the owner may recover a playlist.
+func (pr *PlaylistRepository) RecoverPlaylist(playlistID int, userID int) error {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE playlists SET deleted_at = NULL WHERE id = $1 AND creator_id = $2 AND deleted_at IS NOT NULL",
+		playlistID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to recover playlist: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("playlist not found")
+	}
+
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionRecovered, "recovered the playlist"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDeletedPlaylists lists a user's playlists in the trash, most recently deleted first.
+// Playlists are purged retention after they were deleted.
+func (pr *PlaylistRepository) GetDeletedPlaylists(userID int, retention time.Duration) ([]models.DeletedPlaylist, error) {
+	rows, err := pr.db.Query(`
+		SELECT p.id, p.title, p.creator_id, p.cover_url, p.privacy, p.rules IS NOT NULL, p.created_at, p.deleted_at,
+		       (SELECT COUNT(*) FROM playlist_tracks pt WHERE pt.playlist_id = p.id)
+		FROM playlists p
+		WHERE p.creator_id = $1 AND p.deleted_at IS NOT NULL
+		ORDER BY p.deleted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted playlists: %w", err)
+	}
+	defer rows.Close()
+
+	playlists := []models.DeletedPlaylist{}
+	for rows.Next() {
+		var p models.DeletedPlaylist
+		err := rows.Scan(&p.ID, &p.Title, &p.CreatorID, &p.CoverURL, &p.Privacy, &p.IsSmart, &p.CreatedAt, &p.DeletedAt, &p.TrackCount)
+		if err != nil {
+			return nil, err
+		}
+		p.Role = models.PlaylistRoleOwner
+		p.PurgeAt = p.DeletedAt.Add(retention)
+		playlists = append(playlists, p)
+	}
+	return playlists, rows.Err()
+}
+
+// PurgeDeletedPlaylists permanently deletes playlists that have been in the trash for longer
+// than retention and returns how many were deleted
+func (pr *PlaylistRepository) PurgeDeletedPlaylists(retention time.Duration) (int, error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids, err := txQueryIDs(tx, `
+		SELECT id FROM playlists
+		WHERE deleted_at IS NOT NULL AND deleted_at < NOW() - make_interval(secs => $1)
+		FOR UPDATE
+	`, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Playlist tracks do not cascade; everything else does
+	if _, err := tx.Exec("DELETE FROM playlist_tracks WHERE playlist_id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to delete playlist tracks: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM playlists WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to delete playlists: %w", err)
+	}
+	return len(ids), tx.Commit()
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+// shareTokenBytes is the amount of randomness in a share token
+const shareTokenBytes = 24
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func shareLinkPath(token string) string {
+	return "/api/shared/playlists/" + token
+}
+
+// CreateShareLink creates a new share link for a playlist. Only the owner may share a
+// playlist, and private playlists must be made public or unlisted first.
+func (pr *PlaylistRepository) CreateShareLink(playlistID int, userID int) (*models.PlaylistShareLink, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleOwner, "you don't have permission to share this playlist"); err != nil {
+		return nil, err
+	}
+
+	var privacy string
+	if err := pr.db.QueryRow("SELECT privacy FROM playlists WHERE id = $1", playlistID).Scan(&privacy); err != nil {
+		return nil, err
+	}
+	if privacy == models.PlaylistPrivacyPrivate {
+		return nil, fmt.Errorf("private playlists cannot be shared")
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+
+	link := &models.PlaylistShareLink{PlaylistID: playlistID, Token: token, Path: shareLinkPath(token), CreatedBy: &userID}
+	err = pr.db.QueryRow(`
+		INSERT INTO playlist_share_links (playlist_id, token, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, playlistID, token, userID).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return link, nil
+}
+
+// GetShareLinks lists a playlist's active share links. Only the owner may see them.
+func (pr *PlaylistRepository) GetShareLinks(playlistID int, userID int) ([]models.PlaylistShareLink, error) {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleOwner, "you don't have permission to share this playlist"); err != nil {
+		return nil, err
+	}
+
+	rows, err := pr.db.Query(`
+		SELECT id, playlist_id, token, created_by, created_at
+		FROM playlist_share_links
+		WHERE playlist_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.PlaylistShareLink{}
+	for rows.Next() {
+		var link models.PlaylistShareLink
+		if err := rows.Scan(&link.ID, &link.PlaylistID, &link.Token, &link.CreatedBy, &link.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		link.Path = shareLinkPath(link.Token)
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// RevokeShareLink revokes a share link so it no longer grants access
+func (pr *PlaylistRepository) RevokeShareLink(playlistID int, userID int, linkID int) error {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleOwner, "you don't have permission to share this playlist"); err != nil {
+		return err
+	}
+
+	result, err := pr.db.Exec(`
+		UPDATE playlist_share_links
+		SET revoked_at = NOW()
+		WHERE id = $1 AND playlist_id = $2 AND revoked_at IS NULL
+	`, linkID, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("share link not found")
+	}
+
+	return nil
+}
+
+// GetPlaylistIDByShareToken resolves an active share token. Links stop working while
+// their playlist is private.
+func (pr *PlaylistRepository) GetPlaylistIDByShareToken(token string) (int, error) {
+	var playlistID int
+	err := pr.db.QueryRow(`
+		SELECT p.id
+		FROM playlist_share_links sl
+		JOIN playlists p ON sl.playlist_id = p.id
+		WHERE sl.token = $1 AND sl.revoked_at IS NULL AND p.privacy <> 'private' AND p.deleted_at IS NULL
+	`, token).Scan(&playlistID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("share link not found")
+		}
+		return 0, err
+	}
+	return playlistID, nil
+}
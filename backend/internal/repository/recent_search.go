@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+)
+
+// recentSearchLimit is how many recent searches are kept per user
+const recentSearchLimit = 20
+
+var ErrRecentSearchNotFound = errors.New("recent search not found")
+
+// AddRecentSearch records a search the user ran. Running a search again moves it to the
+// front instead of adding a duplicate, and the oldest searches beyond recentSearchLimit
+// are forgotten.
+func (r *Repository) AddRecentSearch(userID int, query string) (*models.RecentSearch, error) {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	search := &models.RecentSearch{}
+	err = tx.QueryRow(`
+		INSERT INTO recent_searches (user_id, query, searched_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, lower(query)) DO UPDATE SET query = EXCLUDED.query, searched_at = EXCLUDED.searched_at
+		RETURNING id, query, searched_at
+	`, userID, query).Scan(&search.ID, &search.Query, &search.SearchedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record search: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM recent_searches
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM recent_searches WHERE user_id = $1 ORDER BY searched_at DESC, id DESC LIMIT $2
+		)
+	`, userID, recentSearchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trim recent searches: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// GetRecentSearches returns up to limit of the user's recent searches starting with prefix,
+// ignoring case and accents, most recent first. An empty prefix matches them all.
+func (r *Repository) GetRecentSearches(userID int, prefix string, limit int) ([]models.RecentSearch, error) {
+	rows, err := r.Db.Query(`
+		SELECT id, query, searched_at
+		FROM recent_searches
+		WHERE user_id = $1 AND starts_with(search_normalize(query), search_normalize($2))
+		ORDER BY searched_at DESC, id DESC
+		LIMIT $3
+	`, userID, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []models.RecentSearch{}
+	for rows.Next() {
+		var search models.RecentSearch
+		if err := rows.Scan(&search.ID, &search.Query, &search.SearchedAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+// DeleteRecentSearch forgets one of the user's recent searches
+func (r *Repository) DeleteRecentSearch(userID, searchID int) error {
+	result, err := r.Db.Exec("DELETE FROM recent_searches WHERE id = $1 AND user_id = $2", searchID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecentSearchNotFound
+	}
+	return nil
+}
+
+// ClearRecentSearches forgets all of the user's recent searches
+func (r *Repository) ClearRecentSearches(userID int) error {
+	_, err := r.Db.Exec("DELETE FROM recent_searches WHERE user_id = $1", userID)
+	return err
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+)
+
+// UserHasPermission checks if any of the user's roles grants the given permission
+func (r *Repository) UserHasPermission(userID int, permission string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_roles ur
+			JOIN role_permissions rp ON ur.role_id = rp.role_id
+			JOIN permissions p ON rp.permission_id = p.id
+			WHERE ur.user_id = $1 AND p.name = $2
+		)
+	`
+	var allowed bool
+	err := r.Db.QueryRow(query, userID, permission).Scan(&allowed)
+	return allowed, err
+}
+
+// GetRoles returns all roles with their granted permission names
+func (r *Repository) GetRoles() ([]models.Role, error) {
+	query := `
+		SELECT ro.id, ro.name, ro.description,
+		       COALESCE(ARRAY_AGG(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM roles ro
+		LEFT JOIN role_permissions rp ON ro.id = rp.role_id
+		LEFT JOIN permissions p ON rp.permission_id = p.id
+		GROUP BY ro.id
+		ORDER BY ro.id ASC
+	`
+	rows, err := r.Db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		var permissions pq.StringArray
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = permissions
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetPermissions returns all known permissions
+func (r *Repository) GetPermissions() ([]models.Permission, error) {
+	rows, err := r.Db.Query(`SELECT id, name, description FROM permissions ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// GetUserRoles returns the roles assigned to a user
+func (r *Repository) GetUserRoles(userID int) ([]models.UserRole, error) {
+	query := `
+		SELECT ro.name, ur.granted_by, ur.created_at
+		FROM user_roles ur
+		JOIN roles ro ON ur.role_id = ro.id
+		WHERE ur.user_id = $1
+		ORDER BY ro.id ASC
+	`
+	rows, err := r.Db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.UserRole{}
+	for rows.Next() {
+		var role models.UserRole
+		if err := rows.Scan(&role.Role, &role.GrantedBy, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetUserPermissions returns the distinct permission names granted to a user
+func (r *Repository) GetUserPermissions(userID int) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+		JOIN role_permissions rp ON ur.role_id = rp.role_id
+		JOIN permissions p ON rp.permission_id = p.id
+		WHERE ur.user_id = $1
+		ORDER BY p.name ASC
+	`
+	rows, err := r.Db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+
+	return permissions, rows.Err()
+}
+
+// AssignRole assigns a role to a user. Assigning an already held role is a no-op.
+func (r *Repository) AssignRole(userID int, role string, grantedBy *int) error {
+	roleID, err := r.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Db.Exec(
+		`INSERT INTO user_roles (user_id, role_id, granted_by) VALUES ($1, $2, $3) ON CONFLICT (user_id, role_id) DO NOTHING`,
+		userID, roleID, grantedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// RevokeRole removes a role from a user
+func (r *Repository) RevokeRole(userID int, role string) error {
+	roleID, err := r.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.Db.Exec(`DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GrantPermissionToRole adds a permission to a role
+func (r *Repository) GrantPermissionToRole(role, permission string) error {
+	roleID, err := r.getRoleID(role)
+	if err != nil {
+		return err
+	}
+	permissionID, err := r.getPermissionID(permission)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Db.Exec(
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		roleID, permissionID,
+	)
+	return err
+}
+
+// RevokePermissionFromRole removes a permission from a role
+func (r *Repository) RevokePermissionFromRole(role, permission string) error {
+	roleID, err := r.getRoleID(role)
+	if err != nil {
+		return err
+	}
+	permissionID, err := r.getPermissionID(permission)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Db.Exec(
+		`DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`,
+		roleID, permissionID,
+	)
+	return err
+}
+
+// LogAdminAction records a privileged action in admin_logs
+func (r *Repository) LogAdminAction(action string, actorID int, targetID *int) error {
+	_, err := r.Db.Exec(
+		`INSERT INTO admin_logs (action, actor_id, target_id) VALUES ($1, $2, $3)`,
+		action, actorID, targetID,
+	)
+	return err
+}
+
+func (r *Repository) getRoleID(role string) (int, error) {
+	var id int
+	err := r.Db.QueryRow(`SELECT id FROM roles WHERE name = $1`, role).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRoleNotFound
+	}
+	return id, err
+}
+
+func (r *Repository) getPermissionID(permission string) (int, error) {
+	var id int
+	err := r.Db.QueryRow(`SELECT id FROM permissions WHERE name = $1`, permission).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrPermissionNotFound
+	}
+	return id, err
+}
@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"fmt"
+	"html"
+	"music-app/backend/internal/models"
+	"strings"
+)
+
+// Highlighted fragments come back from ts_headline wrapped in these private-use characters,
+// which cannot clash with catalog text, and are turned into <mark> tags once the rest of
+// the fragment has been HTML-escaped
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var (
+	// headlineOptions highlights every match in a short field such as a title
+	headlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", HighlightAll=true`
+	// snippetOptions cuts the matching passages out of a long field such as lyrics
+	snippetOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxWords=20, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
+// searchHighlights turns ts_headline output into HTML-safe fragments keyed by field name,
+// leaving out fields without a match. It returns nil when nothing matched.
+func searchHighlights(fields map[string]*string) map[string]string {
+	var highlights map[string]string
+	for field, fragment := range fields {
+		if fragment == nil || !strings.Contains(*fragment, highlightStart) {
+			continue
+		}
+		escaped := html.EscapeString(*fragment)
+		escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+		escaped = strings.ReplaceAll(escaped, highlightStop, "</mark>")
+		if highlights == nil {
+			highlights = map[string]string{}
+		}
+		highlights[field] = escaped
+	}
+	return highlights
+}
+
+// The match conditions of each searchable type, shared by result pages and counts. They take
+// the query as $1, the genre as $2 and the year as $3; a type without genres or years
+// matches nothing while that filter is set.
+const (
+	trackSearchMatch = `
+		t.status = 'published'
+		AND (
+			t.search_vector @@ search_query($1) OR
+			search_normalize($1) <% search_normalize(t.title) OR
+			t.artist_id IN (SELECT id FROM users WHERE search_normalize($1) <% search_normalize(username))
+		)
+		AND ($2::text = '' OR search_normalize(t.genre) = search_normalize($2))
+		AND ($3::int = 0 OR EXTRACT(YEAR FROM t.created_at) = $3)`
+
+	albumSearchMatch = `
+		(
+			a.search_vector @@ search_query($1) OR
+			search_normalize($1) <% search_normalize(a.title) OR
+			a.artist_id IN (SELECT id FROM users WHERE search_normalize($1) <% search_normalize(username))
+		)
+		AND $2::text = ''
+		AND ($3::int = 0 OR EXTRACT(YEAR FROM COALESCE(a.release_date, a.created_at)) = $3)`
+
+	// Artists are users with published tracks or albums; everyone else is a listener profile
+	userPublishes = `(
+			EXISTS (SELECT 1 FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published') OR
+			EXISTS (SELECT 1 FROM albums a WHERE a.artist_id = u.id)
+		)`
+
+	usernameSearchMatch = `
+		(to_tsvector('search_simple', u.username) @@ search_query($1) OR search_normalize($1) <% search_normalize(u.username))
+		AND $2::text = '' AND $3::int = 0`
+
+	artistSearchMatch = usernameSearchMatch + `
+		AND ` + userPublishes
+
+	userSearchMatch = usernameSearchMatch + `
+		AND NOT ` + userPublishes
+
+	playlistSearchMatch = `
+		p.deleted_at IS NULL AND p.privacy = 'public'
+		AND (to_tsvector('search_simple', p.title) @@ search_query($1) OR search_normalize($1) <% search_normalize(p.title))
+		AND $2::text = '' AND $3::int = 0`
+)
+
+// searchCountQueries count the matches of each type
+var searchCountQueries = map[string]string{
+	models.SearchTypeTrack:    "SELECT COUNT(*) FROM tracks t WHERE " + trackSearchMatch,
+	models.SearchTypeArtist:   "SELECT COUNT(*) FROM users u WHERE " + artistSearchMatch,
+	models.SearchTypeAlbum:    "SELECT COUNT(*) FROM albums a WHERE " + albumSearchMatch,
+	models.SearchTypePlaylist: "SELECT COUNT(*) FROM playlists p WHERE " + playlistSearchMatch,
+	models.SearchTypeUser:     "SELECT COUNT(*) FROM users u WHERE " + userSearchMatch,
+}
+
+// SearchAll searches every requested type at once, paging each the same way, and picks the
+// best match across them on the first page. Facets are counted over all types, whichever
+// were requested.
+func (r *Repository) SearchAll(filter models.SearchFilter, types []string, userID int, limit, offset int) (*models.SearchAllResponse, error) {
+	response := &models.SearchAllResponse{
+		Query:  filter.Query,
+		Types:  types,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	totals := map[string]int{}
+	response.Facets.Types = []models.SearchFacet{}
+	for _, searchType := range models.SearchTypes {
+		var total int
+		args := []interface{}{filter.Query, filter.Genre, filter.Year}
+		if err := r.Db.QueryRow(searchCountQueries[searchType], args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("failed to count %s matches: %w", searchType, err)
+		}
+		totals[searchType] = total
+		response.Facets.Types = append(response.Facets.Types, models.SearchFacet{Value: searchType, Count: total})
+	}
+
+	var err error
+	if response.Facets.Genres, err = r.searchGenreFacets(filter); err != nil {
+		return nil, err
+	}
+	if response.Facets.Years, err = r.searchYearFacets(filter); err != nil {
+		return nil, err
+	}
+
+	var best *models.SearchBestMatch
+	var bestRank float64
+	consider := func(searchType string, item interface{}, rank float64) {
+		if offset == 0 && (best == nil || rank > bestRank) {
+			best = &models.SearchBestMatch{Type: searchType, Item: item}
+			bestRank = rank
+		}
+	}
+
+	for _, searchType := range types {
+		// Skip the queries for a type with nothing on this page
+		empty := totals[searchType] <= offset
+		switch searchType {
+		case models.SearchTypeTrack:
+			results := &models.TrackSearchResults{Items: []models.TrackWithArtist{}, Total: totals[searchType]}
+			if !empty {
+				tracks, rank, err := r.searchTracks(filter, nil, userID, limit, offset)
+				if err != nil {
+					return nil, fmt.Errorf("failed to search tracks: %w", err)
+				}
+				if len(tracks) > 0 {
+					results.Items = tracks
+					consider(searchType, tracks[0], rank)
+				}
+			}
+			response.Tracks = results
+		case models.SearchTypeArtist:
+			results := &models.ArtistSearchResults{Items: []models.ArtistWithStats{}, Total: totals[searchType]}
+			if !empty {
+				artists, rank, err := r.searchArtists(filter, limit, offset)
+				if err != nil {
+					return nil, fmt.Errorf("failed to search artists: %w", err)
+				}
+				if len(artists) > 0 {
+					results.Items = artists
+					consider(searchType, artists[0], rank)
+				}
+			}
+			response.Artists = results
+		case models.SearchTypeAlbum:
+			results := &models.AlbumSearchResults{Items: []models.AlbumWithTracks{}, Total: totals[searchType]}
+			if !empty {
+				albums, rank, err := r.searchAlbums(filter, limit, offset)
+				if err != nil {
+					return nil, err
+				}
+				if len(albums) > 0 {
+					results.Items = albums
+					consider(searchType, albums[0], rank)
+				}
+			}
+			response.Albums = results
+		case models.SearchTypePlaylist:
+			results := &models.PlaylistSearchResults{Items: []models.PlaylistSearchResult{}, Total: totals[searchType]}
+			if !empty {
+				playlists, rank, err := r.searchPlaylists(filter, userID, limit, offset)
+				if err != nil {
+					return nil, err
+				}
+				if len(playlists) > 0 {
+					results.Items = playlists
+					consider(searchType, playlists[0], rank)
+				}
+			}
+			response.Playlists = results
+		case models.SearchTypeUser:
+			results := &models.UserSearchResults{Items: []models.UserSearchResult{}, Total: totals[searchType]}
+			if !empty {
+				users, rank, err := r.searchUsers(filter, limit, offset)
+				if err != nil {
+					return nil, err
+				}
+				if len(users) > 0 {
+					results.Items = users
+					consider(searchType, users[0], rank)
+				}
+			}
+			response.Users = results
+		}
+	}
+
+	response.BestMatch = best
+	return response, nil
+}
+
+// searchPlaylists returns a page of matching public playlists with highlighted titles, along
+// with the rank of the first one
+func (r *Repository) searchPlaylists(filter models.SearchFilter, userID int, limit, offset int) ([]models.PlaylistSearchResult, float64, error) {
+	sqlQuery := `
+		WITH ranked AS (
+			SELECT p.id,
+			       ts_rank(to_tsvector('search_simple', p.title), search_query($1))
+			         + 0.5 * word_similarity(search_normalize($1), search_normalize(p.title)) AS rank
+			FROM playlists p
+			WHERE ` + playlistSearchMatch + `
+		)
+		SELECT p.id, p.title, p.creator_id, p.cover_url, p.privacy, p.rules IS NOT NULL,
+		       p.created_at, p.updated_at, u.username,
+		       (SELECT COUNT(*) FROM playlist_tracks pt WHERE pt.playlist_id = p.id),
+		       (SELECT COUNT(*) FROM playlist_follows pf WHERE pf.playlist_id = p.id) AS follower_count,
+		       EXISTS (SELECT 1 FROM playlist_follows pf WHERE pf.playlist_id = p.id AND pf.user_id = $4),
+		       ts_headline('search_simple', p.title, search_query($1), $7),
+		       ranked.rank
+		FROM ranked
+		JOIN playlists p ON p.id = ranked.id
+		JOIN users u ON u.id = p.creator_id
+		ORDER BY ranked.rank DESC, follower_count DESC, p.id DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.Db.Query(sqlQuery, filter.Query, filter.Genre, filter.Year, userID, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search playlists: %w", err)
+	}
+	defer rows.Close()
+
+	playlists := []models.PlaylistSearchResult{}
+	var bestRank float64
+	for rows.Next() {
+		var playlist models.PlaylistSearchResult
+		var title *string
+		var rank float64
+		if err := rows.Scan(
+			&playlist.ID, &playlist.Title, &playlist.CreatorID, &playlist.CoverURL, &playlist.Privacy,
+			&playlist.IsSmart, &playlist.CreatedAt, &playlist.UpdatedAt, &playlist.CreatorName,
+			&playlist.TrackCount, &playlist.FollowerCount, &playlist.Following, &title, &rank,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan playlist: %w", err)
+		}
+		playlist.Highlights = searchHighlights(map[string]*string{"title": title})
+		if len(playlists) == 0 {
+			bestRank = rank
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists, bestRank, rows.Err()
+}
+
+// searchUsers returns a page of matching listener profiles with highlighted usernames, along
+// with the rank of the first one
+func (r *Repository) searchUsers(filter models.SearchFilter, limit, offset int) ([]models.UserSearchResult, float64, error) {
+	sqlQuery := `
+		WITH ranked AS (
+			SELECT u.id,
+			       ts_rank(to_tsvector('search_simple', u.username), search_query($1))
+			         + word_similarity(search_normalize($1), search_normalize(u.username)) AS rank
+			FROM users u
+			WHERE ` + userSearchMatch + `
+		)
+		SELECT u.id, u.username, u.avatar_url,
+		       (SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) AS follower_count,
+		       ts_headline('search_simple', u.username, search_query($1), $6),
+		       ranked.rank
+		FROM ranked
+		JOIN users u ON u.id = ranked.id
+		ORDER BY ranked.rank DESC, follower_count DESC, u.id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.Db.Query(sqlQuery, filter.Query, filter.Genre, filter.Year, limit, offset, headlineOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.UserSearchResult{}
+	var bestRank float64
+	for rows.Next() {
+		var user models.UserSearchResult
+		var username *string
+		var rank float64
+		if err := rows.Scan(&user.ID, &user.Username, &user.AvatarURL, &user.FollowerCount, &username, &rank); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.Highlights = searchHighlights(map[string]*string{"username": username})
+		if len(users) == 0 {
+			bestRank = rank
+		}
+		users = append(users, user)
+	}
+	return users, bestRank, rows.Err()
+}
+
+// searchGenreFacets counts the matching tracks per genre, most common first, ignoring the
+// genre filter itself
+func (r *Repository) searchGenreFacets(filter models.SearchFilter) ([]models.SearchFacet, error) {
+	sqlQuery := `
+		SELECT t.genre, COUNT(*)
+		FROM tracks t
+		WHERE ` + trackSearchMatch + `
+		  AND COALESCE(t.genre, '') <> ''
+		GROUP BY t.genre
+		ORDER BY COUNT(*) DESC, t.genre
+		LIMIT 20
+	`
+	return r.searchFacets(sqlQuery, filter.Query, "", filter.Year)
+}
+
+// searchYearFacets counts the matching tracks and albums per year, newest first, ignoring
+// the year filter itself
+func (r *Repository) searchYearFacets(filter models.SearchFilter) ([]models.SearchFacet, error) {
+	sqlQuery := `
+		SELECT year::text, COUNT(*)
+		FROM (
+			SELECT EXTRACT(YEAR FROM t.created_at)::int AS year FROM tracks t WHERE ` + trackSearchMatch + `
+			UNION ALL
+			SELECT EXTRACT(YEAR FROM COALESCE(a.release_date, a.created_at))::int FROM albums a WHERE ` + albumSearchMatch + `
+		) years
+		GROUP BY year
+		ORDER BY year DESC
+	`
+	return r.searchFacets(sqlQuery, filter.Query, filter.Genre, 0)
+}
+
+func (r *Repository) searchFacets(sqlQuery string, args ...interface{}) ([]models.SearchFacet, error) {
+	rows, err := r.Db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := []models.SearchFacet{}
+	for rows.Next() {
+		var facet models.SearchFacet
+		if err := rows.Scan(&facet.Value, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+// GetSearchSuggestionSources returns the names offered as search suggestions: published
+// tracks, artists and albums, each with its all-time listen count
+func (r *Repository) GetSearchSuggestionSources() ([]models.SearchSuggestion, error) {
+	rows, err := r.Db.Query(`
+		WITH track_listens AS (
+			SELECT track_id, COUNT(*) AS total FROM listens GROUP BY track_id
+		)
+		SELECT 'track', t.id, t.title, u.username, NULLIF(t.cover_image_url, ''), COALESCE(tl.total, 0)
+		FROM tracks t
+		JOIN users u ON u.id = t.artist_id
+		LEFT JOIN track_listens tl ON tl.track_id = t.id
+		WHERE t.status = 'published'
+		UNION ALL
+		SELECT 'artist', u.id, u.username, '', u.avatar_url,
+		       COALESCE((SELECT SUM(tl.total) FROM tracks t JOIN track_listens tl ON tl.track_id = t.id WHERE t.artist_id = u.id), 0)
+		FROM users u
+		WHERE EXISTS (SELECT 1 FROM tracks t WHERE t.artist_id = u.id AND t.status = 'published')
+		   OR EXISTS (SELECT 1 FROM albums a WHERE a.artist_id = u.id)
+		UNION ALL
+		SELECT 'album', a.id, a.title, u.username, a.cover_url,
+		       COALESCE((SELECT SUM(tl.total) FROM album_tracks at JOIN track_listens tl ON tl.track_id = at.track_id WHERE at.album_id = a.id), 0)
+		FROM albums a
+		JOIN users u ON u.id = a.artist_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []models.SearchSuggestion
+	for rows.Next() {
+		var s models.SearchSuggestion
+		if err := rows.Scan(&s.Type, &s.ID, &s.Text, &s.Subtitle, &s.ImageURL, &s.Listens); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
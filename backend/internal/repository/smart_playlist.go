@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/smartplaylist"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// autoRefreshable limits automatic refreshes to playlists whose result can change without
+// a user doing anything. Random-sort playlists are left alone once evaluated: reshuffling
+// them would bump the version, regenerate the cover and record a revision every time. They
+// are reshuffled when a user refreshes them or changes their rules.
+const autoRefreshable = `(rules_refreshed_at IS NULL OR rules->'sort'->>'field' IS DISTINCT FROM 'random')`
+
+// RefreshSmartPlaylist evaluates a smart playlist's rules and replaces its cached track
+// list when the result changed. The version is only bumped on a change.
+func (pr *PlaylistRepository) RefreshSmartPlaylist(playlistID int) error {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	changed, err := refreshSmartPlaylist(tx, playlistID)
+	if err != nil {
+		return err
+	}
+	if changed {
+		if err := recordPlaylistRevision(tx, playlistID, nil, models.RevisionRefreshed, "refreshed the tracks from the playlist rules"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// refreshSmartPlaylist does the work of RefreshSmartPlaylist within tx and reports whether
+// the track list changed
+func refreshSmartPlaylist(tx *sql.Tx, playlistID int) (bool, error) {
+	var data []byte
+	var ownerID int
+	err := tx.QueryRow("SELECT rules, creator_id FROM playlists WHERE id = $1 FOR UPDATE", playlistID).Scan(&data, &ownerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("playlist not found")
+		}
+		return false, err
+	}
+	if data == nil {
+		return false, fmt.Errorf("playlist is not a smart playlist")
+	}
+
+	rules := &models.SmartPlaylistRules{}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return false, fmt.Errorf("failed to decode playlist rules: %w", err)
+	}
+	query, args, err := smartplaylist.Compile(rules, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := txQueryIDs(tx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate playlist rules: %w", err)
+	}
+	current, err := txQueryIDs(tx, "SELECT track_id FROM playlist_tracks WHERE playlist_id = $1 ORDER BY position, id", playlistID)
+	if err != nil {
+		return false, err
+	}
+
+	changed := !slices.Equal(matched, current)
+	if changed {
+		if _, err := tx.Exec("DELETE FROM playlist_tracks WHERE playlist_id = $1", playlistID); err != nil {
+			return false, fmt.Errorf("failed to clear playlist tracks: %w", err)
+		}
+
+		ids := make(pq.Int64Array, len(matched))
+		for i, id := range matched {
+			ids[i] = int64(id)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO playlist_tracks (playlist_id, track_id, position, added_by)
+			SELECT $1, m.track_id, m.ord - 1, $3
+			FROM UNNEST($2::int[]) WITH ORDINALITY AS m(track_id, ord)
+		`, playlistID, ids, ownerID)
+		if err != nil {
+			return false, fmt.Errorf("failed to add playlist tracks: %w", err)
+		}
+
+		if _, err := bumpPlaylistVersion(tx, playlistID); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE playlists SET rules_refreshed_at = NOW() WHERE id = $1", playlistID); err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// RefreshSmartPlaylistIfStale refreshes a smart playlist whose cached tracks are older than
+// maxAge, and reports whether it did. Regular and random-sort playlists are left alone.
+func (pr *PlaylistRepository) RefreshSmartPlaylistIfStale(playlistID int, maxAge time.Duration) (bool, error) {
+	var stale bool
+	err := pr.db.QueryRow(`
+		SELECT rules IS NOT NULL AND `+autoRefreshable+`
+		       AND (rules_refreshed_at IS NULL OR rules_refreshed_at < NOW() - make_interval(secs => $2))
+		FROM playlists WHERE id = $1 AND deleted_at IS NULL
+	`, playlistID, maxAge.Seconds()).Scan(&stale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("playlist not found")
+		}
+		return false, err
+	}
+	if !stale {
+		return false, nil
+	}
+	return true, pr.RefreshSmartPlaylist(playlistID)
+}
+
+// GetStaleSmartPlaylistIDs returns up to limit smart playlists whose cached tracks are older
+// than maxAge, least recently refreshed first. Random-sort playlists are left out.
+func (pr *PlaylistRepository) GetStaleSmartPlaylistIDs(maxAge time.Duration, limit int) ([]int, error) {
+	return pr.queryIDs(`
+		SELECT id FROM playlists
+		WHERE rules IS NOT NULL AND deleted_at IS NULL AND `+autoRefreshable+`
+		  AND (rules_refreshed_at IS NULL OR rules_refreshed_at < NOW() - make_interval(secs => $1))
+		ORDER BY rules_refreshed_at NULLS FIRST, id
+		LIMIT $2
+	`, maxAge.Seconds(), limit)
+}
+
+// SetPlaylistRules turns a playlist into a smart playlist, or changes its rules, and
+// evaluates them right away. Editors and the owner may change the rules.
+func (pr *PlaylistRepository) SetPlaylistRules(playlistID int, userID int, rules *models.SmartPlaylistRules) error {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to update this playlist"); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE playlists SET rules = $1, rules_refreshed_at = NULL WHERE id = $2", data, playlistID); err != nil {
+		return fmt.Errorf("failed to update playlist rules: %w", err)
+	}
+	if _, err := refreshSmartPlaylist(tx, playlistID); err != nil {
+		return err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionRulesChanged, "changed the playlist rules"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ClearPlaylistRules turns a smart playlist back into a regular one. Its current tracks are
+// kept and can be edited by hand from then on.
+func (pr *PlaylistRepository) ClearPlaylistRules(playlistID int, userID int) error {
+	if _, err := pr.checkPlaylistRole(playlistID, userID, models.PlaylistRoleEditor, "you don't have permission to update this playlist"); err != nil {
+		return err
+	}
+
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE playlists SET rules = NULL, rules_refreshed_at = NULL WHERE id = $1 AND rules IS NOT NULL", playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to update playlist rules: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := recordPlaylistRevision(tx, playlistID, &userID, models.RevisionRulesRemoved, "turned the playlist into a regular playlist"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// txQueryIDs returns the IDs selected by query within tx, in order
+func txQueryIDs(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("a tag with this name already exists")
+)
+
+// tagTrackCount counts the published tracks with the tag aliased tg
+const tagTrackCount = `(
+	SELECT COUNT(*) FROM track_tags tt JOIN tracks t ON t.id = tt.track_id
+	WHERE tt.tag_id = tg.id AND t.status = 'published'
+)`
+
+// GetTags returns all tags in alphabetical order
+func (r *Repository) GetTags() ([]models.Tag, error) {
+	return r.queryTags(`SELECT tg.id, tg.name, ` + tagTrackCount + ` FROM tags tg ORDER BY tg.name ASC`)
+}
+
+// GetPopularTags returns the tags on the most published tracks, for a tag cloud. Tags on
+// no published track are left out.
+func (r *Repository) GetPopularTags(limit int) ([]models.Tag, error) {
+	return r.queryTags(`
+		SELECT id, name, track_count
+		FROM (SELECT tg.id, tg.name, `+tagTrackCount+` AS track_count FROM tags tg) counted
+		WHERE track_count > 0
+		ORDER BY track_count DESC, name ASC
+		LIMIT $1
+	`, limit)
+}
+
+func (r *Repository) queryTags(query string, args ...interface{}) ([]models.Tag, error) {
+	rows, err := r.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.TrackCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetTagByName returns the tag with the given name, or nil if there is none
+func (r *Repository) GetTagByName(name string) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := r.Db.QueryRow(`SELECT tg.id, tg.name, `+tagTrackCount+` FROM tags tg WHERE tg.name = $1`, name).
+		Scan(&tag.ID, &tag.Name, &tag.TrackCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// CreateTag adds a tag to the taxonomy
+func (r *Repository) CreateTag(name string) (*models.Tag, error) {
+	tag := &models.Tag{Name: name}
+	err := r.Db.QueryRow(`INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id`, name).Scan(&tag.ID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTagExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// RenameTag renames a tag, keeping it on its tracks
+func (r *Repository) RenameTag(tagID int, name string) (*models.Tag, error) {
+	var taken bool
+	if err := r.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM tags WHERE name = $1 AND id <> $2)`, name, tagID).Scan(&taken); err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrTagExists
+	}
+
+	tag := &models.Tag{}
+	err := r.Db.QueryRow(`
+		UPDATE tags tg SET name = $1 WHERE tg.id = $2
+		RETURNING tg.id, tg.name, `+tagTrackCount,
+		name, tagID,
+	).Scan(&tag.ID, &tag.Name, &tag.TrackCount)
+	if err == sql.ErrNoRows {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag removes a tag from the taxonomy and from every track
+func (r *Repository) DeleteTag(tagID int) error {
+	result, err := r.Db.Exec(`DELETE FROM tags WHERE id = $1`, tagID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// GetUnknownTags returns the names that are not tags, in the order given
+func (r *Repository) GetUnknownTags(names []string) ([]string, error) {
+	rows, err := r.Db.Query(`
+		SELECT n.name
+		FROM unnest($1::text[]) WITH ORDINALITY AS n(name, position)
+		WHERE NOT EXISTS (SELECT 1 FROM tags tg WHERE tg.name = n.name)
+		ORDER BY n.position
+	`, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unknown := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		unknown = append(unknown, name)
+	}
+	return unknown, rows.Err()
+}
+
+// GetTrackTags returns the names of a track's tags in alphabetical order
+func (r *Repository) GetTrackTags(trackID int) ([]string, error) {
+	var tags pq.StringArray
+	err := r.Db.QueryRow(`
+		SELECT COALESCE(ARRAY_AGG(tg.name ORDER BY tg.name), '{}')
+		FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.track_id = $1
+	`, trackID).Scan(&tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SetTrackTags replaces a track's tags with the named ones, which must exist
+func (r *Repository) SetTrackTags(trackID int, names []string) error {
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM track_tags WHERE track_id = $1`, trackID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO track_tags (track_id, tag_id)
+		SELECT $1, tg.id FROM tags tg WHERE tg.name = ANY($2)
+		ON CONFLICT (track_id, tag_id) DO NOTHING
+	`, trackID, pq.Array(names))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveTrackTag removes a tag from a track
+func (r *Repository) RemoveTrackTag(trackID int, name string) error {
+	result, err := r.Db.Exec(`
+		DELETE FROM track_tags tt
+		USING tags tg
+		WHERE tt.tag_id = tg.id AND tt.track_id = $1 AND tg.name = $2
+	`, trackID, name)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"music-app/backend/internal/listing"
 	"music-app/backend/internal/models"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 // GetTrackByID retrieves a track by its ID
@@ -13,7 +16,7 @@ func (r *Repository) GetTrackByID(id int) (*models.Track, error) {
 	query := `
 		SELECT id, title, artist_id, file_url, 
 		       COALESCE(duration, 0), COALESCE(cover_image_url, ''), 
-		       COALESCE(genre, ''), COALESCE(lyrics, ''), 
+		       COALESCE(genre, ''), genre_id, COALESCE(lyrics, ''), 
 		       COALESCE(quality_bitrate, 0), COALESCE(status, 'published'), 
 		       created_at, updated_at
 		FROM tracks
@@ -28,6 +31,7 @@ func (r *Repository) GetTrackByID(id int) (*models.Track, error) {
 		&track.Duration,
 		&track.CoverImageURL,
 		&track.Genre,
+		&track.GenreID,
 		&track.Lyrics,
 		&track.QualityBitrate,
 		&track.Status,
@@ -43,10 +47,14 @@ func (r *Repository) GetTrackByID(id int) (*models.Track, error) {
 	return track, nil
 }
 
+// CreateTrack stores a track with its status, published unless set otherwise
 func (r *Repository) CreateTrack(track *models.Track) error {
+	if track.Status == "" {
+		track.Status = "published"
+	}
 	query := `
-		INSERT INTO tracks (title, artist_id, file_url, duration, cover_image_url, genre, status)
-		VALUES ($1, $2, $3, $4, $5, $6, 'published')
+		INSERT INTO tracks (title, artist_id, file_url, duration, cover_image_url, genre, genre_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
 	return r.Db.QueryRow(
@@ -57,60 +65,20 @@ func (r *Repository) CreateTrack(track *models.Track) error {
 		track.Duration,
 		track.CoverImageURL,
 		track.Genre,
+		track.GenreID,
+		track.Status,
 	).Scan(&track.ID, &track.CreatedAt, &track.UpdatedAt)
 }
 
-// GetAllTracks retrieves all published tracks with artist information
-func (r *Repository) GetAllTracks(limit, offset int) ([]models.TrackWithArtist, error) {
-	query := `
-		SELECT t.id, t.title, t.artist_id, t.file_url, 
-		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''), 
-		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''), 
-		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'), 
-		       t.created_at, t.updated_at,
-		       u.username as artist_name
-		FROM tracks t
-		LEFT JOIN users u ON t.artist_id = u.id
-		WHERE t.status = 'published'
-		ORDER BY t.created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-
-	rows, err := r.Db.Query(query, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var tracks []models.TrackWithArtist
-	for rows.Next() {
-		var track models.TrackWithArtist
-		err := rows.Scan(
-			&track.ID,
-			&track.Title,
-			&track.ArtistID,
-			&track.FileURL,
-			&track.Duration,
-			&track.CoverImageURL,
-			&track.Genre,
-			&track.Lyrics,
-			&track.QualityBitrate,
-			&track.Status,
-			&track.CreatedAt,
-			&track.UpdatedAt,
-			&track.ArtistName,
-		)
-		if err != nil {
-			return nil, err
-		}
-		tracks = append(tracks, track)
-	}
-
-	return tracks, nil
+// GetAllTracks retrieves published tracks with artist information, filtered and sorted by
+// opts and newest first otherwise
+func (r *Repository) GetAllTracks(opts *listing.Options, limit, offset int) ([]models.TrackWithArtist, error) {
+	return r.GetAllTracksWithFavorites(opts, 0, limit, offset)
 }
 
-// GetAllTracksWithFavorites retrieves all published tracks with artist information and favorite status for a specific user
-func (r *Repository) GetAllTracksWithFavorites(userID int, limit, offset int) ([]models.TrackWithArtist, error) {
+// GetAllTracksWithFavorites retrieves published tracks with artist information and favorite status for a specific user
+func (r *Repository) GetAllTracksWithFavorites(opts *listing.Options, userID int, limit, offset int) ([]models.TrackWithArtist, error) {
+	where, orderBy, args := opts.Compile([]interface{}{limit, offset, userID}, "t.created_at DESC, t.id DESC")
 	query := `
 		SELECT t.id, t.title, t.artist_id, t.file_url, 
 		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''), 
@@ -118,16 +86,18 @@ func (r *Repository) GetAllTracksWithFavorites(userID int, limit, offset int) ([
 		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'), 
 		       t.created_at, t.updated_at,
 		       u.username as artist_name,
-		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited
+		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		LEFT JOIN users u ON t.artist_id = u.id
 		LEFT JOIN likes l ON t.id = l.track_id AND l.user_id = $3
-		WHERE t.status = 'published'
-		ORDER BY t.created_at DESC
+		WHERE t.status = 'published' AND ` + where + `
+		ORDER BY ` + orderBy + `
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.Db.Query(query, limit, offset, userID)
+	rows, err := r.Db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +121,8 @@ func (r *Repository) GetAllTracksWithFavorites(userID int, limit, offset int) ([
 			&track.UpdatedAt,
 			&track.ArtistName,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		)
 		if err != nil {
 			return nil, err
@@ -161,89 +133,74 @@ func (r *Repository) GetAllTracksWithFavorites(userID int, limit, offset int) ([
 	return tracks, nil
 }
 
-// SearchTracks searches for tracks by title, artist name, or genre
-func (r *Repository) SearchTracks(query string, limit, offset int) ([]models.TrackWithArtist, error) {
-	sqlQuery := `
-		SELECT t.id, t.title, t.artist_id, t.file_url, 
-		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''), 
-		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''), 
-		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'), 
-		       t.created_at, t.updated_at,
-		       u.username as artist_name
-		FROM tracks t
-		LEFT JOIN users u ON t.artist_id = u.id
-		WHERE t.status = 'published' AND (
-			t.title ILIKE '%' || $1 || '%' OR 
-			u.username ILIKE '%' || $1 || '%' OR
-			t.genre ILIKE '%' || $1 || '%'
-		)
-		ORDER BY t.created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-
-	rows, err := r.Db.Query(sqlQuery, query, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// SearchTracks searches published tracks by title, artist name, genre and lyrics, best
+// matches first. Matching ignores case and accents, treats each word as a prefix and
+// tolerates small typos in titles and artist names.
+func (r *Repository) SearchTracks(query string, opts *listing.Options, limit, offset int) ([]models.TrackWithArtist, error) {
+	tracks, _, err := r.searchTracks(models.SearchFilter{Query: query}, opts, 0, limit, offset)
+	return tracks, err
+}
 
-	var tracks []models.TrackWithArtist
-	for rows.Next() {
-		var track models.TrackWithArtist
-		err := rows.Scan(
-			&track.ID,
-			&track.Title,
-			&track.ArtistID,
-			&track.FileURL,
-			&track.Duration,
-			&track.CoverImageURL,
-			&track.Genre,
-			&track.Lyrics,
-			&track.QualityBitrate,
-			&track.Status,
-			&track.CreatedAt,
-			&track.UpdatedAt,
-			&track.ArtistName,
-		)
-		if err != nil {
-			return nil, err
-		}
-		tracks = append(tracks, track)
-	}
-	return tracks, nil
+// SearchTracksWithFavorites searches tracks, flagging the ones the user has liked
+func (r *Repository) SearchTracksWithFavorites(query string, opts *listing.Options, userID int, limit, offset int) ([]models.TrackWithArtist, error) {
+	tracks, _, err := r.searchTracks(models.SearchFilter{Query: query}, opts, userID, limit, offset)
+	return tracks, err
 }
 
-// SearchTracksWithFavorites searches for tracks with favorite status for a specific user
-func (r *Repository) SearchTracksWithFavorites(query string, userID int, limit, offset int) ([]models.TrackWithArtist, error) {
+// searchTracks ranks matches by ts_rank over the weighted search vector (title, then artist,
+// genre and lyrics), unless opts sorts them otherwise, and returns them with highlighted
+// fragments, along with the rank of the first one
+func (r *Repository) searchTracks(filter models.SearchFilter, opts *listing.Options, userID int, limit, offset int) ([]models.TrackWithArtist, float64, error) {
+	args := []interface{}{filter.Query, filter.Genre, filter.Year, userID, limit, offset, headlineOptions, snippetOptions}
+	where, orderBy, args := opts.Compile(args, "rank DESC, t.created_at DESC, t.id DESC")
+
+	// Trigram similarity lets close misspellings rank just below exact matches
 	sqlQuery := `
-		SELECT t.id, t.title, t.artist_id, t.file_url, 
-		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''), 
-		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''), 
-		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'), 
+		WITH ranked AS (
+			SELECT t.id,
+			       ts_rank(t.search_vector, search_query($1))
+			         + 0.5 * word_similarity(search_normalize($1), search_normalize(t.title))
+			         + 0.3 * word_similarity(search_normalize($1), search_normalize(u.username)) AS rank
+			FROM tracks t
+			LEFT JOIN users u ON t.artist_id = u.id
+			WHERE ` + trackSearchMatch + ` AND ` + where + `
+			ORDER BY ` + orderBy + `
+			LIMIT $5 OFFSET $6
+		)
+		SELECT t.id, t.title, t.artist_id, t.file_url,
+		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''),
+		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''),
+		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'),
 		       t.created_at, t.updated_at,
 		       u.username as artist_name,
-		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited
-		FROM tracks t
+		       CASE WHEN l.user_id IS NOT NULL THEN true ELSE false END as is_favorited,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags,
+		       ts_headline('search_simple', t.title, search_query($1), $7),
+		       ts_headline('search_simple', COALESCE(u.username, ''), search_query($1), $7),
+		       ts_headline('search_simple', COALESCE(t.genre, ''), search_query($1), $7),
+		       CASE WHEN to_tsvector('search_simple', COALESCE(t.lyrics, '')) @@ search_query($1)
+		            THEN ts_headline('search_simple', t.lyrics, search_query($1), $8) END,
+		       ranked.rank
+		FROM ranked
+		JOIN tracks t ON t.id = ranked.id
 		LEFT JOIN users u ON t.artist_id = u.id
-		LEFT JOIN likes l ON t.id = l.track_id AND l.user_id = $2
-		WHERE t.status = 'published' AND (
-			t.title ILIKE '%' || $1 || '%' OR 
-			u.username ILIKE '%' || $1 || '%' OR
-			t.genre ILIKE '%' || $1 || '%'
-		)
-		ORDER BY t.created_at DESC
-		LIMIT $3 OFFSET $4
+		LEFT JOIN likes l ON t.id = l.track_id AND l.user_id = $4
+		ORDER BY ` + orderBy + ` -- joins don't keep the page's order
 	`
 
-	rows, err := r.Db.Query(sqlQuery, query, userID, limit, offset)
+	rows, err := r.Db.Query(sqlQuery, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var tracks []models.TrackWithArtist
+	var bestRank float64
 	for rows.Next() {
 		var track models.TrackWithArtist
+		var title, artist, genre, lyrics *string
+		var rank float64
 		err := rows.Scan(
 			&track.ID,
 			&track.Title,
@@ -259,13 +216,29 @@ func (r *Repository) SearchTracksWithFavorites(query string, userID int, limit,
 			&track.UpdatedAt,
 			&track.ArtistName,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
+			&title,
+			&artist,
+			&genre,
+			&lyrics,
+			&rank,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
+		}
+		track.Highlights = searchHighlights(map[string]*string{
+			"title":       title,
+			"artist_name": artist,
+			"genre":       genre,
+			"lyrics":      lyrics,
+		})
+		if tracks == nil {
+			bestRank = rank
 		}
 		tracks = append(tracks, track)
 	}
-	return tracks, nil
+	return tracks, bestRank, rows.Err()
 }
 
 // GetTracksByArtistID retrieves all tracks uploaded by a specific artist
@@ -276,7 +249,9 @@ func (r *Repository) GetTracksByArtistID(artistID, limit, offset int) ([]models.
 		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''), 
 		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'), 
 		       t.created_at, t.updated_at,
-		       u.username as artist_name
+		       u.username as artist_name,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		LEFT JOIN users u ON t.artist_id = u.id
 		WHERE t.artist_id = $1
@@ -307,6 +282,8 @@ func (r *Repository) GetTracksByArtistID(artistID, limit, offset int) ([]models.
 			&track.CreatedAt,
 			&track.UpdatedAt,
 			&track.ArtistName,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		)
 		if err != nil {
 			return nil, err
@@ -318,13 +295,13 @@ func (r *Repository) GetTracksByArtistID(artistID, limit, offset int) ([]models.
 }
 
 // UpdateTrack updates a track's details (title, genre, cover_image_url)
-func (r *Repository) UpdateTrack(trackID int, title string, genre, coverImageURL *string) error {
+func (r *Repository) UpdateTrack(trackID int, title string, genre *string, genreID *int, coverImageURL *string) error {
 	query := `
 		UPDATE tracks 
-		SET title = $1, genre = $2, cover_image_url = $3, updated_at = NOW()
-		WHERE id = $4
+		SET title = $1, genre = $2, genre_id = $3, cover_image_url = $4, updated_at = NOW()
+		WHERE id = $5
 	`
-	result, err := r.Db.Exec(query, title, genre, coverImageURL, trackID)
+	result, err := r.Db.Exec(query, title, genre, genreID, coverImageURL, trackID)
 	if err != nil {
 		return err
 	}
@@ -355,6 +332,36 @@ func (r *Repository) DeleteTrack(trackID int) error {
 	return nil
 }
 
+// UpdateTrackStatus sets the status of a track (e.g. published, taken_down)
+func (r *Repository) UpdateTrackStatus(trackID int, status string) error {
+	result, err := r.Db.Exec(`UPDATE tracks SET status = $1, updated_at = NOW() WHERE id = $2`, status, trackID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PublishDraftTrack publishes a draft track and reports whether it was a draft. Taken
+// down tracks are left alone.
+func (r *Repository) PublishDraftTrack(trackID int) (bool, error) {
+	result, err := r.Db.Exec(`UPDATE tracks SET status = 'published', updated_at = NOW() WHERE id = $1 AND status = 'draft'`, trackID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
 // GetTrackOwner returns the artist_id (owner) of a track
 func (r *Repository) GetTrackOwner(trackID int) (int, error) {
 	var artistID int
@@ -400,7 +407,9 @@ func (r *Repository) GetUserFavoriteTracks(userID int, limit, offset int) ([]mod
 		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''),
 		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'),
 		       t.created_at, t.updated_at,
-		       true as is_favorited
+		       true as is_favorited,
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM likes l
 		JOIN tracks t ON l.track_id = t.id
 		JOIN users u ON t.artist_id = u.id
@@ -423,6 +432,8 @@ func (r *Repository) GetUserFavoriteTracks(userID int, limit, offset int) ([]mod
 			&track.Genre, &track.Lyrics, &track.QualityBitrate,
 			&track.Status, &track.CreatedAt, &track.UpdatedAt,
 			&track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		); err != nil {
 			slog.Error("Failed to scan track", "error", err)
 			continue
@@ -452,7 +463,9 @@ func (r *Repository) GetTracksByIDWithFavorites(userID int, ids []int) ([]models
 		       COALESCE(t.duration, 0), COALESCE(t.cover_image_url, ''),
 		       COALESCE(t.genre, ''), COALESCE(t.lyrics, ''),
 		       COALESCE(t.quality_bitrate, 0), COALESCE(t.status, 'published'),
-		       t.created_at, t.updated_at, COALESCE(EXISTS(SELECT 1 FROM likes WHERE user_id = $1 AND track_id = t.id), false)
+		       t.created_at, t.updated_at, COALESCE(EXISTS(SELECT 1 FROM likes WHERE user_id = $1 AND track_id = t.id), false),
+		       (SELECT COUNT(*) FROM comments cm WHERE cm.track_id = t.id) as comment_count,
+		       ARRAY(SELECT tg.name FROM track_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.track_id = t.id ORDER BY tg.name) as tags
 		FROM tracks t
 		JOIN users u ON t.artist_id = u.id
 		WHERE t.id IN (%s)
@@ -472,6 +485,8 @@ func (r *Repository) GetTracksByIDWithFavorites(userID int, ids []int) ([]models
 			&track.FileURL, &track.Duration, &track.CoverImageURL,
 			&track.Genre, &track.Lyrics, &track.QualityBitrate,
 			&track.Status, &track.CreatedAt, &track.UpdatedAt, &track.IsFavorited,
+			&track.CommentCount,
+			pq.Array(&track.Tags),
 		); err != nil {
 			slog.Error("Failed to scan track", "error", err)
 			continue
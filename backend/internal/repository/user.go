@@ -5,14 +5,27 @@ import (
 	utils "music-app/backend/internal/utils"
 )
 
+// CreateUser registers a user as a listener. Other roles are only granted through the
+// admin role endpoints.
 func (r *Repository) CreateUser(user *models.RegisterRequest) error {
-	role := user.Role
-	if role != "admin" {
-		role = "user"
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return err
 	}
-	query := "INSERT INTO users (email, username, password_hash, role) VALUES ($1, $2, $3, $4)"
-	_, err := r.Db.Exec(query, user.Email, user.Username, user.Password, role)
-	return err
+	defer tx.Rollback()
+
+	var userID int
+	query := "INSERT INTO users (email, username, password_hash) VALUES ($1, $2, $3) RETURNING id"
+	if err := tx.QueryRow(query, user.Email, user.Username, user.Password).Scan(&userID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO user_roles (user_id, role_id) SELECT $1, id FROM roles WHERE name = $2", userID, models.RoleListener)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *Repository) CheckLogin(email, password string) (*models.User, error) {
@@ -88,14 +101,6 @@ func (r *Repository) CheckUsernameExists(username string, excludeUserID int) (bo
 	return count > 0, err
 }
 
-func (r *Repository) GetUserRoleByID(userID int) (string, error) {
-	query := "SELECT role FROM users WHERE id=$1"
-	row := r.Db.QueryRow(query, userID)
-	var role string
-	err := row.Scan(&role)
-	return role, err
-}
-
 func (r *Repository) GetAllUsers() ([]models.User, error) {
 	query := "SELECT id, email, username, avatar_url, role, created_at, updated_at FROM users ORDER BY username ASC"
 	rows, err := r.Db.Query(query)
@@ -0,0 +1,280 @@
+// Package smartplaylist validates smart playlist rules and compiles them to SQL. Rules only
+// ever name fields and operators from fixed allowlists; every value is passed as a query
+// argument, so user input never becomes part of the SQL text.
+package smartplaylist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"music-app/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// Rule limits
+const (
+	MaxConditions = 50
+	MaxDepth      = 4
+	MaxInValues   = 50
+	MaxTextLength = 200
+	MaxDays       = 36500
+	DefaultLimit  = 100
+	MaxLimit      = 500
+)
+
+// MaxAge is how long the cached tracks of a smart playlist are served before its rules are
+// evaluated again
+const MaxAge = time.Hour
+
+var ErrInvalidRules = errors.New("invalid smart playlist rules")
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindNumber
+	kindBool
+	kindDate
+)
+
+type field struct {
+	kind fieldKind
+	expr string
+}
+
+// fields maps rule field names to SQL over the tracks query built by Compile. "my_" fields,
+// liked and last_played are relative to the playlist owner.
+var fields = map[string]field{
+	"title":         {kindText, "t.title"},
+	"artist":        {kindText, "u.username"},
+	"genre":         {kindText, "t.genre"},
+	"duration":      {kindNumber, "COALESCE(t.duration, 0)"},
+	"play_count":    {kindNumber, "COALESCE(pc.play_count, 0)"},
+	"my_play_count": {kindNumber, "COALESCE(up.play_count, 0)"},
+	"liked":         {kindBool, "EXISTS (SELECT 1 FROM likes lk WHERE lk.track_id = t.id AND lk.user_id = $1)"},
+	"last_played":   {kindDate, "up.last_played"},
+	"created_at":    {kindDate, "t.created_at"},
+}
+
+var operators = map[fieldKind][]string{
+	kindText:   {"eq", "neq", "contains", "not_contains", "starts_with", "in", "not_in"},
+	kindNumber: {"eq", "neq", "gt", "gte", "lt", "lte"},
+	kindBool:   {"eq"},
+	kindDate:   {"in_last_days", "not_in_last_days"},
+}
+
+var comparisons = map[string]string{"eq": "=", "neq": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+// sortFields are the fields a smart playlist can be ordered by, besides "random"
+var sortFields = []string{"title", "artist", "genre", "duration", "play_count", "my_play_count", "last_played", "created_at"}
+
+// Validate checks rules without compiling them
+func Validate(rules *models.SmartPlaylistRules) error {
+	_, _, err := Compile(rules, 0)
+	return err
+}
+
+// Compile turns rules into a query returning the IDs of matching published tracks in
+// playlist order. ownerID is the user that liked and played conditions refer to.
+func Compile(rules *models.SmartPlaylistRules, ownerID int) (string, []interface{}, error) {
+	if rules == nil {
+		return "", nil, fmt.Errorf("%w: rules are required", ErrInvalidRules)
+	}
+
+	c := &compiler{args: []interface{}{ownerID}}
+	where, err := c.group(rules.Match, rules.Conditions, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.conditions > MaxConditions {
+		return "", nil, fmt.Errorf("%w: at most %d conditions are allowed", ErrInvalidRules, MaxConditions)
+	}
+
+	orderBy, err := sortClause(rules.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+
+	limit := rules.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if limit < 1 || limit > MaxLimit {
+		return "", nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRules, MaxLimit)
+	}
+
+	query := `
+		SELECT t.id
+		FROM tracks t
+		JOIN users u ON t.artist_id = u.id
+		LEFT JOIN (
+			SELECT track_id, COUNT(*) AS play_count FROM listens GROUP BY track_id
+		) pc ON pc.track_id = t.id
+		LEFT JOIN (
+			SELECT track_id, COUNT(*) AS play_count, MAX(timestamp) AS last_played
+			FROM listens WHERE user_id = $1 GROUP BY track_id
+		) up ON up.track_id = t.id
+		WHERE t.status = 'published' AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ` + c.arg(limit)
+	return query, c.args, nil
+}
+
+type compiler struct {
+	args       []interface{}
+	conditions int
+}
+
+// arg adds a query argument and returns its placeholder
+func (c *compiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+func (c *compiler) group(match string, conditions []models.RuleCondition, depth int) (string, error) {
+	if depth > MaxDepth {
+		return "", fmt.Errorf("%w: groups may be nested at most %d levels deep", ErrInvalidRules, MaxDepth)
+	}
+
+	joiner := " AND "
+	switch match {
+	case "", models.RuleMatchAll:
+	case models.RuleMatchAny:
+		joiner = " OR "
+	default:
+		return "", fmt.Errorf("%w: match must be all or any", ErrInvalidRules)
+	}
+	if len(conditions) == 0 {
+		if depth > 1 {
+			return "", fmt.Errorf("%w: groups must have at least one condition", ErrInvalidRules)
+		}
+		return "TRUE", nil
+	}
+
+	parts := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		c.conditions++
+		var sql string
+		var err error
+		if condition.Conditions != nil {
+			sql, err = c.group(condition.Match, condition.Conditions, depth+1)
+		} else {
+			sql, err = c.condition(condition)
+		}
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, sql)
+	}
+	return "(" + strings.Join(parts, joiner) + ")", nil
+}
+
+func (c *compiler) condition(condition models.RuleCondition) (string, error) {
+	f, ok := fields[condition.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidRules, condition.Field)
+	}
+	if !slices.Contains(operators[f.kind], condition.Op) {
+		return "", fmt.Errorf("%w: %s supports %s", ErrInvalidRules, condition.Field, strings.Join(operators[f.kind], ", "))
+	}
+	if len(condition.Value) == 0 {
+		return "", fmt.Errorf("%w: %s needs a value", ErrInvalidRules, condition.Field)
+	}
+
+	switch f.kind {
+	case kindText:
+		if condition.Op == "in" || condition.Op == "not_in" {
+			var values []string
+			if err := json.Unmarshal(condition.Value, &values); err != nil || len(values) == 0 || len(values) > MaxInValues {
+				return "", fmt.Errorf("%w: %s %s needs a list of 1 to %d strings", ErrInvalidRules, condition.Field, condition.Op, MaxInValues)
+			}
+			lowered := make(pq.StringArray, len(values))
+			for i, v := range values {
+				lowered[i] = strings.ToLower(strings.TrimSpace(v))
+			}
+			sql := "LOWER(" + f.expr + ") = ANY(" + c.arg(lowered) + ")"
+			if condition.Op == "not_in" {
+				sql = "NOT COALESCE(" + sql + ", FALSE)"
+			}
+			return sql, nil
+		}
+
+		var value string
+		if err := json.Unmarshal(condition.Value, &value); err != nil || strings.TrimSpace(value) == "" || len(value) > MaxTextLength {
+			return "", fmt.Errorf("%w: %s needs a text value of up to %d characters", ErrInvalidRules, condition.Field, MaxTextLength)
+		}
+		value = strings.TrimSpace(value)
+		switch condition.Op {
+		case "eq":
+			return "LOWER(" + f.expr + ") = LOWER(" + c.arg(value) + ")", nil
+		case "neq":
+			return "LOWER(COALESCE(" + f.expr + ", '')) <> LOWER(" + c.arg(value) + ")", nil
+		case "contains":
+			return f.expr + " ILIKE " + c.arg("%"+EscapeLike(value)+"%"), nil
+		case "not_contains":
+			return "COALESCE(" + f.expr + ", '') NOT ILIKE " + c.arg("%"+EscapeLike(value)+"%"), nil
+		default: // starts_with
+			return f.expr + " ILIKE " + c.arg(EscapeLike(value)+"%"), nil
+		}
+
+	case kindNumber:
+		var value float64
+		if err := json.Unmarshal(condition.Value, &value); err != nil {
+			return "", fmt.Errorf("%w: %s needs a number", ErrInvalidRules, condition.Field)
+		}
+		return f.expr + " " + comparisons[condition.Op] + " " + c.arg(value), nil
+
+	case kindBool:
+		var value bool
+		if err := json.Unmarshal(condition.Value, &value); err != nil {
+			return "", fmt.Errorf("%w: %s needs true or false", ErrInvalidRules, condition.Field)
+		}
+		if value {
+			return f.expr, nil
+		}
+		return "NOT " + f.expr, nil
+
+	default: // kindDate
+		var days int
+		if err := json.Unmarshal(condition.Value, &days); err != nil || days < 1 || days > MaxDays {
+			return "", fmt.Errorf("%w: %s needs a number of days between 1 and %d", ErrInvalidRules, condition.Field, MaxDays)
+		}
+		since := "NOW() - make_interval(days => " + c.arg(days) + ")"
+		if condition.Op == "in_last_days" {
+			return f.expr + " >= " + since, nil
+		}
+		return "(" + f.expr + " IS NULL OR " + f.expr + " < " + since + ")", nil
+	}
+}
+
+func sortClause(sort *models.RuleSort) (string, error) {
+	if sort == nil {
+		return "t.created_at DESC, t.id", nil
+	}
+	if sort.Field == "random" {
+		return "RANDOM()", nil
+	}
+	if !slices.Contains(sortFields, sort.Field) {
+		return "", fmt.Errorf("%w: sort field must be one of random, %s", ErrInvalidRules, strings.Join(sortFields, ", "))
+	}
+
+	direction := "DESC"
+	switch sort.Direction {
+	case "", "desc":
+	case "asc":
+		direction = "ASC"
+	default:
+		return "", fmt.Errorf("%w: sort direction must be asc or desc", ErrInvalidRules)
+	}
+	return fields[sort.Field].expr + " " + direction + " NULLS LAST, t.id", nil
+}
+
+// EscapeLike escapes the LIKE wildcards in a literal value, so it only matches itself
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,183 @@
+// Package suggest answers search-as-you-type queries from an in-memory prefix index of
+// track, artist and album names. The index is rebuilt from the catalog on a schedule, so
+// lookups never touch the database.
+package suggest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"music-app/backend/internal/matching"
+	"music-app/backend/internal/models"
+	"music-app/backend/internal/repository"
+)
+
+const (
+	// maxKeyLength is how many characters of a name, from each word start, are indexed.
+	// Longer queries are narrowed down from the candidates of their first maxKeyLength
+	// characters.
+	maxKeyLength = 24
+	// candidatesPerNode is how many of the most popular names each prefix keeps
+	candidatesPerNode = 32
+)
+
+// Bonuses added to the popularity score, which ranges from 0 to 1, when the query matches
+// the start of a name or the whole name rather than a later word
+const (
+	leadingMatchBonus = 0.5
+	exactMatchBonus   = 1.0
+)
+
+// Index is a prefix index of catalog names, safe for concurrent use. Until the first
+// Refresh it returns no suggestions.
+type Index struct {
+	Db   *sql.DB
+	trie atomic.Pointer[trie]
+}
+
+func NewIndex(db *sql.DB) *Index {
+	return &Index{Db: db}
+}
+
+type trie struct {
+	root    *node
+	entries []entry
+}
+
+type node struct {
+	children map[rune]*node
+	// indexes into trie.entries of the names with this prefix at a word start, most
+	// popular first
+	candidates []int
+}
+
+type entry struct {
+	suggestion models.SearchSuggestion
+	key        string // the normalized name
+	popularity float64
+}
+
+// Refresh rebuilds the index from the catalog and swaps it in
+func (idx *Index) Refresh(ctx context.Context) error {
+	repo := repository.NewRepository(idx.Db)
+	suggestions, err := repo.GetSearchSuggestionSources()
+	if err != nil {
+		return fmt.Errorf("failed to load suggestion sources: %w", err)
+	}
+
+	idx.trie.Store(build(suggestions))
+	slog.Debug("Rebuilt search suggestion index", "names", len(suggestions))
+	return nil
+}
+
+// build indexes every name under each of its word starts, so that "beat" suggests
+// "The Beatles". Names are inserted most popular first, which keeps each node's
+// candidates in popularity order without sorting.
+func build(suggestions []models.SearchSuggestion) *trie {
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Listens > suggestions[j].Listens })
+
+	maxListens := 0
+	if len(suggestions) > 0 {
+		maxListens = suggestions[0].Listens
+	}
+
+	t := &trie{root: &node{}}
+	for _, suggestion := range suggestions {
+		key := matching.Normalize(suggestion.Text)
+		if key == "" {
+			continue
+		}
+
+		// Log scale, so a hit does not drown out an exact match on a lesser known name
+		popularity := 0.0
+		if maxListens > 0 {
+			popularity = math.Log1p(float64(suggestion.Listens)) / math.Log1p(float64(maxListens))
+		}
+
+		i := len(t.entries)
+		t.entries = append(t.entries, entry{suggestion: suggestion, key: key, popularity: popularity})
+
+		runes := []rune(key)
+		for start := range runes {
+			if start > 0 && runes[start-1] != ' ' {
+				continue
+			}
+			t.insert(runes[start:min(len(runes), start+maxKeyLength)], i)
+		}
+	}
+	return t
+}
+
+func (t *trie) insert(key []rune, i int) {
+	n := t.root
+	for _, r := range key {
+		child := n.children[r]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+
+		// A name reaches a node twice when two of its words share a prefix
+		if len(n.candidates) < candidatesPerNode && (len(n.candidates) == 0 || n.candidates[len(n.candidates)-1] != i) {
+			n.candidates = append(n.candidates, i)
+		}
+	}
+}
+
+// Lookup returns up to limit names with a word starting with query, ranked by popularity
+// and favouring names that start with or equal the query
+func (idx *Index) Lookup(query string, limit int) []models.SearchSuggestion {
+	t := idx.trie.Load()
+	key := matching.Normalize(query)
+	if t == nil || key == "" {
+		return []models.SearchSuggestion{}
+	}
+
+	runes := []rune(key)
+	n := t.root
+	for _, r := range runes[:min(len(runes), maxKeyLength)] {
+		if n = n.children[r]; n == nil {
+			return []models.SearchSuggestion{}
+		}
+	}
+
+	type scored struct {
+		entry *entry
+		score float64
+	}
+	var matches []scored
+	for _, i := range n.candidates {
+		e := &t.entries[i]
+		if len(runes) > maxKeyLength && !strings.HasPrefix(e.key, key) && !strings.Contains(e.key, " "+key) {
+			continue
+		}
+		score := e.popularity
+		switch {
+		case e.key == key:
+			score += exactMatchBonus
+		case strings.HasPrefix(e.key, key):
+			score += leadingMatchBonus
+		}
+		matches = append(matches, scored{entry: e, score: score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	suggestions := []models.SearchSuggestion{}
+	for _, m := range matches {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, m.entry.suggestion)
+	}
+	return suggestions
+}
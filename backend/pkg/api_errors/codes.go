@@ -23,10 +23,20 @@ const (
 	ErrValidationError = "VALIDATION_ERROR"
 
 	// Resource errors
-	ErrNotFound       = "NOT_FOUND"
-	ErrTrackNotFound  = "TRACK_NOT_FOUND"
-	ErrAlbumNotFound  = "ALBUM_NOT_FOUND"
-	ErrArtistNotFound = "ARTIST_NOT_FOUND"
+	ErrNotFound             = "NOT_FOUND"
+	ErrTrackNotFound        = "TRACK_NOT_FOUND"
+	ErrAlbumNotFound        = "ALBUM_NOT_FOUND"
+	ErrArtistNotFound       = "ARTIST_NOT_FOUND"
+	ErrRoleNotFound         = "ROLE_NOT_FOUND"
+	ErrPermissionNotFound   = "PERMISSION_NOT_FOUND"
+	ErrCommentNotFound      = "COMMENT_NOT_FOUND"
+	ErrNotificationNotFound = "NOTIFICATION_NOT_FOUND"
+	ErrImportJobNotFound    = "IMPORT_JOB_NOT_FOUND"
+	ErrFolderNotFound       = "FOLDER_NOT_FOUND"
+	ErrTagNotFound          = "TAG_NOT_FOUND"
+	ErrTagAlreadyExists     = "TAG_ALREADY_EXISTS"
+	ErrGenreNotFound        = "GENRE_NOT_FOUND"
+	ErrGenreAlreadyExists   = "GENRE_ALREADY_EXISTS"
 
 	// Server errors
 	ErrInternalServer     = "INTERNAL_SERVER_ERROR"
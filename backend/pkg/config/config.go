@@ -18,6 +18,15 @@ type Config struct {
 	MinioSecretKey  string
 	MinioBucketName string
 	MinioUseSSL     bool
+
+	// Account lifecycle
+	AccountDeletionGraceDays     int
+	DeletedAccountTrackPolicy    string // "delete" or "transfer"
+	DeletedAccountTransferUserID int
+	DataExportTTLHours           int
+
+	// Deleted playlists stay recoverable for this long before they are purged
+	DeletedPlaylistRetentionDays int
 }
 
 func Load() (*Config, error) {
@@ -32,6 +41,13 @@ func Load() (*Config, error) {
 		MinioSecretKey:  os.Getenv("MINIO_SECRET_KEY"),
 		MinioBucketName: os.Getenv("MINIO_BUCKET_NAME"),
 		MinioUseSSL:     getEnvAsBool("MINIO_USE_SSL", false),
+
+		AccountDeletionGraceDays:     getEnvAsInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
+		DeletedAccountTrackPolicy:    getEnv("DELETED_ACCOUNT_TRACK_POLICY", "delete"),
+		DeletedAccountTransferUserID: getEnvAsInt("DELETED_ACCOUNT_TRANSFER_USER_ID", 0),
+		DataExportTTLHours:           getEnvAsInt("DATA_EXPORT_TTL_HOURS", 168),
+
+		DeletedPlaylistRetentionDays: getEnvAsInt("DELETED_PLAYLIST_RETENTION_DAYS", 30),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -53,6 +69,13 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("MINIO_BUCKET_NAME is required")
 	}
 
+	if cfg.DeletedAccountTrackPolicy != "delete" && cfg.DeletedAccountTrackPolicy != "transfer" {
+		return nil, fmt.Errorf("DELETED_ACCOUNT_TRACK_POLICY must be \"delete\" or \"transfer\"")
+	}
+	if cfg.DeletedAccountTrackPolicy == "transfer" && cfg.DeletedAccountTransferUserID == 0 {
+		return nil, fmt.Errorf("DELETED_ACCOUNT_TRANSFER_USER_ID is required when tracks are transferred")
+	}
+
 	return cfg, nil
 }
 
@@ -68,9 +91,9 @@ func getEnvAsInt(key string, fallback int) int {
 		if i, err := strconv.Atoi(value); err == nil {
 			return i
 		}
-		slog.Warn("Invalid integer value in environment variable, using default", 
-			"key", key, 
-			"value", value, 
+		slog.Warn("Invalid integer value in environment variable, using default",
+			"key", key,
+			"value", value,
 			"default", fallback)
 	}
 	return fallback
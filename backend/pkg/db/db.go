@@ -14,20 +14,45 @@ func InitDB(dbURL string) *sql.DB {
 		log.Fatalf("Failed to connect to TimescaleDB: %s", err)
 	}
 
-	// Ensure album_tracks table exists (Temporary migration)
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS "album_tracks" (
-			"id" SERIAL PRIMARY KEY,
-			"album_id" INT NOT NULL,
-			"track_id" INT NOT NULL,
-			UNIQUE ("album_id", "track_id"),
-			FOREIGN KEY ("album_id") REFERENCES "albums" ("id") ON DELETE CASCADE,
-			FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE
+	// Bring databases created from an older db.sql up to date (Temporary migrations)
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS "schema_migrations" (
+			"name" VARCHAR(100) PRIMARY KEY,
+			"applied_at" TIMESTAMP DEFAULT (NOW())
 		);
-	`)
-	if err != nil {
-		log.Printf("Warning: Failed to ensure album_tracks table exists: %v", err)
+	`); err != nil {
+		log.Printf("Warning: Failed to ensure schema_migrations table exists: %v", err)
+		return db
+	}
+	for _, m := range migrations {
+		if err := applyMigration(db, m); err != nil {
+			log.Printf("Warning: Failed to apply migration %q: %v", m.name, err)
+		}
 	}
 
 	return db
 }
+
+// applyMigration runs a migration unless schema_migrations records it as applied, and
+// records it in the same transaction. Recording it first makes another instance starting
+// at the same time wait for this one, then skip the migration.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, m.name)
+	if err != nil {
+		return err
+	}
+	if recorded, err := result.RowsAffected(); err != nil || recorded == 0 {
+		return err
+	}
+
+	if _, err := tx.Exec(m.query); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
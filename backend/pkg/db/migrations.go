@@ -0,0 +1,673 @@
+package db
+
+// migration is a schema change or data backfill that runs once, in order, and is then
+// recorded in schema_migrations. Databases created from db.sql already have the schema and
+// seeds, and run every migration once on their first startup, so migrations must tolerate
+// finding their changes in place. Keep db.sql in sync so that fresh installs get the same
+// schema.
+type migration struct {
+	name  string
+	query string
+}
+
+var migrations = []migration{
+	{
+		name: "album_tracks",
+		query: `
+			CREATE TABLE IF NOT EXISTS "album_tracks" (
+				"id" SERIAL PRIMARY KEY,
+				"album_id" INT NOT NULL,
+				"track_id" INT NOT NULL,
+				UNIQUE ("album_id", "track_id"),
+				FOREIGN KEY ("album_id") REFERENCES "albums" ("id") ON DELETE CASCADE,
+				FOREIGN KEY ("track_id") REFERENCES "tracks" ("id") ON DELETE CASCADE
+			);
+		`,
+	},
+	{
+		name: "roles_and_permissions",
+		query: `
+			CREATE TABLE IF NOT EXISTS "roles" (
+				"id" SERIAL PRIMARY KEY,
+				"name" VARCHAR(50) UNIQUE NOT NULL,
+				"description" TEXT,
+				"created_at" TIMESTAMP DEFAULT (NOW())
+			);
+
+			CREATE TABLE IF NOT EXISTS "permissions" (
+				"id" SERIAL PRIMARY KEY,
+				"name" VARCHAR(100) UNIQUE NOT NULL,
+				"description" TEXT
+			);
+
+			CREATE TABLE IF NOT EXISTS "role_permissions" (
+				"role_id" INT NOT NULL REFERENCES "roles" ("id") ON DELETE CASCADE,
+				"permission_id" INT NOT NULL REFERENCES "permissions" ("id") ON DELETE CASCADE,
+				PRIMARY KEY ("role_id", "permission_id")
+			);
+
+			CREATE TABLE IF NOT EXISTS "user_roles" (
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"role_id" INT NOT NULL REFERENCES "roles" ("id") ON DELETE CASCADE,
+				"granted_by" INT REFERENCES "users" ("id") ON DELETE SET NULL,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				PRIMARY KEY ("user_id", "role_id")
+			);
+		`,
+	},
+	{
+		name: "seed_roles_and_permissions",
+		query: `
+			INSERT INTO roles (name, description) VALUES
+				('listener', 'Regular listener'),
+				('artist', 'Uploads and publishes their own tracks'),
+				('moderator', 'Moderates community content and takes down tracks'),
+				('label_manager', 'Manages tracks and albums on behalf of artists'),
+				('admin', 'Full administrative access')
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO permissions (name, description) VALUES
+				('tracks.upload', 'Upload new tracks'),
+				('tracks.publish', 'Publish tracks to the catalog'),
+				('tracks.manage_all', 'Manage tracks owned by other users'),
+				('tracks.takedown', 'Take down tracks from the catalog'),
+				('albums.manage', 'Create and edit albums'),
+				('comments.moderate', 'Delete comments written by other users'),
+				('roles.manage', 'Assign roles and permissions'),
+				('admin.dashboard', 'View the admin dashboard')
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT r.id, p.id
+			FROM roles r
+			JOIN permissions p ON (
+				(r.name = 'artist' AND p.name IN ('tracks.upload', 'tracks.publish')) OR
+				(r.name = 'moderator' AND p.name IN ('tracks.takedown', 'comments.moderate', 'admin.dashboard')) OR
+				(r.name = 'label_manager' AND p.name IN ('tracks.upload', 'tracks.publish', 'tracks.manage_all', 'albums.manage')) OR
+				r.name = 'admin'
+			)
+			WHERE NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role_id = r.id)
+			ON CONFLICT DO NOTHING;
+
+			-- Backfill users that predate user_roles from the legacy users.role column
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT u.id, r.id
+			FROM users u
+			JOIN roles r ON (
+				r.name = CASE WHEN u.role = 'admin' THEN 'admin' ELSE 'listener' END OR
+				(r.name = 'artist' AND EXISTS (SELECT 1 FROM tracks t WHERE t.artist_id = u.id))
+			)
+			WHERE NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)
+			ON CONFLICT DO NOTHING;
+		`,
+	},
+	{
+		name: "account_lifecycle",
+		query: `
+			CREATE TABLE IF NOT EXISTS "data_export_jobs" (
+				"id" SERIAL PRIMARY KEY,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"status" VARCHAR(20) NOT NULL DEFAULT 'pending',
+				"object_name" TEXT,
+				"error" TEXT,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				"completed_at" TIMESTAMP,
+				"expires_at" TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS "data_export_jobs_user_id_idx" ON "data_export_jobs" ("user_id");
+
+			CREATE TABLE IF NOT EXISTS "account_deletions" (
+				"user_id" INT PRIMARY KEY REFERENCES "users" ("id") ON DELETE CASCADE,
+				"requested_at" TIMESTAMP DEFAULT (NOW()),
+				"scheduled_for" TIMESTAMP NOT NULL
+			);
+		`,
+	},
+	{
+		name: "comment_threads",
+		query: `
+			ALTER TABLE "comments" ADD COLUMN IF NOT EXISTS "parent_id" INT REFERENCES "comments" ("id") ON DELETE CASCADE;
+			ALTER TABLE "comments" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP;
+
+			CREATE INDEX IF NOT EXISTS "comments_track_id_created_at_idx" ON "comments" ("track_id", "created_at");
+			CREATE INDEX IF NOT EXISTS "comments_parent_id_idx" ON "comments" ("parent_id");
+		`,
+	},
+	{
+		name: "timestamped_comments",
+		query: `
+			ALTER TABLE "comments" ADD COLUMN IF NOT EXISTS "position_ms" INT;
+			ALTER TABLE "comments" ADD COLUMN IF NOT EXISTS "resolved" BOOLEAN NOT NULL DEFAULT false;
+			ALTER TABLE "comments" ADD COLUMN IF NOT EXISTS "resolved_at" TIMESTAMP;
+
+			CREATE INDEX IF NOT EXISTS "comments_track_id_position_ms_idx" ON "comments" ("track_id", "position_ms") WHERE "position_ms" IS NOT NULL;
+		`,
+	},
+	{
+		name: "follows",
+		query: `
+			CREATE TABLE IF NOT EXISTS "follows" (
+				"follower_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"followee_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				PRIMARY KEY ("follower_id", "followee_id"),
+				CHECK ("follower_id" <> "followee_id")
+			);
+
+			CREATE INDEX IF NOT EXISTS "follows_followee_id_idx" ON "follows" ("followee_id");
+		`,
+	},
+	{
+		name: "notifications",
+		query: `
+			CREATE TABLE IF NOT EXISTS "notifications" (
+				"id" SERIAL PRIMARY KEY,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"type" VARCHAR(50) NOT NULL,
+				"actor_id" INT REFERENCES "users" ("id") ON DELETE SET NULL,
+				"entity_type" VARCHAR(30),
+				"entity_id" INT,
+				"message" TEXT NOT NULL,
+				"read_at" TIMESTAMP,
+				"created_at" TIMESTAMP DEFAULT (NOW())
+			);
+
+			CREATE INDEX IF NOT EXISTS "notifications_user_id_id_idx" ON "notifications" ("user_id", "id");
+			CREATE INDEX IF NOT EXISTS "notifications_unread_idx" ON "notifications" ("user_id") WHERE "read_at" IS NULL;
+		`,
+	},
+	{
+		name: "playlist_collaborators",
+		query: `
+			CREATE TABLE IF NOT EXISTS "playlist_collaborators" (
+				"playlist_id" INT NOT NULL REFERENCES "playlists" ("id") ON DELETE CASCADE,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"role" VARCHAR(20) NOT NULL DEFAULT 'viewer',
+				"invited_by" INT REFERENCES "users" ("id") ON DELETE SET NULL,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				"accepted_at" TIMESTAMP,
+				PRIMARY KEY ("playlist_id", "user_id")
+			);
+
+			CREATE INDEX IF NOT EXISTS "playlist_collaborators_user_id_idx" ON "playlist_collaborators" ("user_id");
+
+			ALTER TABLE "playlist_tracks" ADD COLUMN IF NOT EXISTS "added_by" INT REFERENCES "users" ("id") ON DELETE SET NULL;
+			ALTER TABLE "playlist_tracks" ADD COLUMN IF NOT EXISTS "added_at" TIMESTAMP DEFAULT (NOW());
+		`,
+	},
+	{
+		name: "playlist_share_links",
+		query: `
+			CREATE TABLE IF NOT EXISTS "playlist_share_links" (
+				"id" SERIAL PRIMARY KEY,
+				"playlist_id" INT NOT NULL REFERENCES "playlists" ("id") ON DELETE CASCADE,
+				"token" VARCHAR(64) UNIQUE NOT NULL,
+				"created_by" INT REFERENCES "users" ("id") ON DELETE SET NULL,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				"revoked_at" TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS "playlist_share_links_playlist_id_idx" ON "playlist_share_links" ("playlist_id");
+		`,
+	},
+	{
+		name: "ordered_playlists",
+		query: `
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 0;
+
+			-- Keep the order users saw before positions existed: newest first for playlists,
+			-- insertion order for albums
+			ALTER TABLE "playlist_tracks" ADD COLUMN IF NOT EXISTS "position" INT;
+			UPDATE "playlist_tracks" pt SET "position" = o.rn
+			FROM (
+				SELECT "id", ROW_NUMBER() OVER (PARTITION BY "playlist_id" ORDER BY "id" DESC) - 1 AS rn
+				FROM "playlist_tracks"
+			) o
+			WHERE pt."id" = o."id" AND pt."position" IS NULL;
+			ALTER TABLE "playlist_tracks" ALTER COLUMN "position" SET NOT NULL;
+
+			ALTER TABLE "album_tracks" ADD COLUMN IF NOT EXISTS "position" INT;
+			UPDATE "album_tracks" at SET "position" = o.rn
+			FROM (
+				SELECT "id", ROW_NUMBER() OVER (PARTITION BY "album_id" ORDER BY "id" ASC) - 1 AS rn
+				FROM "album_tracks"
+			) o
+			WHERE at."id" = o."id" AND at."position" IS NULL;
+			ALTER TABLE "album_tracks" ALTER COLUMN "position" SET NOT NULL;
+
+			-- The same track may appear more than once in a playlist
+			DROP INDEX IF EXISTS "playlist_tracks_playlist_id_track_id_idx";
+			CREATE INDEX IF NOT EXISTS "playlist_tracks_playlist_id_position_idx" ON "playlist_tracks" ("playlist_id", "position");
+		`,
+	},
+	{
+		name: "import_jobs",
+		query: `
+			CREATE TABLE IF NOT EXISTS "import_jobs" (
+				"id" SERIAL PRIMARY KEY,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"source" VARCHAR(20) NOT NULL,
+				"filename" TEXT,
+				"object_name" TEXT,
+				"status" VARCHAR(20) NOT NULL DEFAULT 'pending',
+				"report" JSONB,
+				"error" TEXT,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				"completed_at" TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS "import_jobs_user_id_idx" ON "import_jobs" ("user_id");
+
+			ALTER TABLE "listens" ADD COLUMN IF NOT EXISTS "imported" BOOLEAN NOT NULL DEFAULT FALSE;
+			ALTER TABLE "listens" ADD COLUMN IF NOT EXISTS "import_job_id" INT REFERENCES "import_jobs" ("id") ON DELETE SET NULL;
+
+			-- Re-importing the same history must not count plays twice
+			CREATE UNIQUE INDEX IF NOT EXISTS "listens_imported_idx" ON "listens" ("user_id", "track_id", "timestamp") WHERE "imported";
+		`,
+	},
+	{
+		name: "smart_playlists",
+		query: `
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "rules" JSONB;
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "rules_refreshed_at" TIMESTAMP;
+
+			CREATE INDEX IF NOT EXISTS "playlists_smart_idx" ON "playlists" ("rules_refreshed_at") WHERE "rules" IS NOT NULL;
+		`,
+	},
+	{
+		name: "library",
+		query: `
+			CREATE TABLE IF NOT EXISTS "library_folders" (
+				"id" SERIAL PRIMARY KEY,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"parent_id" INT REFERENCES "library_folders" ("id") ON DELETE CASCADE,
+				"name" VARCHAR(255) NOT NULL,
+				"position" INT NOT NULL DEFAULT 0,
+				"pinned_at" TIMESTAMP,
+				"created_at" TIMESTAMP DEFAULT (NOW())
+			);
+
+			CREATE INDEX IF NOT EXISTS "library_folders_user_id_idx" ON "library_folders" ("user_id");
+
+			CREATE TABLE IF NOT EXISTS "library_playlists" (
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"playlist_id" INT NOT NULL REFERENCES "playlists" ("id") ON DELETE CASCADE,
+				"folder_id" INT REFERENCES "library_folders" ("id") ON DELETE SET NULL,
+				"position" INT,
+				"pinned_at" TIMESTAMP,
+				PRIMARY KEY ("user_id", "playlist_id")
+			);
+
+			CREATE TABLE IF NOT EXISTS "saved_albums" (
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"album_id" INT NOT NULL REFERENCES "albums" ("id") ON DELETE CASCADE,
+				"pinned_at" TIMESTAMP,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				PRIMARY KEY ("user_id", "album_id")
+			);
+
+			ALTER TABLE "follows" ADD COLUMN IF NOT EXISTS "pinned_at" TIMESTAMP;
+		`,
+	},
+	{
+		name: "playlist_revisions",
+		query: `
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMP;
+
+			CREATE INDEX IF NOT EXISTS "playlists_deleted_idx" ON "playlists" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+			CREATE TABLE IF NOT EXISTS "playlist_revisions" (
+				"id" SERIAL PRIMARY KEY,
+				"playlist_id" INT NOT NULL REFERENCES "playlists" ("id") ON DELETE CASCADE,
+				"version" INT NOT NULL,
+				"actor_id" INT REFERENCES "users" ("id") ON DELETE SET NULL,
+				"action" VARCHAR(30) NOT NULL,
+				"summary" TEXT NOT NULL,
+				"snapshot" JSONB NOT NULL,
+				"created_at" TIMESTAMP DEFAULT (NOW())
+			);
+
+			CREATE INDEX IF NOT EXISTS "playlist_revisions_playlist_id_idx" ON "playlist_revisions" ("playlist_id", "id");
+		`,
+	},
+	{
+		name: "playlist_follows",
+		query: `
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "forked_from_id" INT REFERENCES "playlists" ("id") ON DELETE SET NULL;
+
+			CREATE TABLE IF NOT EXISTS "playlist_follows" (
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"playlist_id" INT NOT NULL REFERENCES "playlists" ("id") ON DELETE CASCADE,
+				"created_at" TIMESTAMP DEFAULT (NOW()),
+				PRIMARY KEY ("user_id", "playlist_id")
+			);
+
+			CREATE INDEX IF NOT EXISTS "playlist_follows_playlist_id_idx" ON "playlist_follows" ("playlist_id");
+		`,
+	},
+	{
+		name: "playlist_generated_covers",
+		query: `
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "cover_generated" BOOLEAN NOT NULL DEFAULT false;
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "cover_version" INT;
+			ALTER TABLE "playlists" ADD COLUMN IF NOT EXISTS "cover_sources" TEXT[];
+		`,
+	},
+	{
+		name: "full_text_search",
+		query: `
+			CREATE EXTENSION IF NOT EXISTS unaccent;
+			CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+			DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'search_simple') THEN
+					CREATE TEXT SEARCH CONFIGURATION search_simple (COPY = simple);
+					ALTER TEXT SEARCH CONFIGURATION search_simple ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+				END IF;
+			END
+			$$;
+
+			CREATE OR REPLACE FUNCTION search_normalize(input TEXT) RETURNS TEXT
+				LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+				AS $$ SELECT lower(public.unaccent('public.unaccent'::regdictionary, input)) $$;
+
+			CREATE OR REPLACE FUNCTION search_query(input TEXT) RETURNS tsquery
+				LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+				AS $$
+					SELECT to_tsquery('search_simple', COALESCE(string_agg(quote_literal(word) || ':*', ' & '), ''))
+					FROM regexp_split_to_table(search_normalize(input), '[^[:alnum:]]+') AS word
+					WHERE word <> ''
+				$$;
+
+			CREATE OR REPLACE FUNCTION track_search_vector(title TEXT, artist TEXT, genre TEXT, lyrics TEXT) RETURNS tsvector
+				LANGUAGE sql IMMUTABLE PARALLEL SAFE
+				AS $$
+					SELECT setweight(to_tsvector('search_simple', COALESCE(title, '')), 'A')
+							|| setweight(to_tsvector('search_simple', COALESCE(artist, '')), 'B')
+							|| setweight(to_tsvector('search_simple', COALESCE(genre, '')), 'C')
+							|| setweight(to_tsvector('search_simple', COALESCE(lyrics, '')), 'D')
+				$$;
+
+			CREATE OR REPLACE FUNCTION album_search_vector(title TEXT, artist TEXT) RETURNS tsvector
+				LANGUAGE sql IMMUTABLE PARALLEL SAFE
+				AS $$
+					SELECT setweight(to_tsvector('search_simple', COALESCE(title, '')), 'A')
+							|| setweight(to_tsvector('search_simple', COALESCE(artist, '')), 'B')
+				$$;
+
+			CREATE OR REPLACE FUNCTION tracks_search_vector_update() RETURNS trigger
+				LANGUAGE plpgsql
+				AS $$
+				BEGIN
+					NEW.search_vector := track_search_vector(NEW.title, (SELECT username FROM users WHERE id = NEW.artist_id), NEW.genre, NEW.lyrics);
+					RETURN NEW;
+				END
+				$$;
+
+			CREATE OR REPLACE FUNCTION albums_search_vector_update() RETURNS trigger
+				LANGUAGE plpgsql
+				AS $$
+				BEGIN
+					NEW.search_vector := album_search_vector(NEW.title, (SELECT username FROM users WHERE id = NEW.artist_id));
+					RETURN NEW;
+				END
+				$$;
+
+			CREATE OR REPLACE FUNCTION users_search_vector_update() RETURNS trigger
+				LANGUAGE plpgsql
+				AS $$
+				BEGIN
+					UPDATE tracks SET search_vector = track_search_vector(title, NEW.username, genre, lyrics) WHERE artist_id = NEW.id;
+					UPDATE albums SET search_vector = album_search_vector(title, NEW.username) WHERE artist_id = NEW.id;
+					RETURN NULL;
+				END
+				$$;
+
+			ALTER TABLE "tracks" ADD COLUMN IF NOT EXISTS "search_vector" TSVECTOR;
+			ALTER TABLE "albums" ADD COLUMN IF NOT EXISTS "search_vector" TSVECTOR;
+
+			DROP TRIGGER IF EXISTS "tracks_search_vector" ON "tracks";
+			DROP TRIGGER IF EXISTS "albums_search_vector" ON "albums";
+			DROP TRIGGER IF EXISTS "users_search_vector" ON "users";
+			CREATE TRIGGER "tracks_search_vector" BEFORE INSERT OR UPDATE OF "title", "artist_id", "genre", "lyrics" ON "tracks"
+				FOR EACH ROW EXECUTE FUNCTION tracks_search_vector_update();
+
+			CREATE TRIGGER "albums_search_vector" BEFORE INSERT OR UPDATE OF "title", "artist_id" ON "albums"
+				FOR EACH ROW EXECUTE FUNCTION albums_search_vector_update();
+
+			CREATE TRIGGER "users_search_vector" AFTER UPDATE OF "username" ON "users"
+				FOR EACH ROW WHEN (OLD."username" IS DISTINCT FROM NEW."username") EXECUTE FUNCTION users_search_vector_update();
+
+			UPDATE "tracks" t SET "search_vector" = track_search_vector(t."title", u."username", t."genre", t."lyrics")
+			FROM "users" u WHERE u."id" = t."artist_id" AND t."search_vector" IS NULL;
+			UPDATE "albums" a SET "search_vector" = album_search_vector(a."title", u."username")
+			FROM "users" u WHERE u."id" = a."artist_id" AND a."search_vector" IS NULL;
+
+			CREATE INDEX IF NOT EXISTS "tracks_search_vector_idx" ON "tracks" USING GIN ("search_vector");
+			CREATE INDEX IF NOT EXISTS "tracks_title_trgm_idx" ON "tracks" USING GIN (search_normalize("title") gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS "albums_search_vector_idx" ON "albums" USING GIN ("search_vector");
+			CREATE INDEX IF NOT EXISTS "albums_title_trgm_idx" ON "albums" USING GIN (search_normalize("title") gin_trgm_ops);
+			CREATE INDEX IF NOT EXISTS "users_username_search_idx" ON "users" USING GIN (to_tsvector('search_simple', "username"));
+			CREATE INDEX IF NOT EXISTS "users_username_trgm_idx" ON "users" USING GIN (search_normalize("username") gin_trgm_ops);
+		`,
+	},
+	{
+		name: "playlist_search",
+		query: `
+			CREATE INDEX IF NOT EXISTS "playlists_title_search_idx" ON "playlists" USING GIN (to_tsvector('search_simple', "title"))
+				WHERE "deleted_at" IS NULL AND "privacy" = 'public';
+			CREATE INDEX IF NOT EXISTS "playlists_title_trgm_idx" ON "playlists" USING GIN (search_normalize("title") gin_trgm_ops)
+				WHERE "deleted_at" IS NULL AND "privacy" = 'public';
+		`,
+	},
+	{
+		name: "recent_searches",
+		query: `
+			CREATE TABLE IF NOT EXISTS "recent_searches" (
+				"id" SERIAL PRIMARY KEY,
+				"user_id" INT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+				"query" VARCHAR(200) NOT NULL,
+				"searched_at" TIMESTAMP NOT NULL DEFAULT (NOW())
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS "recent_searches_user_query_idx" ON "recent_searches" ("user_id", lower("query"));
+		`,
+	},
+	{
+		name: "synced_lyrics",
+		query: `
+			ALTER TABLE "tracks" ADD COLUMN IF NOT EXISTS "lyrics_lines" JSONB;
+		`,
+	},
+	{
+		name: "track_tags",
+		query: `
+			DELETE FROM "track_tags" a USING "track_tags" b
+			WHERE a."id" > b."id" AND a."track_id" = b."track_id" AND a."tag_id" = b."tag_id";
+			CREATE UNIQUE INDEX IF NOT EXISTS "track_tags_track_tag_idx" ON "track_tags" ("track_id", "tag_id");
+			CREATE INDEX IF NOT EXISTS "track_tags_tag_idx" ON "track_tags" ("tag_id");
+		`,
+	},
+	{
+		// Runs once: later tag deletions, untaggings and permission revocations must stick
+		name: "seed_track_tags",
+		query: `
+			INSERT INTO permissions (name, description) VALUES
+				('tags.manage', 'Create, rename and delete tags')
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT r.id, p.id
+			FROM roles r
+			JOIN permissions p ON p.name = 'tags.manage'
+			WHERE r.name IN ('moderator', 'admin')
+			ON CONFLICT DO NOTHING;
+
+			-- Seed the taxonomy from genres, which may list several separated by commas
+			INSERT INTO tags (name)
+			SELECT DISTINCT left(lower(regexp_replace(trim(g.name), '\s+', ' ', 'g')), 50)
+			FROM tracks t, regexp_split_to_table(t.genre, ',') AS g(name)
+			WHERE trim(g.name) <> '' AND g.name !~ '/'
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO track_tags (track_id, tag_id)
+			SELECT DISTINCT t.id, tg.id
+			FROM tracks t, regexp_split_to_table(t.genre, ',') AS g(name)
+			JOIN tags tg ON tg.name = left(lower(regexp_replace(trim(g.name), '\s+', ' ', 'g')), 50)
+			ON CONFLICT (track_id, tag_id) DO NOTHING;
+		`,
+	},
+	{
+		name: "genres",
+		query: `
+			-- genre_key normalizes a genre name or alias for matching: case, accents, punctuation and
+			-- spacing are ignored and "&" reads as "and", so "Hip-Hop", "hip hop " and "HIP HOP" match
+			CREATE OR REPLACE FUNCTION genre_key(input TEXT) RETURNS TEXT
+			  LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+			  AS $$ SELECT btrim(regexp_replace(replace(search_normalize(input), '&', ' and '), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+			CREATE TABLE IF NOT EXISTS "genres" (
+				"id" SERIAL PRIMARY KEY,
+				"name" VARCHAR(50) NOT NULL,
+				"slug" TEXT UNIQUE NOT NULL,
+				"parent_id" INT REFERENCES "genres" ("id") ON DELETE SET NULL,
+				"created_at" TIMESTAMP DEFAULT (NOW())
+			);
+			CREATE TABLE IF NOT EXISTS "genre_aliases" (
+				"alias" TEXT PRIMARY KEY,
+				"genre_id" INT NOT NULL REFERENCES "genres" ("id") ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS "genres_parent_idx" ON "genres" ("parent_id");
+			CREATE INDEX IF NOT EXISTS "genre_aliases_genre_idx" ON "genre_aliases" ("genre_id");
+			ALTER TABLE "tracks" ADD COLUMN IF NOT EXISTS "genre_id" INT REFERENCES "genres" ("id") ON DELETE SET NULL;
+			CREATE INDEX IF NOT EXISTS "tracks_genre_idx" ON "tracks" ("genre_id");
+		`,
+	},
+	{
+		// Runs once: seed genres that curators later merge or rename, and revoked
+		// permissions, must not come back
+		name: "seed_genres",
+		query: `
+			INSERT INTO permissions (name, description) VALUES
+				('genres.manage', 'Curate the genre taxonomy')
+			ON CONFLICT (name) DO NOTHING;
+
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT r.id, p.id
+			FROM roles r
+			JOIN permissions p ON p.name = 'genres.manage'
+			WHERE r.name IN ('moderator', 'admin')
+			ON CONFLICT DO NOTHING;
+
+			INSERT INTO "genres" ("name", "slug")
+			SELECT g.name, replace(genre_key(g.name), ' ', '-')
+			FROM (VALUES
+			  ('Rock'), ('Pop'), ('Electronic'), ('Hip Hop'), ('R&B'), ('Jazz'),
+			  ('Blues'), ('Classical'), ('Country'), ('Folk'), ('Reggae'), ('Latin')
+			) AS g(name)
+			ON CONFLICT ("slug") DO NOTHING;
+
+			INSERT INTO "genres" ("name", "slug", "parent_id")
+			SELECT g.name, replace(genre_key(g.name), ' ', '-'), p.id
+			FROM (VALUES
+			  ('Alternative Rock', 'Rock'), ('Indie Rock', 'Rock'), ('Punk', 'Rock'),
+			  ('Hard Rock', 'Rock'), ('Metal', 'Rock'), ('Synthpop', 'Pop'),
+			  ('Indie Pop', 'Pop'), ('K-Pop', 'Pop'), ('House', 'Electronic'),
+			  ('Techno', 'Electronic'), ('Trance', 'Electronic'), ('Drum and Bass', 'Electronic'),
+			  ('Dubstep', 'Electronic'), ('Ambient', 'Electronic'), ('Trap', 'Hip Hop'),
+			  ('Soul', 'R&B'), ('Funk', 'R&B')
+			) AS g(name, parent)
+			JOIN "genres" p ON p.name = g.parent
+			ON CONFLICT ("slug") DO NOTHING;
+
+			INSERT INTO "genre_aliases" ("alias", "genre_id")
+			SELECT genre_key(g.name), g.id FROM "genres" g
+			UNION ALL
+			SELECT genre_key(a.alias), g.id
+			FROM (VALUES
+			  ('hiphop', 'Hip Hop'), ('rap', 'Hip Hop'), ('rnb', 'R&B'),
+			  ('rhythm and blues', 'R&B'), ('dnb', 'Drum and Bass'), ('drum n bass', 'Drum and Bass'),
+			  ('electronica', 'Electronic'), ('edm', 'Electronic'), ('synth pop', 'Synthpop'),
+			  ('kpop', 'K-Pop'), ('alt rock', 'Alternative Rock'), ('alternative', 'Alternative Rock'),
+			  ('punk rock', 'Punk'), ('heavy metal', 'Metal')
+			) AS a(alias, genre)
+			JOIN "genres" g ON g.name = a.genre
+			ON CONFLICT ("alias") DO NOTHING;
+
+			-- Genres of existing tracks that match no alias become top-level genres, named
+			-- after their most common spelling
+			INSERT INTO "genres" ("name", "slug")
+			SELECT DISTINCT ON (genre_key(t.genre)) btrim(regexp_replace(t.genre, '\s+', ' ', 'g')), replace(genre_key(t.genre), ' ', '-')
+			FROM "tracks" t
+			WHERE genre_key(t.genre) <> ''
+			  AND NOT EXISTS (SELECT 1 FROM "genre_aliases" ga WHERE ga."alias" = genre_key(t.genre))
+			GROUP BY genre_key(t.genre), btrim(regexp_replace(t.genre, '\s+', ' ', 'g'))
+			ORDER BY genre_key(t.genre), COUNT(*) DESC
+			ON CONFLICT ("slug") DO NOTHING;
+
+			INSERT INTO "genre_aliases" ("alias", "genre_id")
+			SELECT genre_key("name"), "id" FROM "genres"
+			ON CONFLICT ("alias") DO NOTHING;
+
+			-- Point tracks at their genre and spell it the canonical way
+			UPDATE "tracks" t
+			SET "genre_id" = g."id", "genre" = g."name"
+			FROM "genre_aliases" ga
+			JOIN "genres" g ON g."id" = ga."genre_id"
+			WHERE ga."alias" = genre_key(t."genre")
+			  AND (t."genre_id" IS DISTINCT FROM g."id" OR t."genre" IS DISTINCT FROM g."name");
+
+			UPDATE "tracks" SET "genre" = NULL WHERE btrim("genre") = '';
+		`,
+	},
+	{
+		name: "import_job_queue",
+		query: `
+			ALTER TABLE "import_jobs" ADD COLUMN IF NOT EXISTS "started_at" TIMESTAMP;
+			ALTER TABLE "import_jobs" ADD COLUMN IF NOT EXISTS "attempts" INT NOT NULL DEFAULT 0;
+			CREATE INDEX IF NOT EXISTS "import_jobs_queue_idx" ON "import_jobs" ("status", "created_at")
+				WHERE "status" IN ('pending', 'processing');
+		`,
+	},
+	{
+		name: "import_job_playlists",
+		query: `
+			ALTER TABLE "import_jobs" ADD COLUMN IF NOT EXISTS "playlists" JSONB NOT NULL DEFAULT '[]';
+		`,
+	},
+	{
+		name: "drop_users_manage_permission",
+		query: `
+			DELETE FROM permissions WHERE name = 'users.manage';
+		`,
+	},
+	{
+		name: "data_export_queue",
+		query: `
+			ALTER TABLE "data_export_jobs" ADD COLUMN IF NOT EXISTS "started_at" TIMESTAMP;
+			ALTER TABLE "data_export_jobs" ADD COLUMN IF NOT EXISTS "attempts" INT NOT NULL DEFAULT 0;
+
+			-- Exports used to run in the request that started them; ones left unfinished are queued
+			-- again, keeping only the newest per user
+			UPDATE "data_export_jobs" d SET "status" = 'failed', "error" = 'superseded by a newer export', "completed_at" = NOW()
+			WHERE "status" IN ('pending', 'processing')
+			  AND EXISTS (
+			    SELECT 1 FROM "data_export_jobs" n
+			    WHERE n."user_id" = d."user_id" AND n."status" IN ('pending', 'processing') AND n."id" > d."id"
+			  );
+			UPDATE "data_export_jobs" SET "status" = 'pending' WHERE "status" = 'processing';
+
+			-- A user has at most one export queued or running
+			CREATE UNIQUE INDEX IF NOT EXISTS "data_export_jobs_active_idx" ON "data_export_jobs" ("user_id")
+				WHERE "status" IN ('pending', 'processing');
+		`,
+	},
+	{
+		name: "tracks_artist_index",
+		query: `
+			-- Tracks are looked up by artist when matching imported playlists and history
+			CREATE INDEX IF NOT EXISTS "tracks_artist_id_idx" ON "tracks" ("artist_id");
+		`,
+	},
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
@@ -73,3 +74,70 @@ func CropToSquare(reader io.Reader, contentType string) (io.Reader, string, erro
 
 	return bytes.NewReader(buf.Bytes()), contentType, nil
 }
+
+// DecodeImage decodes a JPEG or PNG image of either format
+func DecodeImage(reader io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// ComposeMosaic lays out four images in a 2x2 grid, or a single image on its own, as a
+// square JPEG of size pixels. Images are cropped to squares and scaled to fit their tile.
+func ComposeMosaic(images []image.Image, size int) ([]byte, error) {
+	if len(images) != 1 && len(images) != 4 {
+		return nil, fmt.Errorf("a mosaic needs 1 or 4 images, got %d", len(images))
+	}
+
+	mosaic := image.NewRGBA(image.Rect(0, 0, size, size))
+	tile := size
+	if len(images) == 4 {
+		tile = size / 2
+	}
+	for i, img := range images {
+		x, y := (i%2)*tile, (i/2)*tile
+		drawScaledSquare(mosaic, image.Rect(x, y, x+tile, y+tile), img)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, mosaic, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawScaledSquare crops src to a centered square and draws it into dst scaled to fill rect.
+// Each destination pixel averages the source pixels it covers, so downscaling stays smooth.
+func drawScaledSquare(dst *image.RGBA, rect image.Rectangle, src image.Image) {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	if side == 0 {
+		return
+	}
+	originX := bounds.Min.X + (bounds.Dx()-side)/2
+	originY := bounds.Min.Y + (bounds.Dy()-side)/2
+	tile := rect.Dx()
+
+	for dy := 0; dy < tile; dy++ {
+		sy0 := originY + dy*side/tile
+		sy1 := max(originY+(dy+1)*side/tile, sy0+1)
+		for dx := 0; dx < tile; dx++ {
+			sx0 := originX + dx*side/tile
+			sx1 := max(originX+(dx+1)*side/tile, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA64(rect.Min.X+dx, rect.Min.Y+dy, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+}
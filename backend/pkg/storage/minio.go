@@ -147,6 +147,17 @@ func (m *MinioClient) UploadImage(ctx context.Context, fileReader interface{}, f
 	return url, nil
 }
 
+// PutObject uploads data under a fixed object name, overwriting any existing object
+func (m *MinioClient) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := m.Client.PutObject(ctx, m.BucketName, objectName, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload object to MinIO bucket %s: %w", m.BucketName, err)
+	}
+	return nil
+}
+
 // GetObject retrieves an object from MinIO with optional range support for streaming
 func (m *MinioClient) GetObject(ctx context.Context, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
 	obj, err := m.Client.GetObject(ctx, m.BucketName, objectName, opts)
@@ -175,6 +186,15 @@ func (m *MinioClient) ExtractObjectName(fileURL string) string {
 	return strings.TrimPrefix(fileURL, prefix)
 }
 
+// ObjectURL returns the public URL of an object, the inverse of ExtractObjectName
+func (m *MinioClient) ObjectURL(objectName string) string {
+	protocol := "http"
+	if m.UseSSL {
+		protocol = "https"
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", protocol, m.Endpoint, m.BucketName, objectName)
+}
+
 // DeleteFile deletes a file from MinIO storage
 func (m *MinioClient) DeleteFile(ctx context.Context, objectName string) error {
 	err := m.Client.RemoveObject(ctx, m.BucketName, objectName, minio.RemoveObjectOptions{})